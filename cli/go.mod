@@ -0,0 +1,5 @@
+module indico-middleware
+
+go 1.20
+
+require github.com/joho/godotenv v1.5.1
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/joho/godotenv"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+const usage = `Usage: indico-middleware [--root dir] [--format json|table] <command>
+
+Commands:
+  sync events
+  sync timetables [--conference id]
+  sync contributions [--conference id] [--only code]
+  digest [--conference id]
+  find --conference id --code code [--format latex|docx]
+  conferences list
+  sessions --conference id [--code code] [--session code]
+  ics --conference id [--session code] [--presenter name] [--per session|contribution]
+  authors --name name | --person id | --author id
+  affiliations [--name name] [--unmapped] | --id id [--ror id|none] [--canonical name] [--alias name] | --load-ror file
+  search --query words [--conference id] [--type type] [--from yyyy-mm-dd] [--to yyyy-mm-dd] [--limit n]
+  statistics [--conference id] [--limit n]
+  graphql --query '{ conference(id: 41) { name } }'
+  keys list | create --name name [--conferences 41,42] [--rate-limit n] | revoke --id id
+  purge [--conference id]
+  webhooks
+  runs [--conference id] [--job name] [--limit n]
+  history --conference id --code code
+  validate --conference id
+  export --conference id [--code code] --format bibtex|csl-json|crossref|csv|xlsx [--columns a,b]
+`
+
+func functionsDir(root string) (string, error) {
+	if root != "" {
+		return root, nil
+	}
+	if env := os.Getenv("INDICO_MIDDLEWARE_ROOT"); env != "" {
+		return env, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	// Walk up until we find the project.yml of the serverless project
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, "project.yml")); err == nil {
+			return filepath.Join(dir, "packages", "indico"), nil
+		}
+		if filepath.Dir(dir) == dir {
+			return "", errors.New("unable to find project.yml, use --root to point at packages/indico")
+		}
+	}
+}
+
+// invoke runs the Main of one of the functions in packages/indico with the
+// given request and returns its response.
+func invoke(root string, function string, request map[string]string) (*Response, error) {
+	body := make(map[string]interface{})
+	for name, value := range request {
+		body[name] = value
+	}
+	// Web functions check the API key, as they would for a web request
+	if key := os.Getenv("INDICO_MIDDLEWARE_API_KEY"); key != "" {
+		body["http"] = map[string]interface{}{
+			"headers": map[string]string{"x-api-key": key},
+		}
+	}
+	requestBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding request: %s", err.Error())
+	}
+	cmd := exec.Command("go", "run", "-tags", "cli", ".")
+	cmd.Dir = filepath.Join(root, function)
+	cmd.Stdin = bytes.NewReader(requestBytes)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error running %s: %s", function, err.Error())
+	}
+	var response Response
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, fmt.Errorf("error decoding response: %s", err.Error())
+	}
+	return &response, nil
+}
+
+func printJSON(body string) error {
+	var value interface{}
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		fmt.Println(body)
+		return nil
+	}
+	out, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+func cell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64, bool:
+		return fmt.Sprint(v)
+	default:
+		out, _ := json.Marshal(v)
+		return string(out)
+	}
+}
+
+func printTable(body string) error {
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &rows); err != nil {
+		var row map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &row); err != nil {
+			fmt.Println(body)
+			return nil
+		}
+		rows = []map[string]interface{}{row}
+	}
+	if len(rows) == 0 {
+		fmt.Println("No results")
+		return nil
+	}
+
+	columnSet := make(map[string]bool)
+	for _, row := range rows {
+		for column := range row {
+			columnSet[column] = true
+		}
+	}
+	var columns []string
+	for column := range columnSet {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, row := range rows {
+		var cells []string
+		for _, column := range columns {
+			cells = append(cells, cell(row[column]))
+		}
+		fmt.Fprintln(writer, strings.Join(cells, "\t"))
+	}
+	return writer.Flush()
+}
+
+// writeBinary saves a base64 encoded response body, such as a docx or xlsx
+func writeBinary(filename string, body string) error {
+	content, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return fmt.Errorf("error decoding %s: %s", filename, err.Error())
+	}
+	if err := os.WriteFile(filename, content, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", filename)
+	return nil
+}
+
+// invocation is a command line parsed into the function to run and its
+// request
+type invocation struct {
+	root     string
+	format   string
+	function string
+	request  map[string]string
+}
+
+func parseArgs(args []string) (*invocation, error) {
+	global := flag.NewFlagSet("indico-middleware", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	root := global.String("root", "", "path to packages/indico")
+	format := global.String("format", "json", "output format: json or table")
+	if err := global.Parse(args); err != nil {
+		return nil, err
+	}
+	args = global.Args()
+	if len(args) == 0 {
+		global.Usage()
+		return nil, errors.New("no command given")
+	}
+
+	var function string
+	command := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	conference := command.String("conference", "", "indico conference id")
+	// Request parameters, other than the conference, set by the command's flags
+	extra := make(map[string]*string)
+	unmapped := new(bool)
+
+	switch {
+	case args[0] == "sync" && len(args) > 1 && args[1] == "events":
+		function = "events"
+	case args[0] == "sync" && len(args) > 1 && args[1] == "timetables":
+		function = "timetables"
+	case args[0] == "sync" && len(args) > 1 && args[1] == "contributions":
+		function = "contributions"
+		extra["code"] = command.String("only", "", "only sync the contribution with this code")
+	case args[0] == "find" || args[0] == "history":
+		function = args[0]
+		extra["code"] = command.String("code", "", "contribution code")
+		if function == "find" {
+			extra["format"] = command.String("format", "", "output format of find: latex or docx")
+		}
+	case args[0] == "conferences" && len(args) > 1 && args[1] == "list":
+		function = "conferences"
+	case args[0] == "digest" || args[0] == "validate" || args[0] == "purge" || args[0] == "webhooks":
+		function = args[0]
+	case args[0] == "export":
+		function = "export"
+		extra["code"] = command.String("code", "", "only export the contribution with this code")
+		extra["format"] = command.String("format", "bibtex", "export format")
+		extra["columns"] = command.String("columns", "", "comma separated csv or xlsx columns")
+	case args[0] == "sessions":
+		function = "sessions"
+		extra["code"] = command.String("code", "", "only the session this contribution is in")
+		extra["session"] = command.String("session", "", "only this session code")
+	case args[0] == "ics":
+		function = "ics"
+		extra["session"] = command.String("session", "", "only include this session code")
+		extra["presenter"] = command.String("presenter", "", "only include contributions presented by this name")
+		extra["per"] = command.String("per", "contribution", "one event per session or per contribution")
+	case args[0] == "authors":
+		function = "authors"
+		extra["name"] = command.String("name", "", "author name, or family name")
+		extra["person"] = command.String("person", "", "indico person id")
+		extra["author"] = command.String("author", "", "resolved author id")
+	case args[0] == "affiliations":
+		function = "affiliations"
+		extra["id"] = command.String("id", "", "indico affiliation id")
+		extra["name"] = command.String("name", "", "affiliation name, canonical name or alias")
+		extra["ror"] = command.String("ror", "", "map the affiliation to this ROR id")
+		extra["canonical"] = command.String("canonical", "", "set the canonical name of the affiliation")
+		extra["alias"] = command.String("alias", "", "add an alias to the affiliation")
+		extra["dump"] = command.String("load-ror", "", "load this ROR data dump JSON file")
+		unmapped = command.Bool("unmapped", false, "only list affiliations without a ROR id")
+	case args[0] == "search":
+		function = "search"
+		extra["query"] = command.String("query", "", "words or \"phrases\" to search for, -word to exclude")
+		extra["type"] = command.String("type", "", "only this contribution type")
+		extra["from"] = command.String("from", "", "only conferences starting on or after this date")
+		extra["to"] = command.String("to", "", "only conferences starting on or before this date")
+		extra["limit"] = command.String("limit", "", "number of results, at most 100")
+	case args[0] == "statistics":
+		function = "statistics"
+		extra["limit"] = command.String("limit", "", "number of top affiliations")
+	case args[0] == "graphql":
+		function = "graphql"
+		extra["query"] = command.String("query", "", "GraphQL query")
+	case args[0] == "keys" && len(args) > 1 && (args[1] == "list" || args[1] == "create" || args[1] == "revoke"):
+		function = "keys"
+		action := args[1]
+		extra["action"] = &action
+		extra["name"] = command.String("name", "", "name of the client the key is for")
+		extra["conferences"] = command.String("conferences", "", "comma separated conference ids the key is limited to")
+		extra["rate_limit"] = command.String("rate-limit", "", "requests per minute, instead of API_KEY_RATE_LIMIT")
+		extra["id"] = command.String("id", "", "id of the key to revoke, or the start of it")
+	case args[0] == "runs":
+		function = "runs"
+		extra["job"] = command.String("job", "", "only show runs of this job")
+		extra["limit"] = command.String("limit", "", "number of runs to show")
+	default:
+		global.Usage()
+		return nil, fmt.Errorf("unknown command: %s", strings.Join(args, " "))
+	}
+
+	commandArgs := args[1:]
+	if args[0] == "sync" || args[0] == "conferences" || args[0] == "keys" {
+		commandArgs = args[2:]
+	}
+	if err := command.Parse(commandArgs); err != nil {
+		return nil, err
+	}
+
+	request := map[string]string{"conference": *conference}
+	for name, value := range extra {
+		request[name] = *value
+	}
+	if *unmapped {
+		request["unmapped"] = "true"
+	}
+	// The function runs in its own directory, so the dump path must be absolute
+	if request["dump"] != "" {
+		dump, err := filepath.Abs(request["dump"])
+		if err != nil {
+			return nil, err
+		}
+		request["dump"] = dump
+	}
+	if (function == "find" || function == "history") && (request["conference"] == "" || request["code"] == "") {
+		return nil, fmt.Errorf("%s requires --conference and --code", function)
+	}
+	return &invocation{root: *root, format: *format, function: function, request: request}, nil
+}
+
+func run(args []string) error {
+	parsed, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+	dir, err := functionsDir(parsed.root)
+	if err != nil {
+		return err
+	}
+	response, err := invoke(dir, parsed.function, parsed.request)
+	if err != nil {
+		return err
+	}
+
+	request := parsed.request
+	switch request["format"] {
+	case "docx":
+		return writeBinary(request["code"]+".docx", response.Body)
+	case "xlsx":
+		return writeBinary(request["conference"]+".xlsx", response.Body)
+	}
+	if parsed.format == "table" {
+		return printTable(response.Body)
+	}
+	return printJSON(response.Body)
+}
+
+func main() {
+	// Pick up INDICO_AUTH and MONGO_AUTH from a local .env if there is one
+	_ = godotenv.Load()
+
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		function string
+		request  map[string]string
+		format   string
+		err      bool
+	}{
+		{
+			name:     "sync events",
+			args:     []string{"sync", "events"},
+			function: "events",
+			request:  map[string]string{"conference": ""},
+		},
+		{
+			name:     "sync timetables",
+			args:     []string{"sync", "timetables"},
+			function: "timetables",
+			request:  map[string]string{"conference": ""},
+		},
+		{
+			name:     "sync one conference",
+			args:     []string{"sync", "timetables", "--conference", "41"},
+			function: "timetables",
+			request:  map[string]string{"conference": "41"},
+		},
+		{
+			name:     "sync one contribution",
+			args:     []string{"sync", "contributions", "--conference", "41", "--only", "TUPA071"},
+			function: "contributions",
+			request:  map[string]string{"conference": "41", "code": "TUPA071"},
+		},
+		{
+			name:     "find",
+			args:     []string{"find", "--conference", "41", "--code", "TUPA071"},
+			function: "find",
+			request:  map[string]string{"conference": "41", "code": "TUPA071", "format": ""},
+		},
+		{
+			name:     "conferences as a table",
+			args:     []string{"--format", "table", "conferences", "list"},
+			function: "conferences",
+			request:  map[string]string{"conference": ""},
+			format:   "table",
+		},
+		{name: "find without a code", args: []string{"find", "--conference", "41"}, err: true},
+		{name: "no command", args: []string{}, err: true},
+		{name: "unknown command", args: []string{"sync", "everything"}, err: true},
+		{name: "unknown flag", args: []string{"find", "--conference", "41", "--code", "A", "--colour"}, err: true},
+	}
+	for _, test := range tests {
+		parsed, err := parseArgs(test.args)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: parsed %v, want an error", test.name, test.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		format := test.format
+		if format == "" {
+			format = "json"
+		}
+		if parsed.function != test.function || parsed.format != format || !reflect.DeepEqual(parsed.request, test.request) {
+			t.Errorf("%s: got %s %s %v, want %s %s %v", test.name,
+				parsed.function, parsed.format, parsed.request, test.function, format, test.request)
+		}
+	}
+}
+
+func TestCell(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, ""},
+		{"TUPA071", "TUPA071"},
+		{float64(41), "41"},
+		{true, "true"},
+		{[]interface{}{"a", float64(1)}, `["a",1]`},
+		{map[string]interface{}{"name": "CERN"}, `{"name":"CERN"}`},
+	}
+	for _, test := range tests {
+		if got := cell(test.value); got != test.want {
+			t.Errorf("cell(%v) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestFunctionsDir(t *testing.T) {
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, "project.yml"), []byte("packages: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(project, "packages", "indico", "find")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("INDICO_MIDDLEWARE_ROOT", "")
+
+	if dir, err := functionsDir("/somewhere/else"); err != nil || dir != "/somewhere/else" {
+		t.Errorf("functionsDir with --root = %s, %v", dir, err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	if err := os.Chdir(nested); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := functionsDir("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := filepath.EvalSymlinks(filepath.Join(project, "packages", "indico"))
+	if got, _ := filepath.EvalSymlinks(dir); got != want {
+		t.Errorf("functionsDir from a function = %s, want %s", got, want)
+	}
+
+	t.Setenv("INDICO_MIDDLEWARE_ROOT", "/from/env")
+	if dir, err := functionsDir(""); err != nil || dir != "/from/env" {
+		t.Errorf("functionsDir with INDICO_MIDDLEWARE_ROOT = %s, %v", dir, err)
+	}
+}
@@ -0,0 +1,41 @@
+// Package client calls the web functions of the indico middleware, as
+// described by openapi.yaml at the root of the repository. The types and
+// requests are generated into client.gen.go.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// DefaultBaseURL is where the web functions are deployed
+const DefaultBaseURL = "https://faas-syd1-c274eac6.doserverless.co/api/v1/web/fn-19977d5d-a466-4a2d-bfd5-e29ba32197eb/indico"
+
+// New returns a client for the functions at baseURL, or the deployed ones
+// when it is empty. The apiKey is sent with every request when set,
+// otherwise requests use the anonymous tier.
+func New(baseURL string, apiKey string, opts ...ClientOption) (*ClientWithResponses, error) {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if apiKey != "" {
+		opts = append([]ClientOption{WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
+			req.Header.Set("X-API-Key", apiKey)
+			return nil
+		})}, opts...)
+	}
+	return NewClientWithResponses(strings.TrimSuffix(baseURL, "/"), opts...)
+}
+
+// Binary returns the bytes of a docx or xlsx response body, which the
+// platform may pass on still base64 encoded. Both are zip files, which start
+// with "PK".
+func Binary(body []byte) ([]byte, error) {
+	if bytes.HasPrefix(body, []byte("PK")) {
+		return body, nil
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,5 @@
+package client
+
+// The types and requests in client.gen.go are generated from openapi.yaml,
+// run go generate here after changing it
+//go:generate go run github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen@v2.8.0 -config oapi-codegen.yaml ../openapi.yaml
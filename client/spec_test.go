@@ -0,0 +1,237 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// fixture is a response written by the tests of a function from its Main,
+// with the status code and headers it returned, and the operation in
+// openapi.yaml which describes it
+type fixture struct {
+	path      string
+	operation string
+	// decoded is the field of the client's response the body is decoded into
+	decoded string
+	// call requests the operation with the client
+	call func(ctx context.Context, c *ClientWithResponses) (interface{}, error)
+}
+
+func findCall(ctx context.Context, c *ClientWithResponses) (interface{}, error) {
+	return c.FindWithResponse(ctx, &FindParams{Conference: "41", Code: "TUPA071"})
+}
+
+func graphqlCall(ctx context.Context, c *ClientWithResponses) (interface{}, error) {
+	return c.GraphqlWithResponse(ctx, GraphqlJSONRequestBody{Query: "{ __typename }"})
+}
+
+var fixtures = []fixture{
+	{"find/testdata/response.json", "find", "JSON200", findCall},
+	{"find/testdata/unauthorized.json", "find", "JSON401", findCall},
+	{"find/testdata/forbidden.json", "find", "JSON403", findCall},
+	{"find/testdata/too-many-requests.json", "find", "JSON429", findCall},
+	{"conferences/testdata/response.json", "conferences", "JSON200", func(ctx context.Context, c *ClientWithResponses) (interface{}, error) {
+		return c.ConferencesWithResponse(ctx)
+	}},
+	{"runs/testdata/response.json", "runs", "JSON200", func(ctx context.Context, c *ClientWithResponses) (interface{}, error) {
+		return c.RunsWithResponse(ctx, &RunsParams{})
+	}},
+	{"history/testdata/response.json", "history", "JSON200", func(ctx context.Context, c *ClientWithResponses) (interface{}, error) {
+		return c.HistoryWithResponse(ctx, &HistoryParams{Conference: "41"})
+	}},
+	{"validate/testdata/response.json", "validate", "JSON200", func(ctx context.Context, c *ClientWithResponses) (interface{}, error) {
+		return c.ValidateWithResponse(ctx, &ValidateParams{Conference: "41"})
+	}},
+	{"export/testdata/csl-json.json", "export", "ApplicationvndCitationstylesCslJSON200", func(ctx context.Context, c *ClientWithResponses) (interface{}, error) {
+		return c.ExportWithResponse(ctx, &ExportParams{Conference: "41", Format: CslJson})
+	}},
+	{"sessions/testdata/response.json", "sessions", "JSON200", func(ctx context.Context, c *ClientWithResponses) (interface{}, error) {
+		return c.SessionsWithResponse(ctx, &SessionsParams{Conference: "41"})
+	}},
+	{"authors/testdata/response.json", "authors", "JSON200", func(ctx context.Context, c *ClientWithResponses) (interface{}, error) {
+		return c.AuthorsWithResponse(ctx, &AuthorsParams{})
+	}},
+	{"search/testdata/response.json", "search", "JSON200", func(ctx context.Context, c *ClientWithResponses) (interface{}, error) {
+		return c.SearchWithResponse(ctx, &SearchParams{Query: "beam loss"})
+	}},
+	{"statistics/testdata/response.json", "statistics", "JSON200", func(ctx context.Context, c *ClientWithResponses) (interface{}, error) {
+		return c.StatisticsWithResponse(ctx, &StatisticsParams{})
+	}},
+	{"graphql/testdata/response.json", "graphql", "JSON200", graphqlCall},
+	{"graphql/testdata/errors.json", "graphql", "JSON200", graphqlCall},
+}
+
+// envelope is a fixture as the functions' tests write it. A JSON body is
+// written as JSON, any other body as a string.
+type envelope struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers"`
+	Body       json.RawMessage   `json:"body"`
+}
+
+// status is the HTTP status the platform sends, 200 when the function leaves
+// it out
+func (e envelope) status() int {
+	if e.StatusCode == 0 {
+		return http.StatusOK
+	}
+	return e.StatusCode
+}
+
+// body is the body the platform sends
+func (e envelope) body() []byte {
+	var text string
+	if err := json.Unmarshal(e.Body, &text); err == nil {
+		return []byte(text)
+	}
+	return e.Body
+}
+
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+	spec, err := openapi3.NewLoader().LoadFromFile(filepath.Join("..", "openapi.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := spec.Validate(context.Background()); err != nil {
+		t.Fatalf("openapi.yaml is invalid: %s", err.Error())
+	}
+	return spec
+}
+
+func readFixture(t *testing.T, path string) envelope {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("..", "packages", "indico", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture envelope
+	if err := json.Unmarshal(content, &fixture); err != nil {
+		t.Fatalf("%s: %s", path, err.Error())
+	}
+	return fixture
+}
+
+// checkResponse checks the status, headers and body of a fixture against the
+// response openapi.yaml gives for its status, or the default response
+func checkResponse(operation *openapi3.Operation, fixture envelope) error {
+	response := operation.Responses.Status(fixture.status())
+	if response == nil {
+		response = operation.Responses.Default()
+	}
+	if response == nil {
+		return fmt.Errorf("no %d response", fixture.status())
+	}
+	mediaType, _, err := mime.ParseMediaType(fixture.Headers["Content-Type"])
+	if err != nil {
+		return fmt.Errorf("Content-Type %q: %s", fixture.Headers["Content-Type"], err.Error())
+	}
+	media := response.Value.Content.Get(mediaType)
+	if media == nil {
+		return fmt.Errorf("no %s content for %d", mediaType, fixture.status())
+	}
+	var value interface{} = string(fixture.body())
+	if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+		if err := json.Unmarshal(fixture.Body, &value); err != nil {
+			return err
+		}
+	}
+	if err := media.Schema.Value.VisitJSON(value); err != nil {
+		return fmt.Errorf("body does not match the schema: %s", err.Error())
+	}
+	for name, header := range response.Value.Headers {
+		text, ok := fixture.Headers[name]
+		if !ok {
+			return fmt.Errorf("no %s header", name)
+		}
+		var value interface{} = text
+		if !header.Value.Schema.Value.Type.Is("string") {
+			if err := json.Unmarshal([]byte(text), &value); err != nil {
+				return fmt.Errorf("%s header %q: %s", name, text, err.Error())
+			}
+		}
+		if err := header.Value.Schema.Value.VisitJSON(value); err != nil {
+			return fmt.Errorf("%s header does not match the schema: %s", name, err.Error())
+		}
+	}
+	return nil
+}
+
+// TestFixturesMatchSpec checks the response fixtures of the functions,
+// written from what their Main returned, against openapi.yaml
+func TestFixturesMatchSpec(t *testing.T) {
+	spec := loadSpec(t)
+	for _, fixture := range fixtures {
+		item := spec.Paths.Find("/" + fixture.operation)
+		if item == nil {
+			t.Errorf("%s: no path /%s", fixture.path, fixture.operation)
+			continue
+		}
+		var operation *openapi3.Operation
+		for _, candidate := range item.Operations() {
+			operation = candidate
+		}
+		if err := checkResponse(operation, readFixture(t, fixture.path)); err != nil {
+			t.Errorf("%s does not match /%s: %s", fixture.path, fixture.operation, err.Error())
+		}
+	}
+}
+
+// TestClientDecodesFixtures serves each fixture and checks the generated
+// client decodes it, sending the API key given to New
+func TestClientDecodesFixtures(t *testing.T) {
+	for _, fixture := range fixtures {
+		response := readFixture(t, fixture.path)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-API-Key") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			for name, value := range response.Headers {
+				w.Header().Set(name, value)
+			}
+			w.WriteHeader(response.status())
+			_, _ = w.Write(response.body())
+		}))
+		c, err := New(server.URL+"/", "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := fixture.call(context.Background(), c)
+		if err != nil {
+			t.Errorf("%s: %s", fixture.path, err.Error())
+		} else if reflect.ValueOf(got).Elem().FieldByName(fixture.decoded).IsNil() {
+			t.Errorf("%s: the client did not decode it into %s", fixture.path, fixture.decoded)
+		}
+		server.Close()
+	}
+}
+
+func TestBinary(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"PK\x03\x04zip", "PK\x03\x04zip"},
+		{"UEsDBHppcA==\n", "PK\x03\x04zip"},
+	}
+	for _, test := range tests {
+		got, err := Binary([]byte(test.body))
+		if err != nil {
+			t.Errorf("Binary(%q): %s", test.body, err.Error())
+		} else if string(got) != test.want {
+			t.Errorf("Binary(%q) = %q, want %q", test.body, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type MongoAffiliation struct {
+	ID            int       `bson:"_id" json:"id"`
+	Name          string    `bson:"name" json:"name"`
+	City          string    `bson:"city" json:"city"`
+	CountryName   string    `bson:"countryName" json:"country_name"`
+	CountryCode   string    `bson:"countryCode" json:"country_code"`
+	Postcode      string    `bson:"postcode" json:"postcode"`
+	CanonicalName string    `bson:"canonicalName" json:"canonical_name"`
+	Aliases       []string  `bson:"aliases" json:"aliases"`
+	ROR           string    `bson:"ror,omitempty" json:"ror,omitempty"`
+	RORName       string    `bson:"rorName,omitempty" json:"ror_name,omitempty"`
+	UpdatedAt     time.Time `bson:"updatedAt" json:"updated_at"`
+}
+
+type Request struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Unmapped  string `json:"unmapped"`
+	ROR       string `json:"ror"`
+	Canonical string `json:"canonical"`
+	Alias     string `json:"alias"`
+	Dump      string `json:"dump"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+type AffiliationPayload struct {
+	MongoAffiliation
+	Candidates []MongoROR `json:"ror_candidates,omitempty"`
+}
+
+// nameCollation compares names ignoring case and accents
+var nameCollation = &options.Collation{Locale: "en", Strength: 1}
+
+func nameFilter(names []string) bson.D {
+	values := bson.A{}
+	for _, name := range names {
+		if name != "" {
+			values = append(values, name)
+		}
+	}
+	return bson.D{{"$or", bson.A{
+		bson.D{{"name", bson.D{{"$in", values}}}},
+		bson.D{{"canonicalName", bson.D{{"$in", values}}}},
+		bson.D{{"aliases", bson.D{{"$in", values}}}},
+	}}}
+}
+
+func findAffiliations(collection *mongo.Collection, filter bson.D) ([]MongoAffiliation, error) {
+	findOptions := options.Find().SetSort(bson.D{{"canonicalName", 1}}).SetCollation(nameCollation)
+	cursor, findError := collection.Find(context.Background(), filter, findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding affiliations: %s", findError.Error())
+	}
+	var affiliations = make([]MongoAffiliation, 0)
+	if err := cursor.All(context.Background(), &affiliations); err != nil {
+		return nil, fmt.Errorf("error decoding affiliations: %s", err.Error())
+	}
+	return affiliations, nil
+}
+
+// updateMapping applies the editor's ROR id, canonical name or alias to one
+// affiliation. A ror of "none" removes the mapping.
+func updateMapping(database *mongo.Database, affiliationId int, in Request) error {
+	set := bson.D{{"updatedAt", time.Now()}}
+	update := bson.D{}
+	switch rorId := normaliseRORID(in.ROR); {
+	case in.ROR == "none":
+		update = append(update, bson.E{"$unset", bson.D{{"ror", ""}, {"rorName", ""}}})
+	case rorId != "":
+		var ror MongoROR
+		err := database.Collection("ror").FindOne(context.Background(), bson.D{{"_id", rorId}}).Decode(&ror)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("unknown ROR id %s, load a ROR data dump first", rorId)
+		}
+		if err != nil {
+			return fmt.Errorf("error finding ROR organisation: %s", err.Error())
+		}
+		set = append(set, bson.E{"ror", ror.ID}, bson.E{"rorName", ror.Name})
+	}
+	if in.Canonical != "" {
+		set = append(set, bson.E{"canonicalName", in.Canonical})
+	}
+	update = append(update, bson.E{"$set", set})
+	if in.Alias != "" {
+		update = append(update, bson.E{"$addToSet", bson.D{{"aliases", in.Alias}}})
+	}
+
+	result, err := database.Collection("affiliations").UpdateOne(context.Background(), bson.D{{"_id", affiliationId}}, update)
+	if err != nil {
+		return fmt.Errorf("error updating affiliation: %s", err.Error())
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("no affiliation with id %d", affiliationId)
+	}
+	return nil
+}
+
+func Main(in Request) (*Response, error) {
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	database := client.Database("author-title")
+	collection := database.Collection("affiliations")
+
+	var output interface{}
+	switch {
+	case in.Dump != "":
+		loaded, err := loadRORDump(database.Collection("ror"), in.Dump)
+		if err != nil {
+			return nil, err
+		}
+		output = map[string]int{"loaded": loaded}
+
+	case in.ID != "":
+		affiliationId, err := strconv.Atoi(in.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error converting affiliation id to int: %s", err.Error())
+		}
+		if in.ROR != "" || in.Canonical != "" || in.Alias != "" {
+			if err := updateMapping(database, affiliationId, in); err != nil {
+				return nil, err
+			}
+		}
+		affiliations, err := findAffiliations(collection, bson.D{{"_id", affiliationId}})
+		if err != nil {
+			return nil, err
+		}
+		if len(affiliations) == 0 {
+			return nil, fmt.Errorf("no affiliation with id %d", affiliationId)
+		}
+		payload := AffiliationPayload{MongoAffiliation: affiliations[0]}
+		if payload.ROR == "" {
+			names := append([]string{payload.Name, payload.CanonicalName}, payload.Aliases...)
+			payload.Candidates, err = rorCandidates(database.Collection("ror"), names)
+			if err != nil {
+				return nil, err
+			}
+		}
+		output = payload
+
+	default:
+		filter := bson.D{}
+		if name := strings.TrimSpace(in.Name); name != "" {
+			filter = nameFilter([]string{name})
+		}
+		if in.Unmapped == "true" {
+			filter = append(filter, bson.E{"ror", bson.D{{"$exists", false}}})
+		}
+		affiliations, err := findAffiliations(collection, filter)
+		if err != nil {
+			return nil, err
+		}
+		output = affiliations
+	}
+
+	jsonBytes, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling documents: %s", err.Error())
+	}
+	return &Response{
+		Body: string(jsonBytes),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+)
+
+func TestNameFilter(t *testing.T) {
+	want := bson.D{{"$or", bson.A{
+		bson.D{{"name", bson.D{{"$in", bson.A{"CERN", "Cern"}}}}},
+		bson.D{{"canonicalName", bson.D{{"$in", bson.A{"CERN", "Cern"}}}}},
+		bson.D{{"aliases", bson.D{{"$in", bson.A{"CERN", "Cern"}}}}},
+	}}}
+	if got := nameFilter([]string{"CERN", "", "Cern"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("nameFilter = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"strings"
+)
+
+// MongoROR is one organisation from the ROR data dump, stored in the ror
+// collection so affiliations can be mapped to it
+type MongoROR struct {
+	ID          string   `bson:"_id" json:"id"`
+	Name        string   `bson:"name" json:"name"`
+	Aliases     []string `bson:"aliases" json:"aliases,omitempty"`
+	Acronyms    []string `bson:"acronyms" json:"acronyms,omitempty"`
+	City        string   `bson:"city" json:"city"`
+	CountryName string   `bson:"countryName" json:"country_name"`
+	CountryCode string   `bson:"countryCode" json:"country_code"`
+}
+
+// RORRecord holds the fields we need from both the v1 and the v2 schema of
+// the ROR data dump
+type RORRecord struct {
+	ID string `json:"id"`
+
+	// v1 schema
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases"`
+	Acronyms []string `json:"acronyms"`
+	Labels   []struct {
+		Label string `json:"label"`
+	} `json:"labels"`
+	Country struct {
+		CountryCode string `json:"country_code"`
+		CountryName string `json:"country_name"`
+	} `json:"country"`
+	Addresses []struct {
+		City string `json:"city"`
+	} `json:"addresses"`
+
+	// v2 schema
+	Names []struct {
+		Value string   `json:"value"`
+		Types []string `json:"types"`
+	} `json:"names"`
+	Locations []struct {
+		GeonamesDetails struct {
+			Name        string `json:"name"`
+			CountryCode string `json:"country_code"`
+			CountryName string `json:"country_name"`
+		} `json:"geonames_details"`
+	} `json:"locations"`
+}
+
+// normaliseRORID accepts both "https://ror.org/01ggx4157" and "01ggx4157"
+func normaliseRORID(id string) string {
+	id = strings.TrimSpace(id)
+	id = strings.TrimPrefix(id, "https://ror.org/")
+	id = strings.TrimPrefix(id, "http://ror.org/")
+	return strings.ToLower(id)
+}
+
+func hasType(types []string, wanted string) bool {
+	for _, t := range types {
+		if t == wanted {
+			return true
+		}
+	}
+	return false
+}
+
+func recordToMongo(record RORRecord) MongoROR {
+	ror := MongoROR{
+		ID:          normaliseRORID(record.ID),
+		Name:        record.Name,
+		Aliases:     record.Aliases,
+		Acronyms:    record.Acronyms,
+		CountryName: record.Country.CountryName,
+		CountryCode: record.Country.CountryCode,
+	}
+	for _, label := range record.Labels {
+		ror.Aliases = append(ror.Aliases, label.Label)
+	}
+	if len(record.Addresses) > 0 {
+		ror.City = record.Addresses[0].City
+	}
+
+	for _, name := range record.Names {
+		switch {
+		case hasType(name.Types, "ror_display"):
+			ror.Name = name.Value
+		case hasType(name.Types, "acronym"):
+			ror.Acronyms = append(ror.Acronyms, name.Value)
+		default:
+			ror.Aliases = append(ror.Aliases, name.Value)
+		}
+	}
+	if len(record.Locations) > 0 {
+		location := record.Locations[0].GeonamesDetails
+		ror.City = location.Name
+		ror.CountryName = location.CountryName
+		ror.CountryCode = location.CountryCode
+	}
+	return ror
+}
+
+// loadRORDump reads the JSON file of a ROR data dump one record at a time
+// and upserts every organisation into the ror collection
+func loadRORDump(collection *mongo.Collection, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("error opening ROR dump: %s", err.Error())
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	if _, err := decoder.Token(); err != nil {
+		return 0, fmt.Errorf("error reading ROR dump: %s", err.Error())
+	}
+
+	var operations []mongo.WriteModel
+	loaded := 0
+	flush := func() error {
+		if len(operations) == 0 {
+			return nil
+		}
+		_, err := collection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return fmt.Errorf("error saving ROR organisations: %s", err.Error())
+		}
+		loaded += len(operations)
+		operations = nil
+		return nil
+	}
+
+	for decoder.More() {
+		var record RORRecord
+		if err := decoder.Decode(&record); err != nil {
+			return loaded, fmt.Errorf("error decoding ROR record: %s", err.Error())
+		}
+		ror := recordToMongo(record)
+		operation := mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{"_id", ror.ID}}).
+			SetReplacement(ror).
+			SetUpsert(true)
+		operations = append(operations, operation)
+		if len(operations) == 1000 {
+			if err := flush(); err != nil {
+				return loaded, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return loaded, err
+	}
+
+	_, err = collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{"name", 1}}, Options: options.Index().SetCollation(nameCollation)},
+		{Keys: bson.D{{"aliases", 1}}, Options: options.Index().SetCollation(nameCollation)},
+		{Keys: bson.D{{"acronyms", 1}}, Options: options.Index().SetCollation(nameCollation)},
+	})
+	if err != nil {
+		return loaded, fmt.Errorf("error creating ROR indexes: %s", err.Error())
+	}
+	return loaded, nil
+}
+
+// rorCandidates suggests ROR organisations whose name, alias or acronym
+// matches one of the names of an affiliation
+func rorCandidates(collection *mongo.Collection, names []string) ([]MongoROR, error) {
+	values := bson.A{}
+	for _, name := range names {
+		if name != "" {
+			values = append(values, name)
+		}
+	}
+	filter := bson.D{{"$or", bson.A{
+		bson.D{{"name", bson.D{{"$in", values}}}},
+		bson.D{{"aliases", bson.D{{"$in", values}}}},
+		bson.D{{"acronyms", bson.D{{"$in", values}}}},
+	}}}
+	findOptions := options.Find().SetCollation(nameCollation).SetLimit(10)
+	cursor, findError := collection.Find(context.Background(), filter, findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding ROR organisations: %s", findError.Error())
+	}
+	var candidates []MongoROR
+	if err := cursor.All(context.Background(), &candidates); err != nil {
+		return nil, fmt.Errorf("error decoding ROR organisations: %s", err.Error())
+	}
+	return candidates, nil
+}
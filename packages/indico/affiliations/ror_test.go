@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNormaliseRORID(t *testing.T) {
+	tests := map[string]string{
+		"":                           "",
+		"01ggx4157":                  "01ggx4157",
+		" https://ror.org/01GGX4157": "01ggx4157",
+		"http://ror.org/01ggx4157":   "01ggx4157",
+	}
+	for id, want := range tests {
+		if got := normaliseRORID(id); got != want {
+			t.Errorf("normaliseRORID(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestHasType(t *testing.T) {
+	tests := []struct {
+		types  []string
+		wanted string
+		want   bool
+	}{
+		{nil, "acronym", false},
+		{[]string{"label", "ror_display"}, "ror_display", true},
+		{[]string{"label"}, "acronym", false},
+	}
+	for _, test := range tests {
+		if got := hasType(test.types, test.wanted); got != test.want {
+			t.Errorf("hasType(%q, %q) = %v, want %v", test.types, test.wanted, got, test.want)
+		}
+	}
+}
+
+func TestRecordToMongo(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		want   MongoROR
+	}{
+		{
+			name: "v1 schema",
+			record: `{
+				"id": "https://ror.org/01ggx4157",
+				"name": "European Organization for Nuclear Research",
+				"aliases": ["Conseil Européen pour la Recherche Nucléaire"],
+				"acronyms": ["CERN"],
+				"labels": [{"label": "Organisation européenne pour la recherche nucléaire", "iso639": "fr"}],
+				"country": {"country_code": "CH", "country_name": "Switzerland"},
+				"addresses": [{"city": "Geneva"}]
+			}`,
+			want: MongoROR{
+				ID:          "01ggx4157",
+				Name:        "European Organization for Nuclear Research",
+				Aliases:     []string{"Conseil Européen pour la Recherche Nucléaire", "Organisation européenne pour la recherche nucléaire"},
+				Acronyms:    []string{"CERN"},
+				City:        "Geneva",
+				CountryName: "Switzerland",
+				CountryCode: "CH",
+			},
+		},
+		{
+			name: "v2 schema",
+			record: `{
+				"id": "https://ror.org/05j7fep28",
+				"names": [
+					{"value": "ANSTO", "types": ["acronym"]},
+					{"value": "Australian Nuclear Science and Technology Organisation", "types": ["ror_display", "label"]},
+					{"value": "Australian Atomic Energy Commission", "types": ["alias"]}
+				],
+				"locations": [{"geonames_details": {"name": "Sydney", "country_code": "AU", "country_name": "Australia"}}]
+			}`,
+			want: MongoROR{
+				ID:          "05j7fep28",
+				Name:        "Australian Nuclear Science and Technology Organisation",
+				Aliases:     []string{"Australian Atomic Energy Commission"},
+				Acronyms:    []string{"ANSTO"},
+				City:        "Sydney",
+				CountryName: "Australia",
+				CountryCode: "AU",
+			},
+		},
+	}
+	for _, test := range tests {
+		var record RORRecord
+		if err := json.Unmarshal([]byte(test.record), &record); err != nil {
+			t.Fatalf("%s: %s", test.name, err.Error())
+		}
+		if got := recordToMongo(record); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: recordToMongo =\n%+v\nwant\n%+v", test.name, got, test.want)
+		}
+	}
+}
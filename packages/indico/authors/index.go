@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/text/unicode/norm"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type MongoConference struct {
+	ID    int       `bson:"_id"`
+	Name  string    `bson:"name"`
+	Start time.Time `bson:"start"`
+}
+
+type MongoAuthorIndexEntry struct {
+	ConferenceId   int    `bson:"conferenceId"`
+	ContributionID int    `bson:"contributionId"`
+	Code           string `bson:"code"`
+	Title          string `bson:"title"`
+	PersonID       int    `bson:"personId,omitempty"`
+	FirstName      string `bson:"firstName"`
+	LastName       string `bson:"lastName"`
+	AuthorType     string `bson:"authorType"`
+	IsSpeaker      bool   `bson:"isSpeaker"`
+	Affiliation    string `bson:"affiliation"`
+	AuthorID       string `bson:"authorId,omitempty"`
+	ORCID          string `bson:"orcid,omitempty"`
+}
+
+type Request struct {
+	Name   string      `json:"name"`
+	Person string      `json:"person"`
+	Author string      `json:"author"`
+	HTTP   HTTPRequest `json:"http"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+type AuthorContribution struct {
+	ConferenceID   int       `json:"conference_id"`
+	ConferenceName string    `json:"conference_name"`
+	ConferenceDate time.Time `json:"conference_date"`
+	ContributionID int       `json:"contribution_id"`
+	Code           string    `json:"code"`
+	Title          string    `json:"title"`
+	PersonID       int       `json:"person_id,omitempty"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	AuthorType     string    `json:"author_type"`
+	IsSpeaker      bool      `json:"is_speaker"`
+	Affiliation    string    `json:"affiliation"`
+	AuthorID       string    `json:"author_id,omitempty"`
+	ORCID          string    `json:"orcid,omitempty"`
+}
+
+// normaliseName matches the normalisation used when the contributions sync
+// builds the author_index collection
+func normaliseName(name string) string {
+	var out strings.Builder
+	for _, r := range norm.NFD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			out.WriteRune(unicode.ToLower(r))
+		default:
+			out.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(out.String()), " ")
+}
+
+// authorFilter finds an author by resolved author id, by indico person id,
+// by full name, or by family name when only one name is given
+func authorFilter(in Request) (bson.D, error) {
+	if in.Author != "" {
+		return bson.D{{"authorId", in.Author}}, nil
+	}
+	if in.Person != "" {
+		personId, err := strconv.Atoi(in.Person)
+		if err != nil {
+			return nil, fmt.Errorf("error converting person id to int: %s", err.Error())
+		}
+		return bson.D{{"personId", personId}}, nil
+	}
+	name := normaliseName(in.Name)
+	if name == "" {
+		return nil, errors.New("a name, person or author is required")
+	}
+	if !strings.Contains(name, " ") {
+		return bson.D{{"normalisedLastName", name}}, nil
+	}
+	return bson.D{{"normalisedName", name}}, nil
+}
+
+func findConferences(database *mongo.Database, entries []MongoAuthorIndexEntry) (map[int]MongoConference, error) {
+	ids := bson.A{}
+	for _, entry := range entries {
+		ids = append(ids, entry.ConferenceId)
+	}
+	cursor, findError := database.Collection("conferences").Find(context.Background(), bson.D{{"_id", bson.D{{"$in", ids}}}})
+	if findError != nil {
+		return nil, fmt.Errorf("error finding conferences: %s", findError.Error())
+	}
+	var conferences []MongoConference
+	if err := cursor.All(context.Background(), &conferences); err != nil {
+		return nil, fmt.Errorf("error decoding conferences: %s", err.Error())
+	}
+	byId := make(map[int]MongoConference)
+	for _, conference := range conferences {
+		byId[conference.ID] = conference
+	}
+	return byId, nil
+}
+
+// Main checks the API key and rate limit of the request before responding
+func Main(in Request) (*Response, error) {
+	return scopedAuthorized(in.HTTP, func(key MongoAPIKey) (*Response, error) {
+		return respond(in, key)
+	})
+}
+
+// respond lists the contributions of the author, only in the conferences of
+// the key when it is scoped
+func respond(in Request, key MongoAPIKey) (*Response, error) {
+	filter, err := authorFilter(in)
+	if err != nil {
+		return nil, err
+	}
+	filter = append(filter, scopeFilter(key, "conferenceId")...)
+
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	database := client.Database("author-title")
+
+	cursor, findError := database.Collection("author_index").Find(context.Background(), filter)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding authors: %s", findError.Error())
+	}
+	var entries []MongoAuthorIndexEntry
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		return nil, fmt.Errorf("error decoding authors: %s", err.Error())
+	}
+
+	conferences, err := findConferences(database, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	var output = make([]AuthorContribution, 0)
+	for _, entry := range entries {
+		conference := conferences[entry.ConferenceId]
+		output = append(output, AuthorContribution{
+			ConferenceID:   entry.ConferenceId,
+			ConferenceName: conference.Name,
+			ConferenceDate: conference.Start,
+			ContributionID: entry.ContributionID,
+			Code:           entry.Code,
+			Title:          entry.Title,
+			PersonID:       entry.PersonID,
+			FirstName:      entry.FirstName,
+			LastName:       entry.LastName,
+			AuthorType:     entry.AuthorType,
+			IsSpeaker:      entry.IsSpeaker,
+			Affiliation:    entry.Affiliation,
+			AuthorID:       entry.AuthorID,
+			ORCID:          entry.ORCID,
+		})
+	}
+	// Most recent conferences first
+	sort.SliceStable(output, func(i, j int) bool {
+		if !output[i].ConferenceDate.Equal(output[j].ConferenceDate) {
+			return output[i].ConferenceDate.After(output[j].ConferenceDate)
+		}
+		return output[i].Code < output[j].Code
+	})
+
+	jsonBytes, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling documents: %s", err.Error())
+	}
+	return &Response{
+		Body: string(jsonBytes),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+)
+
+func TestNormaliseName(t *testing.T) {
+	tests := map[string]string{
+		"":                 "",
+		"Ada Lovelace":     "ada lovelace",
+		"Müller,  J.-P.":   "muller j p",
+		"  O'Neil ":        "o neil",
+		"Ирина Иванова":    "ирина иванова",
+		"Παπαδόπουλος":     "παπαδοπουλος",
+		"Nguyễn Văn An":    "nguyen van an",
+		"ALBERT EINSTEIN2": "albert einstein2",
+	}
+	for name, want := range tests {
+		if got := normaliseName(name); got != want {
+			t.Errorf("normaliseName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestAuthorFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Request
+		want bson.D
+		err  bool
+	}{
+		{"author id", Request{Author: "65a1b2", Person: "12", Name: "Ada"}, bson.D{{"authorId", "65a1b2"}}, false},
+		{"person id", Request{Person: "12", Name: "Ada"}, bson.D{{"personId", 12}}, false},
+		{"invalid person id", Request{Person: "ada"}, nil, true},
+		{"full name", Request{Name: "Émile  Müller"}, bson.D{{"normalisedName", "emile muller"}}, false},
+		{"family name", Request{Name: "Müller"}, bson.D{{"normalisedLastName", "muller"}}, false},
+		{"no name", Request{Name: " ., "}, nil, true},
+		{"nothing", Request{}, nil, true},
+	}
+	for _, test := range tests {
+		got, err := authorFilter(test.in)
+		if (err != nil) != test.err {
+			t.Errorf("%s: authorFilter error %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: authorFilter = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestScopedKey(t *testing.T) {
+	var filter bson.Raw
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		switch collection {
+		case "api_keys":
+			return []interface{}{MongoAPIKey{ID: hashAPIKey("imw_scoped"), Conferences: []int{58}}}
+		case "author_index":
+			filter = body.Lookup("filter").Document()
+		}
+		return nil
+	})
+	response, err := Main(Request{Name: "Ada Lovelace", HTTP: HTTPRequest{Headers: map[string]string{"X-API-Key": "imw_scoped"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != 0 {
+		t.Fatalf("a scoped key gets %d, want its conferences", response.StatusCode)
+	}
+	var got bson.D
+	if err := bson.Unmarshal(filter, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := bson.D{{"normalisedName", "ada lovelace"}, {"conferenceId", bson.D{{"$in", bson.A{int32(58)}}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("author_index filter = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+// TestResponseFixture keeps testdata/response.json the same as the response
+// of authors. Run go test -update after changing the output.
+func TestResponseFixture(t *testing.T) {
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		switch collection {
+		case "author_index":
+			return []interface{}{MongoAuthorIndexEntry{
+				ConferenceId:   41,
+				ContributionID: 7,
+				Code:           "TUPA071",
+				Title:          "Beam loss monitors for the storage ring",
+				PersonID:       1203,
+				FirstName:      "Ada",
+				LastName:       "Lovelace",
+				AuthorType:     "primary",
+				IsSpeaker:      true,
+				Affiliation:    "ANSTO",
+				AuthorID:       "a1b2",
+				ORCID:          "0000-0002-1825-0097",
+			}}
+		case "conferences":
+			return []interface{}{MongoConference{ID: 41, Name: "IPAC'24", Start: time.Date(2024, 5, 19, 0, 0, 0, 0, time.UTC)}}
+		}
+		return nil
+	})
+	response, err := Main(Request{Name: "Ada Lovelace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponse(t, "testdata/response.json", response)
+}
@@ -15,8 +15,9 @@ type MongoConference struct {
 	Name string `bson:"name"`
 }
 type Request struct {
-	Conference string `json:"conference"`
-	Code       string `json:"code"`
+	Conference string      `json:"conference"`
+	Code       string      `json:"code"`
+	HTTP       HTTPRequest `json:"http"`
 }
 
 type Response struct {
@@ -25,7 +26,14 @@ type Response struct {
 	Body       string            `json:"body,omitempty"`
 }
 
+// Main checks the API key and rate limit of the request before responding
 func Main(in Request) (*Response, error) {
+	return authorized(in.HTTP, in.Conference, func() (*Response, error) {
+		return respond(in)
+	})
+}
+
+func respond(in Request) (*Response, error) {
 	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
 	client, connectErr := mongo.Connect(context.Background(), clientOptions)
 	if connectErr != nil {
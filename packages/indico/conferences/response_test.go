@@ -0,0 +1,25 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+// TestResponseFixture keeps testdata/response.json the same as the response
+// of conferences. Run go test -update after changing the output.
+func TestResponseFixture(t *testing.T) {
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		if collection != "conferences" {
+			return nil
+		}
+		return []interface{}{
+			bson.D{{"_id", 41}, {"name", "IPAC'24"}, {"start", "2024-05-19"}},
+			bson.D{{"_id", 58}, {"name", "LINAC2024"}},
+		}
+	})
+	response, err := Main(Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponse(t, "testdata/response.json", response)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strings"
+	"time"
+)
+
+// RegisteredAffiliation is what the contributions sync knows about an
+// affiliation in the affiliations collection. The canonical name, aliases
+// and ROR id are curated by editors and only seeded here.
+type RegisteredAffiliation struct {
+	Link    MongoAffiliationLink
+	Aliases []string
+}
+
+// registeredAffiliations collects the indico affiliations linked by the
+// persons of a conference, with the free text spellings used for each
+func registeredAffiliations(contributions []IndexedContribution) map[int]*RegisteredAffiliation {
+	affiliations := make(map[int]*RegisteredAffiliation)
+	for _, contribution := range contributions {
+		for _, person := range contribution.Persons {
+			link := person.AffiliationLink
+			if link.ID == 0 {
+				continue
+			}
+			affiliation, found := affiliations[link.ID]
+			if !found {
+				affiliation = &RegisteredAffiliation{Link: link}
+				affiliations[link.ID] = affiliation
+			}
+			spelling := strings.TrimSpace(person.Affiliation)
+			if spelling != "" && spelling != link.Name {
+				affiliation.Aliases = appendUniqueString(affiliation.Aliases, spelling)
+			}
+		}
+	}
+	return affiliations
+}
+
+// indexAffiliations adds the affiliations linked in a conference to the
+// affiliations collection, keyed by indico affiliation id
+func indexAffiliations(database *mongo.Database, conferenceId int) error {
+	findOptions := options.Find().SetProjection(bson.D{{"persons", 1}})
+	cursor, findError := database.Collection("contributions").Find(context.Background(), bson.D{{"conferenceId", conferenceId}}, findOptions)
+	if findError != nil {
+		return fmt.Errorf("error finding contributions: %s", findError.Error())
+	}
+	var contributions []IndexedContribution
+	if err := cursor.All(context.Background(), &contributions); err != nil {
+		return fmt.Errorf("error decoding contributions: %s", err.Error())
+	}
+
+	var operations []mongo.WriteModel
+	now := time.Now()
+	for id, affiliation := range registeredAffiliations(contributions) {
+		aliases := bson.A{}
+		for _, alias := range affiliation.Aliases {
+			aliases = append(aliases, alias)
+		}
+		operation := mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{"_id", id}}).
+			SetUpdate(bson.D{
+				{"$set", bson.D{
+					{"name", affiliation.Link.Name},
+					{"city", affiliation.Link.City},
+					{"countryName", affiliation.Link.CountryName},
+					{"countryCode", affiliation.Link.CountryCode},
+					{"postcode", affiliation.Link.Postcode},
+					{"updatedAt", now},
+				}},
+				{"$setOnInsert", bson.D{{"canonicalName", affiliation.Link.Name}}},
+				{"$addToSet", bson.D{{"aliases", bson.D{{"$each", aliases}}}}},
+			}).
+			SetUpsert(true)
+		operations = append(operations, operation)
+	}
+	if len(operations) == 0 {
+		return nil
+	}
+	_, err := database.Collection("affiliations").BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return fmt.Errorf("error updating affiliations: %s", err.Error())
+	}
+	return nil
+}
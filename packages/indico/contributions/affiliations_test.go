@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegisteredAffiliations(t *testing.T) {
+	cern := MongoAffiliationLink{ID: 4, Name: "CERN"}
+	ansto := MongoAffiliationLink{ID: 3, Name: "ANSTO"}
+	contributions := []IndexedContribution{
+		{Persons: []MongoPerson{
+			{Affiliation: "CERN", AffiliationLink: cern},
+			{Affiliation: " Cern, Geneva ", AffiliationLink: cern},
+			{Affiliation: "Unlinked Laboratory"},
+		}},
+		{Persons: []MongoPerson{
+			{Affiliation: "Cern, Geneva", AffiliationLink: cern},
+			{AffiliationLink: ansto},
+		}},
+	}
+	want := map[int]*RegisteredAffiliation{
+		4: {Link: cern, Aliases: []string{"Cern, Geneva"}},
+		3: {Link: ansto},
+	}
+	if got := registeredAffiliations(contributions); !reflect.DeepEqual(got, want) {
+		t.Errorf("registeredAffiliations = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type MongoTimetablePerson struct {
+	FirstName   string `bson:"firstName"`
+	FamilyName  string `bson:"familyName"`
+	Affiliation string `bson:"affiliation"`
+}
+
+// IndexedContribution is the part of a stored contribution needed to index
+// its authors
+type IndexedContribution struct {
+	ID         int                     `bson:"_id"`
+	Code       string                  `bson:"code"`
+	Title      string                  `bson:"title"`
+	Presenters *[]MongoTimetablePerson `bson:"presenters,omitempty"`
+	Authors    *[]MongoTimetablePerson `bson:"authors,omitempty"`
+	Persons    []MongoPerson           `bson:"persons"`
+}
+
+type MongoAuthorIndexEntry struct {
+	ConferenceId       int       `bson:"conferenceId"`
+	ContributionID     int       `bson:"contributionId"`
+	Code               string    `bson:"code"`
+	Title              string    `bson:"title"`
+	PersonID           int       `bson:"personId,omitempty"`
+	FirstName          string    `bson:"firstName"`
+	LastName           string    `bson:"lastName"`
+	NormalisedName     string    `bson:"normalisedName"`
+	NormalisedLastName string    `bson:"normalisedLastName"`
+	AuthorType         string    `bson:"authorType"`
+	IsSpeaker          bool      `bson:"isSpeaker"`
+	Affiliation        string    `bson:"affiliation"`
+	AffiliationID      int       `bson:"affiliationId,omitempty"`
+	AuthorID           string    `bson:"authorId,omitempty"`
+	ORCID              string    `bson:"orcid,omitempty"`
+	IndexedAt          time.Time `bson:"indexedAt"`
+}
+
+// normaliseName lowercases a name, strips accents and punctuation and
+// collapses whitespace, so "Müller,  J.-P." becomes "muller j p"
+func normaliseName(name string) string {
+	var out strings.Builder
+	for _, r := range norm.NFD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			out.WriteRune(unicode.ToLower(r))
+		default:
+			out.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(out.String()), " ")
+}
+
+func authorIndexEntries(conferenceId int, contribution IndexedContribution, resolver *identityResolver, indexedAt time.Time) []MongoAuthorIndexEntry {
+	entry := func(firstName string, lastName string) MongoAuthorIndexEntry {
+		return MongoAuthorIndexEntry{
+			ConferenceId:       conferenceId,
+			ContributionID:     contribution.ID,
+			Code:               contribution.Code,
+			Title:              contribution.Title,
+			FirstName:          firstName,
+			LastName:           lastName,
+			NormalisedName:     normaliseName(firstName + " " + lastName),
+			NormalisedLastName: normaliseName(lastName),
+			IndexedAt:          indexedAt,
+		}
+	}
+
+	var entries []MongoAuthorIndexEntry
+	if len(contribution.Persons) > 0 {
+		for _, person := range contribution.Persons {
+			indexEntry := entry(person.FirstName, person.LastName)
+			indexEntry.PersonID = person.ID
+			indexEntry.AuthorType = person.AuthorType
+			indexEntry.IsSpeaker = person.IsSpeaker
+			indexEntry.Affiliation = person.Affiliation
+			indexEntry.AffiliationID = person.AffiliationLink.ID
+			identity := resolver.resolve(person)
+			indexEntry.AuthorID = identity.ID
+			indexEntry.ORCID = identity.ORCID
+			entries = append(entries, indexEntry)
+		}
+		return entries
+	}
+
+	// Without details from indico, fall back to the timetable
+	if contribution.Presenters != nil {
+		for _, presenter := range *contribution.Presenters {
+			indexEntry := entry(presenter.FirstName, presenter.FamilyName)
+			indexEntry.IsSpeaker = true
+			indexEntry.Affiliation = presenter.Affiliation
+			entries = append(entries, indexEntry)
+		}
+	}
+	if contribution.Authors != nil {
+		for _, author := range *contribution.Authors {
+			indexEntry := entry(author.FirstName, author.FamilyName)
+			indexEntry.Affiliation = author.Affiliation
+			entries = append(entries, indexEntry)
+		}
+	}
+	return entries
+}
+
+// indexAuthors rebuilds the author_index entries of a conference from its
+// stored contributions, resolving each person to an identity
+func indexAuthors(database *mongo.Database, conferenceId int) error {
+	cursor, findError := database.Collection("contributions").Find(context.Background(), bson.D{{"conferenceId", conferenceId}})
+	if findError != nil {
+		return fmt.Errorf("error finding contributions: %s", findError.Error())
+	}
+	var contributions []IndexedContribution
+	if err := cursor.All(context.Background(), &contributions); err != nil {
+		return fmt.Errorf("error decoding contributions: %s", err.Error())
+	}
+
+	var persons []MongoPerson
+	for _, contribution := range contributions {
+		persons = append(persons, contribution.Persons...)
+	}
+	identities := database.Collection("identities")
+	resolver, err := loadIdentities(identities, persons)
+	if err != nil {
+		return err
+	}
+
+	indexedAt := time.Now()
+	var entries []interface{}
+	for _, contribution := range contributions {
+		for _, entry := range authorIndexEntries(conferenceId, contribution, resolver, indexedAt) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := resolver.save(identities); err != nil {
+		return err
+	}
+
+	collection := database.Collection("author_index")
+	_, err = collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{"normalisedName", 1}}},
+		{Keys: bson.D{{"normalisedLastName", 1}}},
+		{Keys: bson.D{{"personId", 1}}},
+		{Keys: bson.D{{"authorId", 1}}},
+		{Keys: bson.D{{"conferenceId", 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating author index indexes: %s", err.Error())
+	}
+	if _, err := collection.DeleteMany(context.Background(), bson.D{{"conferenceId", conferenceId}}); err != nil {
+		return fmt.Errorf("error clearing author index: %s", err.Error())
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if _, err := collection.InsertMany(context.Background(), entries, options.InsertMany().SetOrdered(false)); err != nil {
+		return fmt.Errorf("error inserting author index: %s", err.Error())
+	}
+	return nil
+}
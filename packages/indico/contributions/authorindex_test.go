@@ -0,0 +1,106 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormaliseName(t *testing.T) {
+	tests := map[string]string{
+		"":                 "",
+		"Ada Lovelace":     "ada lovelace",
+		"Müller,  J.-P.":   "muller j p",
+		"  O'Neil ":        "o neil",
+		"Ирина Иванова":    "ирина иванова",
+		"Παπαδόπουλος":     "παπαδοπουλος",
+		"Nguyễn Văn An":    "nguyen van an",
+		"ALBERT EINSTEIN2": "albert einstein2",
+	}
+	for name, want := range tests {
+		if got := normaliseName(name); got != want {
+			t.Errorf("normaliseName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestAuthorIndexEntriesFromTimetable(t *testing.T) {
+	indexedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	contribution := IndexedContribution{
+		ID:         7,
+		Code:       "TUPA071",
+		Title:      "Beam loss monitors",
+		Presenters: &[]MongoTimetablePerson{{FirstName: "Ada", FamilyName: "Lovelace", Affiliation: "ANSTO"}},
+		Authors:    &[]MongoTimetablePerson{{FirstName: "Émile", FamilyName: "Müller", Affiliation: "PSI"}},
+	}
+	want := []MongoAuthorIndexEntry{
+		{
+			ConferenceId:       41,
+			ContributionID:     7,
+			Code:               "TUPA071",
+			Title:              "Beam loss monitors",
+			FirstName:          "Ada",
+			LastName:           "Lovelace",
+			NormalisedName:     "ada lovelace",
+			NormalisedLastName: "lovelace",
+			IsSpeaker:          true,
+			Affiliation:        "ANSTO",
+			IndexedAt:          indexedAt,
+		},
+		{
+			ConferenceId:       41,
+			ContributionID:     7,
+			Code:               "TUPA071",
+			Title:              "Beam loss monitors",
+			FirstName:          "Émile",
+			LastName:           "Müller",
+			NormalisedName:     "emile muller",
+			NormalisedLastName: "muller",
+			Affiliation:        "PSI",
+			IndexedAt:          indexedAt,
+		},
+	}
+
+	// Timetable persons have no indico details to resolve an identity from
+	got := authorIndexEntries(41, contribution, nil, indexedAt)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("authorIndexEntries =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := authorIndexEntries(41, IndexedContribution{ID: 8}, nil, indexedAt); got != nil {
+		t.Errorf("authorIndexEntries of a contribution without persons = %+v", got)
+	}
+}
+
+func TestAuthorIndexEntriesFromPersons(t *testing.T) {
+	t.Setenv("EMAIL_POLICY", "hashed")
+	t.Setenv("EMAIL_HASH_KEY", testEmailHashKey)
+	contribution := IndexedContribution{
+		ID:         7,
+		Presenters: &[]MongoTimetablePerson{{FirstName: "Ignored", FamilyName: "Presenter"}},
+		Persons: []MongoPerson{
+			{ID: 1, FirstName: "Ada", LastName: "Lovelace", IsSpeaker: true, AuthorType: "primary", Affiliation: "ANSTO",
+				AffiliationLink: MongoAffiliationLink{ID: 3}, ORCID: "https://orcid.org/0000-0002-1825-0097"},
+			{ID: 2, FirstName: "Jean", LastName: "Dupont", AuthorType: "secondary"},
+		},
+	}
+	resolver := newIdentityResolver()
+	entries := authorIndexEntries(41, contribution, resolver, time.Now())
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want one per person: %+v", len(entries), entries)
+	}
+	ada, jean := entries[0], entries[1]
+	if ada.PersonID != 1 || !ada.IsSpeaker || ada.AuthorType != "primary" || ada.AffiliationID != 3 {
+		t.Errorf("entry = %+v, want the details of the person", ada)
+	}
+	if ada.ORCID != "0000-0002-1825-0097" || jean.ORCID != "" {
+		t.Errorf("ORCIDs = %q and %q", ada.ORCID, jean.ORCID)
+	}
+	if ada.AuthorID == "" || ada.AuthorID == jean.AuthorID {
+		t.Errorf("author ids = %q and %q, want two identities", ada.AuthorID, jean.AuthorID)
+	}
+
+	again := authorIndexEntries(42, IndexedContribution{ID: 8, Persons: []MongoPerson{{ID: 1, FirstName: "A.", LastName: "Lovelace"}}}, resolver, time.Now())
+	if again[0].AuthorID != ada.AuthorID {
+		t.Errorf("the same person in another conference is author %s, want %s", again[0].AuthorID, ada.AuthorID)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strings"
+	"time"
+)
+
+// MongoIdentity is one real person, clustered from the persons of every
+// contribution. Emails are only kept as hashes, see emailKey, and are only
+// used for matching.
+type MongoIdentity struct {
+	ID        string    `bson:"_id"`
+	FirstName string    `bson:"firstName"`
+	LastName  string    `bson:"lastName"`
+	ORCID     string    `bson:"orcid,omitempty"`
+	Emails    []string  `bson:"emails"`
+	PersonIDs []int     `bson:"personIds"`
+	NameKeys  []string  `bson:"nameKeys"`
+	Names     []string  `bson:"names"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// identityResolver matches persons to identities in memory, remembering
+// which identities changed so they can be written back in one go
+type identityResolver struct {
+	identities map[string]*MongoIdentity
+	byORCID    map[string]string
+	byEmail    map[string]string
+	byPersonID map[int]string
+	byNameKey  map[string]string
+	changed    map[string]bool
+}
+
+func appendUniqueString(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func appendUniqueInt(values []int, value int) []int {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// nameKey combines the full first and family name with the affiliation, so
+// the same name at the same institute is the same person. An initial could
+// stand for anyone, so "J. Smith" and "John Smith" are not matched by name,
+// and without an affiliation a name alone is too ambiguous to match on.
+func nameKey(firstName string, lastName string, affiliationId int, affiliation string) string {
+	first := normaliseName(firstName)
+	last := normaliseName(lastName)
+	if first == "" || last == "" {
+		return ""
+	}
+	institute := normaliseName(affiliation)
+	if affiliationId != 0 {
+		institute = fmt.Sprint(affiliationId)
+	}
+	if institute == "" {
+		return ""
+	}
+	return first + " " + last + "@" + institute
+}
+
+func normaliseORCID(orcid string) string {
+	orcid = strings.TrimSpace(orcid)
+	orcid = strings.TrimPrefix(orcid, "https://orcid.org/")
+	orcid = strings.TrimPrefix(orcid, "http://orcid.org/")
+	return strings.ToUpper(orcid)
+}
+
+// emailKey is the hash an email is matched on, whether it is stored hashed,
+// encrypted or, from before EMAIL_POLICY, in plaintext. Hashes from before
+// EMAIL_HASH_KEY can't be matched, and are dropped.
+func emailKey(stored string) string {
+	if stored == "" || strings.HasPrefix(stored, unkeyedEmailPrefix) {
+		return ""
+	}
+	if strings.HasPrefix(stored, hashedEmailPrefix) {
+		return stored
+	}
+	hashKey, err := emailHashKeyFromEnv()
+	if err != nil {
+		return ""
+	}
+	if strings.HasPrefix(stored, encryptedEmailPrefix) {
+		key, err := emailKeyFromEnv()
+		if err != nil {
+			return ""
+		}
+		email, err := decryptEmail(key, stored)
+		if err != nil {
+			return ""
+		}
+		return hashEmail(hashKey, email)
+	}
+	return hashEmail(hashKey, stored)
+}
+
+func newIdentityResolver() *identityResolver {
+	return &identityResolver{
+		identities: make(map[string]*MongoIdentity),
+		byORCID:    make(map[string]string),
+		byEmail:    make(map[string]string),
+		byPersonID: make(map[int]string),
+		byNameKey:  make(map[string]string),
+		changed:    make(map[string]bool),
+	}
+}
+
+// identityCandidatesFilter selects the identities which could match one of
+// the persons being resolved, on any of the evidence resolve uses
+func identityCandidatesFilter(persons []MongoPerson) bson.D {
+	orcids, emails, personIds, nameKeys := bson.A{}, bson.A{}, bson.A{}, bson.A{}
+	for _, person := range persons {
+		if orcid := normaliseORCID(person.ORCID); orcid != "" {
+			orcids = append(orcids, orcid)
+		}
+		if email := emailKey(person.Email); email != "" {
+			emails = append(emails, email)
+			// Identities from before EMAIL_POLICY have plaintext emails
+			if !strings.HasPrefix(person.Email, hashedEmailPrefix) && !strings.HasPrefix(person.Email, encryptedEmailPrefix) {
+				emails = append(emails, person.Email)
+			}
+		}
+		if person.ID != 0 {
+			personIds = append(personIds, person.ID)
+		}
+		if key := nameKey(person.FirstName, person.LastName, person.AffiliationLink.ID, person.Affiliation); key != "" {
+			nameKeys = append(nameKeys, key)
+		}
+	}
+	var candidates bson.A
+	for _, field := range []struct {
+		name   string
+		values bson.A
+	}{
+		{"orcid", orcids},
+		{"emails", emails},
+		{"personIds", personIds},
+		{"nameKeys", nameKeys},
+	} {
+		if len(field.values) > 0 {
+			candidates = append(candidates, bson.D{{field.name, bson.D{{"$in", field.values}}}})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return bson.D{{"$or", candidates}}
+}
+
+// loadIdentities loads the identities which could match the persons of the
+// conference being indexed, rather than every identity
+func loadIdentities(collection *mongo.Collection, persons []MongoPerson) (*identityResolver, error) {
+	resolver := newIdentityResolver()
+	filter := identityCandidatesFilter(persons)
+	if filter == nil {
+		return resolver, nil
+	}
+	cursor, findError := collection.Find(context.Background(), filter)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding identities: %s", findError.Error())
+	}
+	defer func(cursor *mongo.Cursor, ctx context.Context) {
+		_ = cursor.Close(ctx)
+	}(cursor, context.Background())
+	for cursor.Next(context.Background()) {
+		var identity MongoIdentity
+		if decodeErr := cursor.Decode(&identity); decodeErr != nil {
+			return nil, fmt.Errorf("error decoding identity: %s", decodeErr.Error())
+		}
+		// Identities from before EMAIL_POLICY have plaintext emails
+		var keys []string
+		for _, email := range identity.Emails {
+			if key := emailKey(email); key != "" {
+				keys = appendUniqueString(keys, key)
+			}
+		}
+		if strings.Join(keys, ",") != strings.Join(identity.Emails, ",") {
+			identity.Emails = keys
+			resolver.changed[identity.ID] = true
+		}
+		resolver.remember(&identity)
+	}
+	return resolver, nil
+}
+
+func (resolver *identityResolver) remember(identity *MongoIdentity) {
+	resolver.identities[identity.ID] = identity
+	if identity.ORCID != "" {
+		resolver.byORCID[identity.ORCID] = identity.ID
+	}
+	for _, email := range identity.Emails {
+		resolver.byEmail[email] = identity.ID
+	}
+	for _, personId := range identity.PersonIDs {
+		resolver.byPersonID[personId] = identity.ID
+	}
+	for _, key := range identity.NameKeys {
+		resolver.byNameKey[key] = identity.ID
+	}
+}
+
+// resolve finds the identity of a person, trying the strongest evidence
+// first, and creates a new identity when nothing matches
+func (resolver *identityResolver) resolve(person MongoPerson) *MongoIdentity {
+	orcid := normaliseORCID(person.ORCID)
+	email := emailKey(person.Email)
+	key := nameKey(person.FirstName, person.LastName, person.AffiliationLink.ID, person.Affiliation)
+
+	var id string
+	var found bool
+	if orcid != "" {
+		id, found = resolver.byORCID[orcid]
+	}
+	if !found && email != "" {
+		id, found = resolver.byEmail[email]
+	}
+	if !found && person.ID != 0 {
+		id, found = resolver.byPersonID[person.ID]
+	}
+	if !found && key != "" {
+		id, found = resolver.byNameKey[key]
+	}
+
+	identity := resolver.identities[id]
+	if !found {
+		identity = &MongoIdentity{ID: primitive.NewObjectID().Hex()}
+	}
+	identity.FirstName = person.FirstName
+	identity.LastName = person.LastName
+	if orcid != "" && identity.ORCID == "" {
+		identity.ORCID = orcid
+	}
+	if email != "" {
+		identity.Emails = appendUniqueString(identity.Emails, email)
+	}
+	if person.ID != 0 {
+		identity.PersonIDs = appendUniqueInt(identity.PersonIDs, person.ID)
+	}
+	if key != "" {
+		identity.NameKeys = appendUniqueString(identity.NameKeys, key)
+	}
+	identity.Names = appendUniqueString(identity.Names, normaliseName(person.FirstName+" "+person.LastName))
+	identity.UpdatedAt = time.Now()
+
+	resolver.remember(identity)
+	resolver.changed[identity.ID] = true
+	return identity
+}
+
+func (resolver *identityResolver) save(collection *mongo.Collection) error {
+	var operations []mongo.WriteModel
+	for id := range resolver.changed {
+		operation := mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{"_id", id}}).
+			SetReplacement(resolver.identities[id]).
+			SetUpsert(true)
+		operations = append(operations, operation)
+	}
+	if len(operations) == 0 {
+		return nil
+	}
+	_, err := collection.BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return fmt.Errorf("error saving identities: %s", err.Error())
+	}
+	return nil
+}
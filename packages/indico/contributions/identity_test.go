@@ -0,0 +1,179 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNameKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		firstName     string
+		lastName      string
+		affiliationId int
+		affiliation   string
+		want          string
+	}{
+		{"initial", "J.", "Smith", 0, "CERN", "j smith@cern"},
+		{"first name", "John", "Smith", 0, "CERN", "john smith@cern"},
+		{"two first names", "Jean-Pierre", "Dupont", 0, "CERN", "jean pierre dupont@cern"},
+		{"affiliation id", "John", "Smith", 12, "CERN", "john smith@12"},
+		{"accents", "Émile", "Müller", 0, "PSI", "emile muller@psi"},
+		{"cyrillic", "Ирина", "Иванова", 3, "", "ирина иванова@3"},
+		{"cyrillic short i", "Йосиф", "Петров", 3, "", "иосиф петров@3"},
+		{"greek", "Ωμέγα", "Παπαδόπουλος", 0, "Δημόκριτος", "ωμεγα παπαδοπουλος@δημοκριτος"},
+		{"han", "小明", "李", 5, "", "小明 李@5"},
+		{"no first name", "", "Smith", 0, "CERN", ""},
+		{"only punctuation", "-", "Smith", 0, "CERN", ""},
+		{"no affiliation", "John", "Smith", 0, "", ""},
+	}
+	for _, test := range tests {
+		got := nameKey(test.firstName, test.lastName, test.affiliationId, test.affiliation)
+		if got != test.want {
+			t.Errorf("%s: nameKey(%q, %q, %d, %q) = %q, want %q", test.name,
+				test.firstName, test.lastName, test.affiliationId, test.affiliation, got, test.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("%s: nameKey returned invalid UTF-8 %q", test.name, got)
+		}
+	}
+}
+
+func TestNormaliseORCID(t *testing.T) {
+	tests := map[string]string{
+		"":                                       "",
+		"0000-0002-1825-0097":                    "0000-0002-1825-0097",
+		" https://orcid.org/0000-0002-1694-233x": "0000-0002-1694-233X",
+		"http://orcid.org/0000-0002-1825-0097":   "0000-0002-1825-0097",
+	}
+	for orcid, want := range tests {
+		if got := normaliseORCID(orcid); got != want {
+			t.Errorf("normaliseORCID(%q) = %q, want %q", orcid, got, want)
+		}
+	}
+}
+
+// testHashEmail hashes an email with EMAIL_HASH_KEY, as protectEmail does
+func testHashEmail(t *testing.T, email string) string {
+	t.Helper()
+	key, err := emailHashKeyFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hashEmail(key, email)
+}
+
+func TestEmailKey(t *testing.T) {
+	t.Setenv("EMAIL_POLICY", "encrypted")
+	t.Setenv("EMAIL_ENCRYPTION_KEY", testEmailKey)
+	t.Setenv("EMAIL_HASH_KEY", testEmailHashKey)
+	encrypted := protectEmail("jane@example.org")
+	hashed := testHashEmail(t, "jane@example.org")
+
+	tests := []struct {
+		name   string
+		stored string
+		key    string
+		want   string
+	}{
+		{"empty", "", testEmailKey, ""},
+		{"hashed", hashed, testEmailKey, hashed},
+		{"hashed without a key", "sha256:13d855ce931073d4924ac377cda0e9a543908b9d6607727c8033d729c65eced6", testEmailKey, ""},
+		{"plaintext", "Jane@Example.org", testEmailKey, hashed},
+		{"encrypted", encrypted, testEmailKey, hashed},
+		{"encrypted without the key", encrypted, "", ""},
+		{"corrupt", encryptedEmailPrefix + "AAAA", testEmailKey, ""},
+	}
+	for _, test := range tests {
+		t.Setenv("EMAIL_ENCRYPTION_KEY", test.key)
+		if got := emailKey(test.stored); got != test.want {
+			t.Errorf("%s: emailKey(%q) = %q, want %q", test.name, test.stored, got, test.want)
+		}
+	}
+}
+
+func TestIdentityResolverResolve(t *testing.T) {
+	t.Setenv("EMAIL_POLICY", "hashed")
+	t.Setenv("EMAIL_HASH_KEY", testEmailHashKey)
+	ansto := MongoAffiliationLink{ID: 3, Name: "ANSTO"}
+	email := protectEmail("ada@example.org")
+	steps := []struct {
+		name     string
+		person   MongoPerson
+		identity string
+	}{
+		{"new person", MongoPerson{ID: 1, FirstName: "Ada", LastName: "Lovelace", Email: email, ORCID: "https://orcid.org/0000-0002-1825-0097", AffiliationLink: ansto}, "ada"},
+		{"same orcid", MongoPerson{ID: 2, FirstName: "A.", LastName: "Lovelace", ORCID: "0000-0002-1825-0097"}, "ada"},
+		{"same email", MongoPerson{ID: 3, FirstName: "Augusta", LastName: "Lovelace", Email: email}, "ada"},
+		{"same person id", MongoPerson{ID: 1, FirstName: "Ada", LastName: "King", AffiliationLink: ansto}, "ada"},
+		{"same name key", MongoPerson{FirstName: "ada", LastName: "King", AffiliationLink: ansto}, "ada"},
+		{"only an initial", MongoPerson{FirstName: "A.", LastName: "King", AffiliationLink: ansto}, "initial ada"},
+		{"other person", MongoPerson{ID: 9, FirstName: "Jean", LastName: "Dupont", AffiliationLink: ansto}, "jean"},
+		{"same name elsewhere", MongoPerson{FirstName: "Ada", LastName: "King", Affiliation: "CERN"}, "other ada"},
+		{"no evidence", MongoPerson{FirstName: "Ada", LastName: "King"}, "unmatched ada"},
+		{"orcid before person id", MongoPerson{ID: 9, FirstName: "Ada", LastName: "King", ORCID: "0000-0002-1825-0097"}, "ada"},
+	}
+	resolver := newIdentityResolver()
+	ids := make(map[string]string)
+	for _, step := range steps {
+		identity := resolver.resolve(step.person)
+		id, seen := ids[step.identity]
+		if !seen {
+			for other, otherId := range ids {
+				if otherId == identity.ID {
+					t.Errorf("%s: resolved to %s, want a new identity", step.name, other)
+				}
+			}
+			ids[step.identity] = identity.ID
+		} else if identity.ID != id {
+			t.Errorf("%s: resolved to a new identity, want %s", step.name, step.identity)
+		}
+		if identity.FirstName != step.person.FirstName || identity.LastName != step.person.LastName {
+			t.Errorf("%s: identity is named %s %s", step.name, identity.FirstName, identity.LastName)
+		}
+		if !resolver.changed[identity.ID] {
+			t.Errorf("%s: identity is not saved", step.name)
+		}
+	}
+
+	ada := resolver.identities[ids["ada"]]
+	if ada.ORCID != "0000-0002-1825-0097" {
+		t.Errorf("ORCID = %q", ada.ORCID)
+	}
+	if want := []string{testHashEmail(t, "ada@example.org")}; !reflect.DeepEqual(ada.Emails, want) {
+		t.Errorf("Emails = %q, want %q", ada.Emails, want)
+	}
+	if want := []int{1, 2, 3, 9}; !reflect.DeepEqual(ada.PersonIDs, want) {
+		t.Errorf("PersonIDs = %v, want %v", ada.PersonIDs, want)
+	}
+	if want := []string{"ada lovelace@3", "ada king@3"}; !reflect.DeepEqual(ada.NameKeys, want) {
+		t.Errorf("NameKeys = %q, want %q", ada.NameKeys, want)
+	}
+	if want := []string{"ada lovelace", "a lovelace", "augusta lovelace", "ada king"}; !reflect.DeepEqual(ada.Names, want) {
+		t.Errorf("Names = %q, want %q", ada.Names, want)
+	}
+}
+
+func TestIdentityCandidatesFilter(t *testing.T) {
+	t.Setenv("EMAIL_POLICY", "hashed")
+	t.Setenv("EMAIL_HASH_KEY", testEmailHashKey)
+	hashed := testHashEmail(t, "ada@example.org")
+	persons := []MongoPerson{
+		{ID: 1, FirstName: "Ada", LastName: "Lovelace", Email: hashed, ORCID: "https://orcid.org/0000-0002-1825-0097", AffiliationLink: MongoAffiliationLink{ID: 3}},
+		{FirstName: "Jean", LastName: "Dupont", Email: "Jean@Example.org"},
+	}
+	want := bson.D{{"$or", bson.A{
+		bson.D{{"orcid", bson.D{{"$in", bson.A{"0000-0002-1825-0097"}}}}},
+		bson.D{{"emails", bson.D{{"$in", bson.A{hashed, testHashEmail(t, "jean@example.org"), "Jean@Example.org"}}}}},
+		bson.D{{"personIds", bson.D{{"$in", bson.A{1}}}}},
+		bson.D{{"nameKeys", bson.D{{"$in", bson.A{"ada lovelace@3"}}}}},
+	}}}
+	if got := identityCandidatesFilter(persons); !reflect.DeepEqual(got, want) {
+		t.Errorf("identityCandidatesFilter = %v, want %v", got, want)
+	}
+	if got := identityCandidatesFilter([]MongoPerson{{FirstName: "Ada", LastName: "Lovelace"}}); got != nil {
+		t.Errorf("identityCandidatesFilter without evidence = %v, want nil", got)
+	}
+}
@@ -10,17 +10,19 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"reflect"
+	"strconv"
 	"sync"
 	"time"
 )
 
 type MongoConference struct {
-	ID  int       `bson:"_id"`
-	End time.Time `bson:"end"`
+	ID int `bson:"_id"`
 }
 
 type MongoContribution struct {
-	ID int `bson:"_id"`
+	ID   int    `bson:"_id"`
+	Code string `bson:"code"`
 }
 
 type IndicoCustomField struct {
@@ -47,6 +49,7 @@ type IndicoDetailedPerson struct {
 	AuthorType      string                `json:"author_type"`
 	Affiliation     string                `json:"affiliation"`
 	AffiliationLink IndicoAffiliationLink `json:"affiliation_link"`
+	ORCID           string                `json:"orcid"`
 }
 
 type IndicoType struct {
@@ -62,7 +65,9 @@ type IndicoDetailedContribution struct {
 }
 
 type Request struct {
-	Name string `json:"name"`
+	Name       string `json:"name"`
+	Conference string `json:"conference"`
+	Code       string `json:"code"`
 }
 
 type Response struct {
@@ -72,23 +77,53 @@ type Response struct {
 }
 
 type MongoAffiliationLink struct {
-	ID          int    `bson:"id"`
-	Name        string `bson:"name"`
-	City        string `bson:"city"`
-	CountryName string `bson:"country_name"`
-	CountryCode string `bson:"country_code"`
-	Postcode    string `bson:"postcode"`
+	ID          int    `bson:"id" json:"id"`
+	Name        string `bson:"name" json:"name"`
+	City        string `bson:"city" json:"city"`
+	CountryName string `bson:"country_name" json:"country_name"`
+	CountryCode string `bson:"country_code" json:"country_code"`
+	Postcode    string `bson:"postcode" json:"postcode"`
 }
 
 type MongoPerson struct {
-	ID              int                  `bson:"person_id"`
-	FirstName       string               `bson:"first_name"`
-	LastName        string               `bson:"last_name"`
-	Email           string               `bson:"email"`
-	IsSpeaker       bool                 `bson:"is_speaker"`
-	AuthorType      string               `bson:"author_type"`
-	Affiliation     string               `bson:"affiliation"`
-	AffiliationLink MongoAffiliationLink `bson:"affiliation_link"`
+	ID              int                  `bson:"person_id" json:"person_id"`
+	FirstName       string               `bson:"first_name" json:"first_name"`
+	LastName        string               `bson:"last_name" json:"last_name"`
+	Email           string               `bson:"email" json:"-"` // protected by EMAIL_POLICY
+	IsSpeaker       bool                 `bson:"is_speaker" json:"is_speaker"`
+	AuthorType      string               `bson:"author_type" json:"author_type"`
+	Affiliation     string               `bson:"affiliation" json:"affiliation"`
+	AffiliationLink MongoAffiliationLink `bson:"affiliation_link" json:"affiliation_link"`
+	ORCID           string               `bson:"orcid,omitempty" json:"orcid,omitempty"`
+}
+
+// MongoHistoryPerson is a MongoPerson as recorded in contribution history,
+// which never includes the email
+type MongoHistoryPerson struct {
+	ID              int                  `bson:"person_id" json:"person_id"`
+	FirstName       string               `bson:"first_name" json:"first_name"`
+	LastName        string               `bson:"last_name" json:"last_name"`
+	Email           string               `bson:"-" json:"-"`
+	IsSpeaker       bool                 `bson:"is_speaker" json:"is_speaker"`
+	AuthorType      string               `bson:"author_type" json:"author_type"`
+	Affiliation     string               `bson:"affiliation" json:"affiliation"`
+	AffiliationLink MongoAffiliationLink `bson:"affiliation_link" json:"affiliation_link"`
+	ORCID           string               `bson:"orcid,omitempty" json:"orcid,omitempty"`
+}
+
+// historyPersons drops the emails of persons, so a change of email alone is
+// not a change and is never recorded or sent to webhooks
+func historyPersons(persons []MongoPerson) []MongoHistoryPerson {
+	if persons == nil {
+		return nil
+	}
+	var public = make([]MongoHistoryPerson, 0, len(persons))
+	for _, person := range persons {
+		historyPerson := MongoHistoryPerson(person)
+		historyPerson.Email = ""
+		public = append(public, historyPerson)
+	}
+	return public
 }
 
 type DetailedMongoContribution struct {
@@ -132,8 +167,8 @@ func currentConferences() ([]int, error) {
 
 	collection := client.Database("author-title").Collection("conferences")
 
-	// Print the ID of all items in this collection
-	cursor, findError := collection.Find(context.Background(), bson.D{})
+	findOptions := options.Find().SetProjection(bson.D{{"_id", 1}})
+	cursor, findError := collection.Find(context.Background(), activeConferencesFilter(time.Now()), findOptions)
 	if findError != nil {
 		return nil, fmt.Errorf("error finding conferences: %s", findError.Error())
 	}
@@ -149,16 +184,18 @@ func currentConferences() ([]int, error) {
 			return nil, fmt.Errorf("error decoding conference: %s", decodeErr.Error())
 		}
 
-		if conference.End.After(time.Now()) {
-			ids = append(ids, conference.ID)
-		}
+		ids = append(ids, conference.ID)
 	}
 
 	return ids, nil
 }
 
-func getCurrentContributions(collection mongo.Collection, conferenceId int) ([]MongoContribution, error) {
-	cursor, findError := collection.Find(context.Background(), bson.D{{"conferenceId", conferenceId}})
+func getCurrentContributions(collection mongo.Collection, conferenceId int, code string) ([]MongoContribution, error) {
+	filter := bson.D{{"conferenceId", conferenceId}}
+	if code != "" {
+		filter = append(filter, bson.E{"code", code})
+	}
+	cursor, findError := collection.Find(context.Background(), filter)
 	if findError != nil {
 		return nil, fmt.Errorf("error finding contributions: %s", findError.Error())
 	}
@@ -182,7 +219,7 @@ func indicoPersonToMongoPerson(entry IndicoDetailedPerson) MongoPerson {
 		ID:          entry.ID,
 		FirstName:   entry.FirstName,
 		LastName:    entry.LastName,
-		Email:       entry.Email,
+		Email:       protectEmail(entry.Email),
 		IsSpeaker:   entry.IsSpeaker,
 		AuthorType:  entry.AuthorType,
 		Affiliation: entry.Affiliation,
@@ -194,6 +231,7 @@ func indicoPersonToMongoPerson(entry IndicoDetailedPerson) MongoPerson {
 			CountryCode: entry.AffiliationLink.CountryCode,
 			Postcode:    entry.AffiliationLink.Postcode,
 		},
+		ORCID: entry.ORCID,
 	}
 }
 
@@ -234,7 +272,30 @@ func indicoDetailedContributionToMongoContribution(entry IndicoDetailedContribut
 	}
 }
 
-func fetchAndUpdateDetails(conferenceId int, contributionId int, collection mongo.Collection) error {
+// contributionChanges lists the fields of a contribution which differ
+// between what is stored and what was fetched from indico
+func contributionChanges(existing DetailedMongoContribution, updated DetailedMongoContribution) []MongoFieldChange {
+	var changes []MongoFieldChange
+	compare := func(field string, old interface{}, new interface{}) {
+		if !reflect.DeepEqual(old, new) {
+			changes = append(changes, MongoFieldChange{Field: field, Old: old, New: new})
+		}
+	}
+	compare("persons", historyPersons(existing.Persons), historyPersons(updated.Persons))
+	compare("is_duplicate", existing.IsDuplicate, updated.IsDuplicate)
+	compare("contribution_type", existing.ContributionType, updated.ContributionType)
+	compare("funding_agency", existing.FundingAgency, updated.FundingAgency)
+	compare("footnotes", existing.Footnotes, updated.Footnotes)
+	return changes
+}
+
+// webhookFields are the changes which are sent to webhooks
+var webhookFields = map[string]bool{
+	"persons": true,
+}
+
+func fetchAndUpdateDetails(conferenceId int, contribution MongoContribution, collection mongo.Collection, run *syncRun) error {
+	contributionId := contribution.ID
 	detailsContributionContent, err := fetch(fmt.Sprintf("https://indico.jacow.org/event/%d/contributions/%d.json", conferenceId, contributionId))
 	if err != nil {
 		return err
@@ -243,39 +304,65 @@ func fetchAndUpdateDetails(conferenceId int, contributionId int, collection mong
 	if err := json.Unmarshal([]byte(detailsContributionContent), &detailedContribution); err != nil {
 		return fmt.Errorf("unable to parse json: %s", err.Error())
 	}
-	_, err = collection.UpdateOne(context.Background(), bson.D{{"_id", contributionId}}, bson.D{
-		{"$set", indicoDetailedContributionToMongoContribution(detailedContribution)},
-	})
+	updated := indicoDetailedContributionToMongoContribution(detailedContribution)
+	var existing DetailedMongoContribution
+	err = collection.FindOneAndUpdate(context.Background(), bson.D{{"_id", contributionId}}, bson.D{
+		{"$set", updated},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.Before)).Decode(&existing)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	changes := contributionChanges(existing, updated)
+	if len(changes) == 0 {
+		return nil
+	}
+	run.addUpdated(1)
+
+	// Contributions which have never had their details fetched have nothing
+	// worth recording as a change
+	if existing.Persons == nil && existing.ContributionType == "" {
+		return nil
+	}
+	history := MongoContributionHistory{
+		ContributionID: contributionId,
+		ConferenceId:   conferenceId,
+		Code:           contribution.Code,
+		Job:            "contributions",
+		SyncedAt:       time.Now(),
+		Changes:        changes,
+	}
+	if err := insertHistory(collection.Database(), []MongoContributionHistory{history}); err != nil {
+		return err
+	}
+
+	return notifyWebhooks(collection.Database(), history)
 }
 
-func fetchConferenceContributions(conferenceId int) error {
+func fetchConferenceContributions(conferenceId int, code string, run *syncRun) error {
 	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
 	client, connectErr := mongo.Connect(context.Background(), clientOptions)
 	if connectErr != nil {
 		return fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
 	}
 	collection := client.Database("author-title").Collection("contributions")
-	contributions, err := getCurrentContributions(*collection, conferenceId)
+	contributions, err := getCurrentContributions(*collection, conferenceId, code)
 
 	if err != nil {
 		return err
 	}
 	var wg sync.WaitGroup
 	maxWorkers := 8
-	idsChan := make(chan int, maxWorkers)
+	contributionsChan := make(chan MongoContribution, maxWorkers)
 
 	for i := 0; i < maxWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for id := range idsChan {
-				err := fetchAndUpdateDetails(conferenceId, id, *collection)
+			for contribution := range contributionsChan {
+				err := fetchAndUpdateDetails(conferenceId, contribution, *collection, run)
 				if err != nil {
+					run.addError(fmt.Errorf("contribution %d: %s", contribution.ID, err.Error()))
 					fmt.Printf("error fetching contribution details: %s", err.Error())
 				}
 			}
@@ -283,29 +370,87 @@ func fetchConferenceContributions(conferenceId int) error {
 	}
 
 	for _, contribution := range contributions {
-		idsChan <- contribution.ID
+		contributionsChan <- contribution
 	}
-	close(idsChan)
+	close(contributionsChan)
+	wg.Wait()
 
-	return nil
+	database := client.Database("author-title")
+	if err := indexAffiliations(database, conferenceId); err != nil {
+		return err
+	}
+	return indexAuthors(database, conferenceId)
 }
 
-func Main(in Request) (*Response, error) {
+// requestedConferences returns the conference asked for in the request, or
+// every current conference when none was given
+func requestedConferences(in Request) ([]int, error) {
+	if in.Conference == "" {
+		if in.Code != "" {
+			return nil, fmt.Errorf("a conference is required when syncing a single contribution")
+		}
+		return currentConferences()
+	}
+	conferenceId, err := strconv.Atoi(in.Conference)
+	if err != nil {
+		return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+	}
+	return []int{conferenceId}, nil
+}
 
-	ids, err := currentConferences()
+// syncRun collects the outcome of a run from the concurrent workers
+type syncRun struct {
+	sync.Mutex
+	MongoSyncRun
+}
+
+func (run *syncRun) addUpdated(count int64) {
+	run.Lock()
+	defer run.Unlock()
+	run.Updated += count
+}
+
+func (run *syncRun) addError(err error) {
+	run.Lock()
+	defer run.Unlock()
+	run.Errors = append(run.Errors, err.Error())
+}
+
+func syncContributions(in Request, run *syncRun) (*Response, error) {
+	if _, err := emailPolicyFromEnv(); err != nil {
+		return nil, err
+	}
+	if err := ensureSearchIndex(); err != nil {
+		return nil, err
+	}
+	ids, err := requestedConferences(in)
 	if err != nil {
-		return nil, fmt.Errorf("error finding current conferences: %s", err.Error())
+		return nil, fmt.Errorf("error finding conferences: %s", err.Error())
 	}
 
 	for _, id := range ids {
-		err := fetchConferenceContributions(id)
+		err := fetchConferenceContributions(id, in.Code, run)
 
 		if err != nil {
 			return nil, fmt.Errorf("error fetching conference contributions: %s", err.Error())
 		}
+		run.Conferences = append(run.Conferences, id)
 	}
 
 	return &Response{
 		Body: fmt.Sprintf("Updated details for %d conferences", len(ids)),
 	}, nil
 }
+
+func Main(in Request) (*Response, error) {
+	run := &syncRun{MongoSyncRun: MongoSyncRun{Job: "contributions", Start: time.Now()}}
+	response, err := syncContributions(in, run)
+	if err != nil {
+		run.addError(err)
+	}
+	run.End = time.Now()
+	if recordErr := recordSyncRun(run.MongoSyncRun); recordErr != nil {
+		fmt.Printf("error recording sync run: %s", recordErr.Error())
+	}
+	return response, err
+}
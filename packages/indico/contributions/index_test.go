@@ -0,0 +1,97 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+	"testing"
+)
+
+func TestContributionChanges(t *testing.T) {
+	jane := MongoPerson{ID: 7, FirstName: "Jane", LastName: "Smith", Email: "hmac:aa", Affiliation: "CERN"}
+	janeNewEmail := jane
+	janeNewEmail.Email = "enc:bXgpZ8QWjg569zTI"
+	janeSpeaker := jane
+	janeSpeaker.IsSpeaker = true
+
+	tests := []struct {
+		name     string
+		existing DetailedMongoContribution
+		updated  DetailedMongoContribution
+		fields   []string
+	}{
+		{
+			name:     "unchanged",
+			existing: DetailedMongoContribution{Persons: []MongoPerson{jane}, ContributionType: "Poster"},
+			updated:  DetailedMongoContribution{Persons: []MongoPerson{jane}, ContributionType: "Poster"},
+		},
+		{
+			name:     "email only",
+			existing: DetailedMongoContribution{Persons: []MongoPerson{jane}},
+			updated:  DetailedMongoContribution{Persons: []MongoPerson{janeNewEmail}},
+		},
+		{
+			name:     "speaker",
+			existing: DetailedMongoContribution{Persons: []MongoPerson{jane}},
+			updated:  DetailedMongoContribution{Persons: []MongoPerson{janeSpeaker}},
+			fields:   []string{"persons"},
+		},
+		{
+			name:     "details fetched",
+			existing: DetailedMongoContribution{},
+			updated:  DetailedMongoContribution{Persons: []MongoPerson{jane}, ContributionType: "Poster"},
+			fields:   []string{"persons", "contribution_type"},
+		},
+		{
+			name:     "footnotes",
+			existing: DetailedMongoContribution{Footnotes: "a"},
+			updated:  DetailedMongoContribution{Footnotes: "b", IsDuplicate: true},
+			fields:   []string{"is_duplicate", "footnotes"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes := contributionChanges(test.existing, test.updated)
+			var fields []string
+			for _, change := range changes {
+				fields = append(fields, change.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(test.fields, ",") {
+				t.Fatalf("changed fields are %v, want %v", fields, test.fields)
+			}
+			// The changes are stored as history, which must not hold emails
+			raw, err := bson.Marshal(MongoContributionHistory{Changes: changes})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(bson.Raw(raw).String(), "email") || strings.Contains(bson.Raw(raw).String(), "hmac:") {
+				t.Errorf("history holds an email: %s", bson.Raw(raw).String())
+			}
+		})
+	}
+}
+
+func TestRequestedConferences(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Request
+		want []int
+		err  string
+	}{
+		{name: "conference", in: Request{Conference: "41"}, want: []int{41}},
+		{name: "contribution", in: Request{Conference: "41", Code: "TUPA071"}, want: []int{41}},
+		{name: "contribution without a conference", in: Request{Code: "TUPA071"}, err: "a conference is required"},
+		{name: "bad conference", in: Request{Conference: "IPAC"}, err: "error converting conference id"},
+	}
+	for _, test := range tests {
+		got, err := requestedConferences(test.in)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil || len(got) != len(test.want) || got[0] != test.want[0] {
+			t.Errorf("%s: got %v, %v, want %v", test.name, got, err, test.want)
+		}
+	}
+}
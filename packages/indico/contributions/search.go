@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+)
+
+// searchIndex is the text index over contributions which the search
+// function queries. Titles weigh the most and descriptions the least. A
+// collection can only have one text index, so changing the fields or weights
+// means dropping it first.
+var searchIndex = mongo.IndexModel{
+	Keys: bson.D{
+		{"title", "text"},
+		{"description", "text"},
+		{"presenters.firstName", "text"},
+		{"presenters.familyName", "text"},
+		{"authors.firstName", "text"},
+		{"authors.familyName", "text"},
+		{"persons.first_name", "text"},
+		{"persons.last_name", "text"},
+	},
+	Options: options.Index().
+		SetName("search").
+		SetDefaultLanguage("english").
+		SetWeights(bson.D{
+			{"title", 10},
+			{"presenters.familyName", 5},
+			{"authors.familyName", 5},
+			{"persons.last_name", 5},
+			{"presenters.firstName", 2},
+			{"authors.firstName", 2},
+			{"persons.first_name", 2},
+			{"description", 1},
+		}),
+}
+
+// ensureSearchIndex creates the text index used by the search function,
+// once per sync rather than on every search
+func ensureSearchIndex() error {
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	collection := client.Database("author-title").Collection("contributions")
+	if _, err := collection.Indexes().CreateOne(context.Background(), searchIndex); err != nil {
+		return fmt.Errorf("error creating search index: %s", err.Error())
+	}
+	return nil
+}
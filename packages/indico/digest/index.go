@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"mime"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type MongoConference struct {
+	ID      int      `bson:"_id"`
+	Name    string   `bson:"name"`
+	Editors []string `bson:"editors"`
+}
+
+type MongoContribution struct {
+	ID          int                   `bson:"_id"`
+	Code        string                `bson:"code"`
+	Title       string                `bson:"title"`
+	Presenters  *[]MongoPerson        `bson:"presenters,omitempty"`
+	Authors     *[]MongoPerson        `bson:"authors,omitempty"`
+	Persons     []MongoDetailedPerson `bson:"persons"`
+	IsDuplicate bool                  `bson:"is_duplicate"`
+}
+
+type MongoPerson struct {
+	FirstName   string `bson:"firstName"`
+	FamilyName  string `bson:"familyName"`
+	Affiliation string `bson:"affiliation"`
+}
+
+type MongoAffiliationLink struct {
+	ID   int    `bson:"id"`
+	Name string `bson:"name"`
+}
+
+type MongoDetailedPerson struct {
+	AffiliationLink MongoAffiliationLink `bson:"affiliation_link"`
+}
+
+type Request struct {
+	Name       string `json:"name"`
+	Conference string `json:"conference"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+type Issue struct {
+	ContributionID int
+	Code           string
+	Title          string
+	Problem        string
+}
+
+func requestedConferences(database *mongo.Database, in Request) ([]MongoConference, error) {
+	filter := activeConferencesFilter(time.Now())
+	if in.Conference != "" {
+		conferenceId, err := strconv.Atoi(in.Conference)
+		if err != nil {
+			return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+		}
+		filter = bson.D{{"_id", conferenceId}}
+	}
+	cursor, findError := database.Collection("conferences").Find(context.Background(), filter)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding conferences: %s", findError.Error())
+	}
+	defer func(cursor *mongo.Cursor, ctx context.Context) {
+		_ = cursor.Close(ctx)
+	}(cursor, context.Background())
+	var conferences []MongoConference
+	if err := cursor.All(context.Background(), &conferences); err != nil {
+		return nil, fmt.Errorf("error decoding conferences: %s", err.Error())
+	}
+	return conferences, nil
+}
+
+func contributionIssues(contribution MongoContribution) []Issue {
+	var problems []string
+
+	if contribution.Code == "" {
+		problems = append(problems, "Missing code")
+	}
+	if contribution.IsDuplicate {
+		problems = append(problems, "Flagged as a duplicate")
+	}
+
+	// A presenter is usually an author too, and is only reported once
+	var authors []MongoPerson
+	seen := make(map[string]bool)
+	for _, persons := range []*[]MongoPerson{contribution.Presenters, contribution.Authors} {
+		if persons == nil {
+			continue
+		}
+		for _, person := range *persons {
+			key := strings.ToLower(strings.TrimSpace(person.FirstName) + " " + strings.TrimSpace(person.FamilyName))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			authors = append(authors, person)
+		}
+	}
+	if len(authors) == 0 {
+		problems = append(problems, "No authors")
+	}
+
+	linkedAffiliations := make(map[string]bool)
+	for _, person := range contribution.Persons {
+		linkedAffiliations[person.AffiliationLink.Name] = true
+	}
+	for _, author := range authors {
+		name := strings.TrimSpace(author.FirstName + " " + author.FamilyName)
+		if author.Affiliation == "" {
+			problems = append(problems, fmt.Sprintf("%s has no affiliation", name))
+		} else if len(contribution.Persons) > 0 && !linkedAffiliations[author.Affiliation] {
+			problems = append(problems, fmt.Sprintf("%s's affiliation \"%s\" is not linked to an affiliation in indico", name, author.Affiliation))
+		}
+	}
+
+	var issues []Issue
+	for _, problem := range problems {
+		issues = append(issues, Issue{
+			ContributionID: contribution.ID,
+			Code:           contribution.Code,
+			Title:          contribution.Title,
+			Problem:        problem,
+		})
+	}
+	return issues
+}
+
+func conferenceIssues(database *mongo.Database, conferenceId int) ([]Issue, error) {
+	findOptions := options.Find().SetSort(bson.D{{"code", 1}})
+	cursor, findError := database.Collection("contributions").Find(context.Background(), bson.D{{"conferenceId", conferenceId}}, findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding contributions: %s", findError.Error())
+	}
+	defer func(cursor *mongo.Cursor, ctx context.Context) {
+		_ = cursor.Close(ctx)
+	}(cursor, context.Background())
+
+	var issues []Issue
+	for cursor.Next(context.Background()) {
+		var contribution MongoContribution
+		if decodeErr := cursor.Decode(&contribution); decodeErr != nil {
+			return nil, fmt.Errorf("error decoding contribution: %s", decodeErr.Error())
+		}
+		issues = append(issues, contributionIssues(contribution)...)
+	}
+	return issues, nil
+}
+
+func digestBody(conference MongoConference, issues []Issue) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "%d data quality problems were found in %s.\r\n", len(issues), conference.Name)
+	previous := -1
+	for _, issue := range issues {
+		if issue.ContributionID != previous {
+			code := issue.Code
+			if code == "" {
+				code = fmt.Sprintf("#%d", issue.ContributionID)
+			}
+			fmt.Fprintf(&body, "\r\n%s %s\r\n", code, issue.Title)
+			previous = issue.ContributionID
+		}
+		fmt.Fprintf(&body, "  - %s\r\n", issue.Problem)
+	}
+	return body.String()
+}
+
+func recipients(conference MongoConference) []string {
+	if len(conference.Editors) > 0 {
+		return conference.Editors
+	}
+	var fallback []string
+	for _, recipient := range strings.Split(os.Getenv("DIGEST_RECIPIENTS"), ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			fallback = append(fallback, recipient)
+		}
+	}
+	return fallback
+}
+
+// mailMessage is the message sent by sendMail. The subject holds the
+// conference name, which may not be ASCII, so it is MIME encoded.
+func mailMessage(from string, to []string, subject string, body string) string {
+	return "From: " + from + "\r\n" +
+		"To: " + strings.Join(to, ", ") + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("UTF-8", subject) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+}
+
+func sendMail(to []string, subject string, body string) error {
+	host := os.Getenv("SMTP_HOST")
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	message := mailMessage(from, to, subject, body)
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	return smtp.SendMail(host+":"+port, auth, from, to, []byte(message))
+}
+
+func Main(in Request) (*Response, error) {
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	database := client.Database("author-title")
+
+	conferences, err := requestedConferences(database, in)
+	if err != nil {
+		return nil, err
+	}
+
+	sent := 0
+	var sendErrors []string
+	for _, conference := range conferences {
+		to := recipients(conference)
+		if len(to) == 0 {
+			continue
+		}
+		issues, err := conferenceIssues(database, conference.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(issues) == 0 {
+			continue
+		}
+		subject := fmt.Sprintf("Data quality digest for %s", conference.Name)
+		if err := sendMail(to, subject, digestBody(conference, issues)); err != nil {
+			sendErrors = append(sendErrors, fmt.Sprintf("conference %d: %s", conference.ID, err.Error()))
+			continue
+		}
+		sent++
+	}
+
+	response := &Response{
+		Body: fmt.Sprintf("Sent digests for %d conferences", sent),
+	}
+	if len(sendErrors) > 0 {
+		return response, fmt.Errorf("error sending digests: %s", strings.Join(sendErrors, "; "))
+	}
+	return response, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContributionIssues(t *testing.T) {
+	ada := MongoPerson{FirstName: "Ada", FamilyName: "Lovelace", Affiliation: "ANSTO"}
+	tests := []struct {
+		name         string
+		contribution MongoContribution
+		want         []string
+	}{
+		{
+			name: "no problems",
+			contribution: MongoContribution{
+				Code:       "TUPA071",
+				Presenters: &[]MongoPerson{ada},
+				Persons:    []MongoDetailedPerson{{AffiliationLink: MongoAffiliationLink{ID: 3, Name: "ANSTO"}}},
+			},
+		},
+		{
+			name:         "no code, duplicate and no authors",
+			contribution: MongoContribution{IsDuplicate: true},
+			want:         []string{"Missing code", "Flagged as a duplicate", "No authors"},
+		},
+		{
+			name: "no affiliation",
+			contribution: MongoContribution{
+				Code:    "TUPA071",
+				Authors: &[]MongoPerson{{FirstName: "Jean", FamilyName: "Dupont"}},
+			},
+			want: []string{"Jean Dupont has no affiliation"},
+		},
+		{
+			name: "unlinked affiliation",
+			contribution: MongoContribution{
+				Code:       "TUPA071",
+				Presenters: &[]MongoPerson{ada},
+				Persons:    []MongoDetailedPerson{{AffiliationLink: MongoAffiliationLink{ID: 4, Name: "CERN"}}},
+			},
+			want: []string{"Ada Lovelace's affiliation \"ANSTO\" is not linked to an affiliation in indico"},
+		},
+		{
+			name: "presenter who is also an author",
+			contribution: MongoContribution{
+				Code:       "TUPA071",
+				Presenters: &[]MongoPerson{{FirstName: "Ada", FamilyName: "Lovelace"}},
+				Authors:    &[]MongoPerson{{FirstName: "ada", FamilyName: "Lovelace "}, {FirstName: "Jean", FamilyName: "Dupont"}},
+			},
+			want: []string{"Ada Lovelace has no affiliation", "Jean Dupont has no affiliation"},
+		},
+		{
+			name:         "no persons to link against",
+			contribution: MongoContribution{Code: "TUPA071", Authors: &[]MongoPerson{ada}},
+		},
+	}
+	for _, test := range tests {
+		test.contribution.ID = 7
+		test.contribution.Title = "Beam loss monitors"
+		var got []string
+		for _, issue := range contributionIssues(test.contribution) {
+			if issue.ContributionID != 7 || issue.Title != "Beam loss monitors" {
+				t.Errorf("%s: issue %+v is not for the contribution", test.name, issue)
+			}
+			got = append(got, issue.Problem)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: contributionIssues = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDigestBody(t *testing.T) {
+	conference := MongoConference{ID: 41, Name: "IPAC'24"}
+	issues := []Issue{
+		{ContributionID: 7, Code: "TUPA071", Title: "Beam loss monitors", Problem: "No authors"},
+		{ContributionID: 7, Code: "TUPA071", Title: "Beam loss monitors", Problem: "Flagged as a duplicate"},
+		{ContributionID: 8, Title: "Untitled poster", Problem: "Missing code"},
+	}
+	want := strings.Join([]string{
+		"3 data quality problems were found in IPAC'24.",
+		"",
+		"TUPA071 Beam loss monitors",
+		"  - No authors",
+		"  - Flagged as a duplicate",
+		"",
+		"#8 Untitled poster",
+		"  - Missing code",
+		"",
+	}, "\r\n")
+	if got := digestBody(conference, issues); got != want {
+		t.Errorf("digestBody = %q, want %q", got, want)
+	}
+}
+
+func TestMailMessage(t *testing.T) {
+	got := mailMessage("digest@example.org", []string{"a@example.org", "b@example.org"}, "Data quality digest for Linac'24, Genève", "body")
+	want := strings.Join([]string{
+		"From: digest@example.org",
+		"To: a@example.org, b@example.org",
+		"Subject: =?UTF-8?q?Data_quality_digest_for_Linac'24,_Gen=C3=A8ve?=",
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		"body",
+	}, "\r\n")
+	if got != want {
+		t.Errorf("mailMessage = %q, want %q", got, want)
+	}
+	if got := mailMessage("", nil, "Data quality digest for IPAC'24", ""); !strings.Contains(got, "Subject: Data quality digest for IPAC'24\r\n") {
+		t.Errorf("an ASCII subject was encoded: %q", got)
+	}
+}
+
+func TestRecipients(t *testing.T) {
+	tests := []struct {
+		name     string
+		editors  []string
+		fallback string
+		want     []string
+	}{
+		{"editors", []string{"editor@example.org"}, "digest@example.org", []string{"editor@example.org"}},
+		{"fallback", nil, " digest@example.org, ,chair@example.org ", []string{"digest@example.org", "chair@example.org"}},
+		{"nobody", nil, "", nil},
+	}
+	for _, test := range tests {
+		t.Setenv("DIGEST_RECIPIENTS", test.fallback)
+		got := recipients(MongoConference{Editors: test.editors})
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: recipients = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
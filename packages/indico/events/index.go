@@ -108,36 +108,35 @@ type Response struct {
 	Body       string            `json:"body,omitempty"`
 }
 
-func Main(in Request) (*Response, error) {
+func syncEvents(run *MongoSyncRun) *Response {
+	failed := func(message string) *Response {
+		run.Errors = append(run.Errors, message)
+		return &Response{
+			Body: message,
+		}
+	}
+
 	payload, err := fetch(fmt.Sprintf("https://indico.jacow.org/export/categ/%d.json", 2))
 	if err != nil {
-		return &Response{
-			Body: fmt.Sprintf("Error downloading sessions: %s", err.Error()),
-		}, nil
+		return failed(fmt.Sprintf("Error downloading sessions: %s", err.Error()))
 	}
 
 	var data map[string]interface{}
 	if err := json.Unmarshal([]byte(payload), &data); err != nil {
-		return &Response{
-			Body: fmt.Sprintf("Error decoding JSON: %s", err.Error()),
-		}, nil
+		return failed(fmt.Sprintf("Error decoding JSON: %s", err.Error()))
 	}
 
 	conferences, err := getConferences(data)
 
 	if err != nil {
-		return &Response{
-			Body: fmt.Sprintf("Failed to parse events payload: %s", err.Error()),
-		}, nil
+		return failed(fmt.Sprintf("Failed to parse events payload: %s", err.Error()))
 	}
 
 	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
 
 	client, err := mongo.Connect(context.Background(), clientOptions)
 	if err != nil {
-		return &Response{
-			Body: fmt.Sprintf("Error connecting to MongoDB: %s", err.Error()),
-		}, nil
+		return failed(fmt.Sprintf("Error connecting to MongoDB: %s", err.Error()))
 	}
 
 	collection := client.Database("author-title").Collection("conferences")
@@ -154,15 +153,26 @@ func Main(in Request) (*Response, error) {
 			}},
 		}
 
-		_, err := collection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+		result, err := collection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
 		if err != nil {
-			return &Response{
-				Body: fmt.Sprintf("Error performing upsert: %s", err.Error()),
-			}, nil
+			return failed(fmt.Sprintf("Error performing upsert: %s", err.Error()))
 		}
+		run.Conferences = append(run.Conferences, conference.id)
+		run.Inserted += result.UpsertedCount
+		run.Updated += result.ModifiedCount
 	}
 
 	return &Response{
 		Body: fmt.Sprintf("%d Contributions downloaded", len(conferences)),
-	}, nil
+	}
+}
+
+func Main(in Request) (*Response, error) {
+	run := MongoSyncRun{Job: "events", Start: time.Now()}
+	response := syncEvents(&run)
+	run.End = time.Now()
+	if err := recordSyncRun(run); err != nil {
+		fmt.Printf("error recording sync run: %s", err.Error())
+	}
+	return response, nil
 }
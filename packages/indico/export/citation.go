@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var bibtexSpecials = strings.NewReplacer(
+	"\\", "\\textbackslash{}",
+	"{", "\\{",
+	"}", "\\}",
+	"&", "\\&",
+	"%", "\\%",
+	"$", "\\$",
+	"#", "\\#",
+	"_", "\\_",
+	"~", "\\textasciitilde{}",
+	"^", "\\textasciicircum{}",
+)
+
+func citationKey(conference MongoConference, contribution MongoContribution) string {
+	code := contribution.Code
+	if code == "" {
+		code = fmt.Sprint(contribution.ID)
+	}
+	key := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, code)
+	return fmt.Sprintf("jacow-%d-%s", conference.ID, key)
+}
+
+func bibtexEntry(conference MongoConference, contribution MongoContribution) string {
+	var authors []string
+	for _, author := range orderedAuthors(contribution) {
+		authors = append(authors, bibtexSpecials.Replace(author.FamilyName)+", "+bibtexSpecials.Replace(author.FirstName))
+	}
+
+	fields := [][2]string{
+		{"author", strings.Join(authors, " and ")},
+		// Double braces keep the capitalisation of the title
+		{"title", "{" + bibtexSpecials.Replace(contribution.Title) + "}"},
+		{"booktitle", bibtexSpecials.Replace(conference.Name)},
+		{"address", bibtexSpecials.Replace(conference.Location)},
+		{"year", fmt.Sprint(conference.Start.Year())},
+		{"month", strings.ToLower(conference.Start.Month().String()[:3])},
+		{"eventdate", conference.Start.Format("2006-01-02") + "/" + conference.End.Format("2006-01-02")},
+		{"number", bibtexSpecials.Replace(contribution.Code)},
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "@inproceedings{%s,\n", citationKey(conference, contribution))
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&out, "  %-9s = {%s},\n", field[0], field[1])
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+func bibtex(conference MongoConference, contributions []MongoContribution) string {
+	var entries []string
+	for _, contribution := range contributions {
+		entries = append(entries, bibtexEntry(conference, contribution))
+	}
+	return strings.Join(entries, "\n")
+}
+
+type CSLName struct {
+	Family string `json:"family"`
+	Given  string `json:"given,omitempty"`
+}
+
+type CSLDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+type CSLItem struct {
+	ID             string    `json:"id"`
+	Type           string    `json:"type"`
+	Title          string    `json:"title"`
+	Author         []CSLName `json:"author"`
+	ContainerTitle string    `json:"container-title,omitempty"`
+	EventTitle     string    `json:"event-title,omitempty"`
+	EventPlace     string    `json:"event-place,omitempty"`
+	EventDate      CSLDate   `json:"event-date"`
+	Issued         CSLDate   `json:"issued"`
+	Number         string    `json:"number,omitempty"`
+}
+
+func cslDateParts(date time.Time) []int {
+	return []int{date.Year(), int(date.Month()), date.Day()}
+}
+
+func cslJSON(conference MongoConference, contributions []MongoContribution) (string, error) {
+	var items = make([]CSLItem, 0)
+	for _, contribution := range contributions {
+		var authors = make([]CSLName, 0)
+		for _, author := range orderedAuthors(contribution) {
+			authors = append(authors, CSLName{Family: author.FamilyName, Given: author.FirstName})
+		}
+		items = append(items, CSLItem{
+			ID:             citationKey(conference, contribution),
+			Type:           "paper-conference",
+			Title:          contribution.Title,
+			Author:         authors,
+			ContainerTitle: conference.Name,
+			EventTitle:     conference.Name,
+			EventPlace:     conference.Location,
+			EventDate: CSLDate{DateParts: [][]int{
+				cslDateParts(conference.Start),
+				cslDateParts(conference.End),
+			}},
+			Issued: CSLDate{DateParts: [][]int{{conference.Start.Year()}}},
+			Number: contribution.Code,
+		})
+	}
+	jsonBytes, err := json.Marshal(items)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCitationKey(t *testing.T) {
+	tests := []struct {
+		contribution MongoContribution
+		want         string
+	}{
+		{MongoContribution{ID: 7, Code: "TUPA071"}, "jacow-41-tupa071"},
+		{MongoContribution{ID: 7, Code: "MO-PL 01.2"}, "jacow-41-mo-pl012"},
+		{MongoContribution{ID: 7, Code: "ÉTÉ_01"}, "jacow-41-été01"},
+		{MongoContribution{ID: 7}, "jacow-41-7"},
+	}
+	for _, test := range tests {
+		if got := citationKey(MongoConference{ID: 41}, test.contribution); got != test.want {
+			t.Errorf("citationKey(%q) = %s, want %s", test.contribution.Code, got, test.want)
+		}
+	}
+}
+
+func TestOrderedAuthors(t *testing.T) {
+	ada := MongoPerson{FirstName: "Ada", FamilyName: "Lovelace", DisplayOrder: 2}
+	contribution := MongoContribution{
+		Presenters: &[]MongoPerson{ada},
+		Authors: &[]MongoPerson{
+			{FirstName: "Jean", FamilyName: "Dupont", DisplayOrder: 3},
+			{FirstName: "ada", FamilyName: "LOVELACE", DisplayOrder: 2},
+			{FirstName: "Li", FamilyName: "Wei", DisplayOrder: 1},
+		},
+	}
+	var got []string
+	for _, author := range orderedAuthors(contribution) {
+		got = append(got, author.FamilyName)
+	}
+	if want := []string{"Wei", "Lovelace", "Dupont"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("orderedAuthors = %q, want %q", got, want)
+	}
+}
+
+func TestBibtexEntry(t *testing.T) {
+	tests := []struct {
+		name         string
+		conference   func(*MongoConference)
+		contribution func(*MongoContribution)
+		want         string
+	}{
+		{
+			name: "full entry",
+			want: "@inproceedings{jacow-41-tupa071,\n" +
+				"  author    = {Lovelace, Ada and Dupont, Jean},\n" +
+				"  title     = {{Beam loss monitors for the storage ring}},\n" +
+				"  booktitle = {15th International Particle Accelerator Conference},\n" +
+				"  address   = {Nashville, TN, USA},\n" +
+				"  year      = {2024},\n" +
+				"  month     = {may},\n" +
+				"  eventdate = {2024-05-19/2024-05-24},\n" +
+				"  number    = {TUPA071},\n" +
+				"}\n",
+		},
+		{
+			name:       "special characters and no location",
+			conference: func(conference *MongoConference) { conference.Location = "" },
+			contribution: func(contribution *MongoContribution) {
+				contribution.Code = "TU_01"
+				contribution.Title = "100% R&D {costs} of $5 #2 ~ x^2"
+				contribution.Presenters = nil
+				contribution.Authors = &[]MongoPerson{{FirstName: "Jean", FamilyName: "O\\Neil"}}
+			},
+			want: "@inproceedings{jacow-41-tu01,\n" +
+				"  author    = {O\\textbackslash{}Neil, Jean},\n" +
+				"  title     = {{100\\% R\\&D \\{costs\\} of \\$5 \\#2 \\textasciitilde{} x\\textasciicircum{}2}},\n" +
+				"  booktitle = {15th International Particle Accelerator Conference},\n" +
+				"  year      = {2024},\n" +
+				"  month     = {may},\n" +
+				"  eventdate = {2024-05-19/2024-05-24},\n" +
+				"  number    = {TU\\_01},\n" +
+				"}\n",
+		},
+	}
+	for _, test := range tests {
+		conference := crossrefConference()
+		if test.conference != nil {
+			test.conference(&conference)
+		}
+		contribution := crossrefContribution()
+		if test.contribution != nil {
+			test.contribution(&contribution)
+		}
+		if got := bibtexEntry(conference, contribution); got != test.want {
+			t.Errorf("%s: bibtexEntry =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestBibtex(t *testing.T) {
+	first, second := crossrefContribution(), crossrefContribution()
+	second.Code = "TUPA072"
+	conference := crossrefConference()
+	want := bibtexEntry(conference, first) + "\n" + bibtexEntry(conference, second)
+	if got := bibtex(conference, []MongoContribution{first, second}); got != want {
+		t.Errorf("bibtex =\n%s\nwant\n%s", got, want)
+	}
+	if got := bibtex(conference, nil); got != "" {
+		t.Errorf("bibtex of no contributions = %q, want it empty", got)
+	}
+}
+
+func TestCSLDateParts(t *testing.T) {
+	date := time.Date(2024, 5, 9, 23, 0, 0, 0, time.UTC)
+	if got, want := cslDateParts(date), []int{2024, 5, 9}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cslDateParts(%s) = %v, want %v", date, got, want)
+	}
+}
+
+func TestCSLJSONWithoutData(t *testing.T) {
+	tests := []struct {
+		name          string
+		contributions []MongoContribution
+		want          string
+	}{
+		{"no contributions", nil, "[]"},
+		{
+			"no authors",
+			[]MongoContribution{{ID: 7, Title: "Beam loss monitors"}},
+			`[{"id":"jacow-41-7","type":"paper-conference","title":"Beam loss monitors","author":[],` +
+				`"event-date":{"date-parts":[[1,1,1],[1,1,1]]},"issued":{"date-parts":[[1]]}}]`,
+		},
+	}
+	for _, test := range tests {
+		got, err := cslJSON(MongoConference{ID: 41}, test.contributions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%s: cslJSON = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const crossrefNamespace = "http://www.crossref.org/schema/5.3.1"
+
+type CrossrefBatch struct {
+	XMLName        xml.Name     `xml:"doi_batch"`
+	Version        string       `xml:"version,attr"`
+	Namespace      string       `xml:"xmlns,attr"`
+	XSINamespace   string       `xml:"xmlns:xsi,attr"`
+	SchemaLocation string       `xml:"xsi:schemaLocation,attr"`
+	Head           CrossrefHead `xml:"head"`
+	Conference     CrossrefBody `xml:"body>conference"`
+}
+
+type CrossrefHead struct {
+	BatchID        string `xml:"doi_batch_id"`
+	Timestamp      string `xml:"timestamp"`
+	DepositorName  string `xml:"depositor>depositor_name"`
+	DepositorEmail string `xml:"depositor>email_address"`
+	Registrant     string `xml:"registrant"`
+}
+
+type CrossrefBody struct {
+	Event       CrossrefEvent       `xml:"event_metadata"`
+	Proceedings CrossrefProceedings `xml:"proceedings_metadata"`
+	Papers      []CrossrefPaper     `xml:"conference_paper"`
+}
+
+type CrossrefConferenceDate struct {
+	StartDay   int `xml:"start_day,attr"`
+	StartMonth int `xml:"start_month,attr"`
+	StartYear  int `xml:"start_year,attr"`
+	EndDay     int `xml:"end_day,attr"`
+	EndMonth   int `xml:"end_month,attr"`
+	EndYear    int `xml:"end_year,attr"`
+}
+
+type CrossrefEvent struct {
+	Name     string                 `xml:"conference_name"`
+	Acronym  string                 `xml:"conference_acronym,omitempty"`
+	Location string                 `xml:"conference_location,omitempty"`
+	Date     CrossrefConferenceDate `xml:"conference_date"`
+}
+
+type CrossrefPublicationDate struct {
+	MediaType string `xml:"media_type,attr"`
+	Year      int    `xml:"year"`
+}
+
+type CrossrefNoISBN struct {
+	Reason string `xml:"reason,attr"`
+}
+
+type CrossrefProceedings struct {
+	Language        string                  `xml:"language,attr"`
+	Title           string                  `xml:"proceedings_title"`
+	Publisher       string                  `xml:"publisher>publisher_name"`
+	PublicationDate CrossrefPublicationDate `xml:"publication_date"`
+	NoISBN          CrossrefNoISBN          `xml:"noisbn"`
+}
+
+type CrossrefInstitutionID struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type CrossrefInstitution struct {
+	Name string                 `xml:"institution_name"`
+	ID   *CrossrefInstitutionID `xml:"institution_id,omitempty"`
+}
+
+type CrossrefPerson struct {
+	Sequence     string                `xml:"sequence,attr"`
+	Role         string                `xml:"contributor_role,attr"`
+	GivenName    string                `xml:"given_name,omitempty"`
+	Surname      string                `xml:"surname"`
+	Affiliations *CrossrefAffiliations `xml:"affiliations,omitempty"`
+	ORCID        string                `xml:"ORCID,omitempty"`
+}
+
+// CrossrefAffiliations is left out of a person without an affiliation, as
+// the schema doesn't allow an empty one
+type CrossrefAffiliations struct {
+	Institutions []CrossrefInstitution `xml:"institution"`
+}
+
+type CrossrefPaper struct {
+	PublicationType string                  `xml:"publication_type,attr"`
+	Contributors    []CrossrefPerson        `xml:"contributors>person_name"`
+	Title           string                  `xml:"titles>title"`
+	PublicationDate CrossrefPublicationDate `xml:"publication_date"`
+	DOI             string                  `xml:"doi_data>doi"`
+	Resource        string                  `xml:"doi_data>resource"`
+}
+
+func envOrDefault(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// requiredEnv reads a setting which a deposit is invalid without
+func requiredEnv(name string) (string, error) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return "", fmt.Errorf("%s is required for a crossref export", name)
+	}
+	return value, nil
+}
+
+// crossrefReplacer fills in the {conference}, {acronym} and {code}
+// placeholders of the DOI and resource URL templates
+func crossrefReplacer(conference MongoConference, contribution MongoContribution) *strings.Replacer {
+	return strings.NewReplacer(
+		"{conference}", fmt.Sprint(conference.ID),
+		"{acronym}", conferenceAcronym(conference),
+		"{code}", contribution.Code,
+	)
+}
+
+func conferenceAcronym(conference MongoConference) string {
+	if conference.Acronym != "" {
+		return conference.Acronym
+	}
+	return fmt.Sprint(conference.ID)
+}
+
+// crossrefInstitutions finds the institution of each detailed person, which
+// carries the name indico links the affiliation to and the ROR id mapped to
+// that affiliation in the registry
+func crossrefInstitutions(contribution MongoContribution, rors map[int]string) map[string]CrossrefInstitution {
+	institutions := make(map[string]CrossrefInstitution)
+	for _, person := range contribution.Persons {
+		name := person.AffiliationLink.Name
+		if name == "" {
+			name = person.Affiliation
+		}
+		if name == "" {
+			continue
+		}
+		institution := CrossrefInstitution{Name: name}
+		if ror, found := rors[person.AffiliationLink.ID]; found {
+			institution.ID = &CrossrefInstitutionID{Type: "ror", Value: "https://ror.org/" + ror}
+		}
+		institutions[personKey(person.FirstName, person.LastName)] = institution
+	}
+	return institutions
+}
+
+// crossrefORCIDs finds the ORCID of each detailed person from the identity
+// their indico person id was resolved to
+func crossrefORCIDs(contribution MongoContribution, orcids map[int]string) map[string]string {
+	byName := make(map[string]string)
+	for _, person := range contribution.Persons {
+		if orcid, found := orcids[person.ID]; found {
+			byName[personKey(person.FirstName, person.LastName)] = "https://orcid.org/" + orcid
+		}
+	}
+	return byName
+}
+
+func personKey(firstName string, lastName string) string {
+	return strings.ToLower(strings.Join(strings.Fields(firstName+" "+lastName), " "))
+}
+
+// crossrefPaper is the conference_paper of a contribution, which Crossref
+// refuses without a title and a surname for every author
+func crossrefPaper(conference MongoConference, contribution MongoContribution, rors map[int]string, orcids map[int]string, resourceURL string) (CrossrefPaper, error) {
+	if strings.TrimSpace(contribution.Title) == "" {
+		return CrossrefPaper{}, fmt.Errorf("contribution %s has no title", contribution.Code)
+	}
+	institutions := crossrefInstitutions(contribution, rors)
+	personORCIDs := crossrefORCIDs(contribution, orcids)
+	var contributors []CrossrefPerson
+	for index, author := range orderedAuthors(contribution) {
+		if strings.TrimSpace(author.FamilyName) == "" {
+			return CrossrefPaper{}, fmt.Errorf("an author of contribution %s has no family name", contribution.Code)
+		}
+		sequence := "additional"
+		if index == 0 {
+			sequence = "first"
+		}
+		person := CrossrefPerson{
+			Sequence:  sequence,
+			Role:      "author",
+			GivenName: author.FirstName,
+			Surname:   author.FamilyName,
+		}
+		if institution, found := institutions[personKey(author.FirstName, author.FamilyName)]; found {
+			person.Affiliations = &CrossrefAffiliations{Institutions: []CrossrefInstitution{institution}}
+		} else if author.Affiliation != "" {
+			person.Affiliations = &CrossrefAffiliations{Institutions: []CrossrefInstitution{{Name: author.Affiliation}}}
+		}
+		person.ORCID = personORCIDs[personKey(author.FirstName, author.FamilyName)]
+		contributors = append(contributors, person)
+	}
+
+	replacer := crossrefReplacer(conference, contribution)
+	return CrossrefPaper{
+		PublicationType: "full_text",
+		Contributors:    contributors,
+		Title:           contribution.Title,
+		PublicationDate: CrossrefPublicationDate{MediaType: "online", Year: conference.End.Year()},
+		DOI:             replacer.Replace(envOrDefault("CROSSREF_DOI", "10.18429/JACoW-{acronym}-{code}")),
+		Resource:        replacer.Replace(resourceURL),
+	}, nil
+}
+
+// crossref builds a Crossref deposit for the conference proceedings, with a
+// conference_paper for every contribution which has a code. It fails rather
+// than build a deposit Crossref would reject for a missing required field.
+func crossref(conference MongoConference, contributions []MongoContribution, rors map[int]string, orcids map[int]string, now time.Time) (string, error) {
+	resourceURL, err := requiredEnv("CROSSREF_RESOURCE_URL")
+	if err != nil {
+		return "", err
+	}
+	depositorName, err := requiredEnv("CROSSREF_DEPOSITOR_NAME")
+	if err != nil {
+		return "", err
+	}
+	depositorEmail, err := requiredEnv("CROSSREF_DEPOSITOR_EMAIL")
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(conference.Name) == "" {
+		return "", fmt.Errorf("conference %d has no name", conference.ID)
+	}
+	if conference.Start.IsZero() || conference.End.IsZero() {
+		return "", fmt.Errorf("conference %d has no dates", conference.ID)
+	}
+
+	var papers []CrossrefPaper
+	for _, contribution := range contributions {
+		if contribution.Code == "" || contribution.IsDuplicate {
+			continue
+		}
+		paper, err := crossrefPaper(conference, contribution, rors, orcids, resourceURL)
+		if err != nil {
+			return "", err
+		}
+		papers = append(papers, paper)
+	}
+
+	batch := CrossrefBatch{
+		Version:        "5.3.1",
+		Namespace:      crossrefNamespace,
+		XSINamespace:   "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: crossrefNamespace + " https://www.crossref.org/schemas/crossref5.3.1.xsd",
+		Head: CrossrefHead{
+			BatchID:        fmt.Sprintf("%s-%d", conferenceAcronym(conference), now.Unix()),
+			Timestamp:      now.UTC().Format("20060102150405"),
+			DepositorName:  depositorName,
+			DepositorEmail: depositorEmail,
+			Registrant:     envOrDefault("CROSSREF_REGISTRANT", "JACoW"),
+		},
+		Conference: CrossrefBody{
+			Event: CrossrefEvent{
+				Name:     conference.Name,
+				Acronym:  conference.Acronym,
+				Location: conference.Location,
+				Date: CrossrefConferenceDate{
+					StartDay:   conference.Start.Day(),
+					StartMonth: int(conference.Start.Month()),
+					StartYear:  conference.Start.Year(),
+					EndDay:     conference.End.Day(),
+					EndMonth:   int(conference.End.Month()),
+					EndYear:    conference.End.Year(),
+				},
+			},
+			Proceedings: CrossrefProceedings{
+				Language:        "en",
+				Title:           conference.Name,
+				Publisher:       envOrDefault("CROSSREF_PUBLISHER", "JACoW Publishing"),
+				PublicationDate: CrossrefPublicationDate{MediaType: "online", Year: conference.End.Year()},
+				NoISBN:          CrossrefNoISBN{Reason: "simple_series"},
+			},
+			Papers: papers,
+		},
+	}
+
+	xmlBytes, err := xml.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(xmlBytes) + "\n", nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func crossrefConference() MongoConference {
+	return MongoConference{
+		ID:       41,
+		Name:     "15th International Particle Accelerator Conference",
+		Start:    time.Date(2024, 5, 19, 0, 0, 0, 0, time.UTC),
+		End:      time.Date(2024, 5, 24, 0, 0, 0, 0, time.UTC),
+		Location: "Nashville, TN, USA",
+		Acronym:  "IPAC2024",
+	}
+}
+
+func crossrefContribution() MongoContribution {
+	return MongoContribution{
+		ID:           7,
+		Code:         "TUPA071",
+		Title:        "Beam loss monitors for the storage ring",
+		ConferenceId: 41,
+		Presenters:   &[]MongoPerson{{FirstName: "Ada", FamilyName: "Lovelace", Affiliation: "ANSTO", DisplayOrder: 1}},
+		Authors:      &[]MongoPerson{{FirstName: "Jean", FamilyName: "Dupont", DisplayOrder: 2}},
+		Persons: []MongoDetailedPerson{{
+			ID:              1,
+			FirstName:       "Ada",
+			LastName:        "Lovelace",
+			Affiliation:     "ANSTO",
+			AffiliationLink: MongoAffiliationLink{ID: 3, Name: "Australian Nuclear Science and Technology Organisation"},
+		}},
+	}
+}
+
+func setCrossrefEnv(t *testing.T) {
+	t.Setenv("CROSSREF_DEPOSITOR_NAME", "JACoW Publishing")
+	t.Setenv("CROSSREF_DEPOSITOR_EMAIL", "deposits@example.org")
+	t.Setenv("CROSSREF_RESOURCE_URL", "https://proceedings.example.org/{acronym}/{code}")
+	t.Setenv("CROSSREF_DOI", "")
+	t.Setenv("CROSSREF_REGISTRANT", "")
+	t.Setenv("CROSSREF_PUBLISHER", "")
+}
+
+func TestCrossrefRequiredFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          string
+		conference   func(*MongoConference)
+		contribution func(*MongoContribution)
+		want         string
+	}{
+		{name: "resource url", env: "CROSSREF_RESOURCE_URL", want: "CROSSREF_RESOURCE_URL is required"},
+		{name: "depositor name", env: "CROSSREF_DEPOSITOR_NAME", want: "CROSSREF_DEPOSITOR_NAME is required"},
+		{name: "depositor email", env: "CROSSREF_DEPOSITOR_EMAIL", want: "CROSSREF_DEPOSITOR_EMAIL is required"},
+		{
+			name:       "conference name",
+			conference: func(conference *MongoConference) { conference.Name = " " },
+			want:       "conference 41 has no name",
+		},
+		{
+			name:       "conference dates",
+			conference: func(conference *MongoConference) { conference.End = time.Time{} },
+			want:       "conference 41 has no dates",
+		},
+		{
+			name:         "title",
+			contribution: func(contribution *MongoContribution) { contribution.Title = "" },
+			want:         "contribution TUPA071 has no title",
+		},
+		{
+			name: "family name",
+			contribution: func(contribution *MongoContribution) {
+				contribution.Authors = &[]MongoPerson{{FirstName: "Jean", DisplayOrder: 2}}
+			},
+			want: "an author of contribution TUPA071 has no family name",
+		},
+	}
+	for _, test := range tests {
+		setCrossrefEnv(t)
+		if test.env != "" {
+			t.Setenv(test.env, "")
+		}
+		conference := crossrefConference()
+		if test.conference != nil {
+			test.conference(&conference)
+		}
+		contribution := crossrefContribution()
+		if test.contribution != nil {
+			test.contribution(&contribution)
+		}
+		_, err := crossref(conference, []MongoContribution{contribution}, nil, nil, time.Now())
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want %q", test.name, err, test.want)
+		}
+	}
+}
+
+// TestCrossrefFixture keeps testdata/crossref.xml the same as the deposit
+// built for a fixture conference and checks it against the Crossref schemas
+// in testdata/crossref with xmllint
+func TestCrossrefFixture(t *testing.T) {
+	setCrossrefEnv(t)
+	deposit, err := crossref(crossrefConference(), []MongoContribution{crossrefContribution()},
+		map[int]string{3: "05j7fep28"}, map[int]string{1: "0000-0002-1825-0097"}, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join("testdata", "crossref.xml")
+	if *update {
+		if err := os.WriteFile(path, []byte(deposit), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fixture, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(fixture) != deposit {
+		t.Errorf("%s is out of date, run go test -update\ngot:\n%s", path, deposit)
+	}
+
+	schema := filepath.Join("testdata", "crossref", "crossref5.3.1.xsd")
+	if _, err := os.Stat(schema); err != nil {
+		t.Fatal("the Crossref schemas are missing from testdata/crossref, run testdata/fetch-crossref-schema.sh and commit them")
+	}
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Fatal("xmllint is needed to check the deposit against the Crossref schemas")
+	}
+	if output, err := exec.Command(xmllint, "--noout", "--schema", schema, path).CombinedOutput(); err != nil {
+		t.Errorf("%s is not a valid deposit: %s\n%s", path, err.Error(), output)
+	}
+}
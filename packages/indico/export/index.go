@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type MongoConference struct {
+	ID       int       `bson:"_id"`
+	Name     string    `bson:"name"`
+	Start    time.Time `bson:"start"`
+	End      time.Time `bson:"end"`
+	Location string    `bson:"location"`
+	Acronym  string    `bson:"acronym"`
+}
+
+type MongoContribution struct {
+	ID               int                   `bson:"_id"`
+	Code             string                `bson:"code"`
+	Title            string                `bson:"title"`
+	Presenters       *[]MongoPerson        `bson:"presenters,omitempty"`
+	Authors          *[]MongoPerson        `bson:"authors,omitempty"`
+	ConferenceId     int                   `bson:"conferenceId"`
+	Persons          []MongoDetailedPerson `bson:"persons"`
+	IsDuplicate      bool                  `bson:"is_duplicate"`
+	ContributionType string                `bson:"contribution_type"`
+	FundingAgency    string                `bson:"funding_agency"`
+	Schedule         *MongoSchedule        `bson:"schedule,omitempty"`
+}
+
+type MongoSchedule struct {
+	SessionCode  string `bson:"sessionCode"`
+	SessionTitle string `bson:"sessionTitle"`
+}
+
+type MongoPerson struct {
+	FirstName    string `bson:"firstName"`
+	FamilyName   string `bson:"familyName"`
+	Affiliation  string `bson:"affiliation"`
+	DisplayOrder int    `bson:"displayOrder"`
+}
+
+type MongoAffiliationLink struct {
+	ID          int    `bson:"id"`
+	Name        string `bson:"name"`
+	City        string `bson:"city"`
+	CountryName string `bson:"country_name"`
+	CountryCode string `bson:"country_code"`
+	Postcode    string `bson:"postcode"`
+}
+
+type MongoDetailedPerson struct {
+	ID              int                  `bson:"person_id"`
+	FirstName       string               `bson:"first_name"`
+	LastName        string               `bson:"last_name"`
+	IsSpeaker       bool                 `bson:"is_speaker"`
+	AuthorType      string               `bson:"author_type"`
+	Affiliation     string               `bson:"affiliation"`
+	AffiliationLink MongoAffiliationLink `bson:"affiliation_link"`
+}
+
+type Request struct {
+	Conference string      `json:"conference"`
+	Code       string      `json:"code"`
+	Format     string      `json:"format"`
+	Columns    string      `json:"columns"`
+	HTTP       HTTPRequest `json:"http"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+// orderedAuthors lists the presenters and authors of a contribution in the
+// order they appear on the paper, without repeating anyone listed as both
+func orderedAuthors(contribution MongoContribution) []MongoPerson {
+	var persons []MongoPerson
+	if contribution.Presenters != nil {
+		persons = append(persons, *contribution.Presenters...)
+	}
+	if contribution.Authors != nil {
+		persons = append(persons, *contribution.Authors...)
+	}
+	sort.SliceStable(persons, func(i, j int) bool {
+		return persons[i].DisplayOrder < persons[j].DisplayOrder
+	})
+	seen := make(map[string]bool)
+	var authors []MongoPerson
+	for _, person := range persons {
+		key := strings.ToLower(person.FirstName + " " + person.FamilyName)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		authors = append(authors, person)
+	}
+	return authors
+}
+
+func findConference(database *mongo.Database, conferenceId int) (MongoConference, error) {
+	var conference MongoConference
+	err := database.Collection("conferences").FindOne(context.Background(), bson.D{{"_id", conferenceId}}).Decode(&conference)
+	if err != nil {
+		return conference, fmt.Errorf("error finding conference: %s", err.Error())
+	}
+	return conference, nil
+}
+
+func findContributions(database *mongo.Database, conferenceId int, code string) ([]MongoContribution, error) {
+	filter := bson.D{{"conferenceId", conferenceId}}
+	if code != "" {
+		filter = append(filter, bson.E{"code", code})
+	}
+	findOptions := options.Find().SetSort(bson.D{{"code", 1}})
+	cursor, findError := database.Collection("contributions").Find(context.Background(), filter, findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding documents: %s", findError.Error())
+	}
+	var contributions = make([]MongoContribution, 0)
+	if err := cursor.All(context.Background(), &contributions); err != nil {
+		return nil, fmt.Errorf("error decoding documents: %s", err.Error())
+	}
+	return contributions, nil
+}
+
+// findRORs returns the ROR id mapped in the affiliations registry for each
+// indico affiliation id linked in the contributions
+func findRORs(database *mongo.Database, contributions []MongoContribution) (map[int]string, error) {
+	ids := bson.A{}
+	for _, contribution := range contributions {
+		for _, person := range contribution.Persons {
+			if person.AffiliationLink.ID != 0 {
+				ids = append(ids, person.AffiliationLink.ID)
+			}
+		}
+	}
+	filter := bson.D{
+		{"_id", bson.D{{"$in", ids}}},
+		{"ror", bson.D{{"$exists", true}}},
+	}
+	cursor, findError := database.Collection("affiliations").Find(context.Background(), filter)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding affiliations: %s", findError.Error())
+	}
+	var affiliations []struct {
+		ID  int    `bson:"_id"`
+		ROR string `bson:"ror"`
+	}
+	if err := cursor.All(context.Background(), &affiliations); err != nil {
+		return nil, fmt.Errorf("error decoding affiliations: %s", err.Error())
+	}
+	rors := make(map[int]string)
+	for _, affiliation := range affiliations {
+		rors[affiliation.ID] = affiliation.ROR
+	}
+	return rors, nil
+}
+
+// findORCIDs maps the indico person ids of a conference to the ORCIDs of
+// the identities they were resolved to when authors were indexed
+func findORCIDs(database *mongo.Database, conferenceId int) (map[int]string, error) {
+	filter := bson.D{
+		{"conferenceId", conferenceId},
+		{"personId", bson.D{{"$exists", true}}},
+		{"orcid", bson.D{{"$exists", true}}},
+	}
+	cursor, findError := database.Collection("author_index").Find(context.Background(), filter)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding identities: %s", findError.Error())
+	}
+	var entries []struct {
+		PersonID int    `bson:"personId"`
+		ORCID    string `bson:"orcid"`
+	}
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		return nil, fmt.Errorf("error decoding identities: %s", err.Error())
+	}
+	orcids := make(map[int]string)
+	for _, entry := range entries {
+		orcids[entry.PersonID] = entry.ORCID
+	}
+	return orcids, nil
+}
+
+// Main checks the API key and rate limit of the request before responding
+func Main(in Request) (*Response, error) {
+	return authorized(in.HTTP, in.Conference, func() (*Response, error) {
+		return respond(in)
+	})
+}
+
+func respond(in Request) (*Response, error) {
+	conferenceId, err := strconv.Atoi(in.Conference)
+	if err != nil {
+		return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+	}
+
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	database := client.Database("author-title")
+
+	conference, err := findConference(database, conferenceId)
+	if err != nil {
+		return nil, err
+	}
+	contributions, err := findContributions(database, conferenceId, in.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	switch in.Format {
+	case "bibtex":
+		return &Response{
+			Body: bibtex(conference, contributions),
+			Headers: map[string]string{
+				"Content-Type": "application/x-bibtex; charset=utf-8",
+			},
+		}, nil
+	case "csl-json":
+		body, err := cslJSON(conference, contributions)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling documents: %s", err.Error())
+		}
+		return &Response{
+			Body: body,
+			Headers: map[string]string{
+				"Content-Type": "application/vnd.citationstyles.csl+json",
+			},
+		}, nil
+	case "crossref":
+		rors, err := findRORs(database, contributions)
+		if err != nil {
+			return nil, err
+		}
+		orcids, err := findORCIDs(database, conferenceId)
+		if err != nil {
+			return nil, err
+		}
+		body, err := crossref(conference, contributions, rors, orcids, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("error building crossref deposit: %s", err.Error())
+		}
+		return &Response{
+			Body: body,
+			Headers: map[string]string{
+				"Content-Type": "application/xml; charset=utf-8",
+			},
+		}, nil
+	case "csv", "xlsx":
+		columns, err := selectColumns(in.Columns)
+		if err != nil {
+			return nil, err
+		}
+		rows := spreadsheetRows(contributions, columns)
+		if in.Format == "csv" {
+			body, err := csvExport(rows)
+			if err != nil {
+				return nil, fmt.Errorf("error writing csv: %s", err.Error())
+			}
+			return &Response{
+				Body: body,
+				Headers: map[string]string{
+					"Content-Type":        "text/csv; charset=utf-8",
+					"Content-Disposition": fmt.Sprintf("attachment; filename=\"%d.csv\"", conferenceId),
+				},
+			}, nil
+		}
+		xlsx, err := xlsxExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error writing xlsx: %s", err.Error())
+		}
+		return &Response{
+			Body: base64.StdEncoding.EncodeToString(xlsx),
+			Headers: map[string]string{
+				"Content-Type":        "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+				"Content-Disposition": fmt.Sprintf("attachment; filename=\"%d.xlsx\"", conferenceId),
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format: %s", in.Format)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+// TestResponseFixture keeps testdata/csl-json.json the same as the csl-json
+// response of export. Run go test -update after changing the output.
+func TestResponseFixture(t *testing.T) {
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		switch collection {
+		case "conferences":
+			return []interface{}{MongoConference{
+				ID:       41,
+				Name:     "15th International Particle Accelerator Conference",
+				Start:    time.Date(2024, 5, 19, 0, 0, 0, 0, time.UTC),
+				End:      time.Date(2024, 5, 24, 0, 0, 0, 0, time.UTC),
+				Location: "Nashville, TN, USA",
+				Acronym:  "IPAC'24",
+			}}
+		case "contributions":
+			return []interface{}{MongoContribution{
+				ID:           7,
+				Code:         "TUPA071",
+				Title:        "Beam loss monitors for the storage ring",
+				ConferenceId: 41,
+				Presenters:   &[]MongoPerson{{FirstName: "Ada", FamilyName: "Lovelace", DisplayOrder: 1}},
+				Authors:      &[]MongoPerson{{FirstName: "Jean", FamilyName: "Dupont", DisplayOrder: 2}},
+			}}
+		}
+		return nil
+	})
+	response, err := Main(Request{Conference: "41", Format: "csl-json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponse(t, "testdata/csl-json.json", response)
+}
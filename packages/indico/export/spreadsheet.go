@@ -0,0 +1,233 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+type SpreadsheetColumn struct {
+	Name  string
+	Value func(contribution MongoContribution) string
+}
+
+func personName(person MongoPerson) string {
+	return strings.TrimSpace(person.FirstName + " " + person.FamilyName)
+}
+
+// uniqueValues joins values in the order they first appear, skipping blanks
+func uniqueValues(values []string) string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		unique = append(unique, value)
+	}
+	return strings.Join(unique, "; ")
+}
+
+var spreadsheetColumns = []SpreadsheetColumn{
+	{"code", func(contribution MongoContribution) string {
+		return contribution.Code
+	}},
+	{"title", func(contribution MongoContribution) string {
+		return contribution.Title
+	}},
+	{"type", func(contribution MongoContribution) string {
+		return contribution.ContributionType
+	}},
+	{"session", func(contribution MongoContribution) string {
+		if contribution.Schedule == nil {
+			return ""
+		}
+		return strings.TrimSpace(contribution.Schedule.SessionCode + " " + contribution.Schedule.SessionTitle)
+	}},
+	{"presenter", func(contribution MongoContribution) string {
+		var names []string
+		if contribution.Presenters != nil {
+			for _, presenter := range *contribution.Presenters {
+				names = append(names, personName(presenter))
+			}
+		}
+		return uniqueValues(names)
+	}},
+	{"authors", func(contribution MongoContribution) string {
+		var names []string
+		for _, author := range orderedAuthors(contribution) {
+			names = append(names, personName(author))
+		}
+		return strings.Join(names, "; ")
+	}},
+	{"affiliations", func(contribution MongoContribution) string {
+		var affiliations []string
+		for _, author := range orderedAuthors(contribution) {
+			affiliations = append(affiliations, author.Affiliation)
+		}
+		return uniqueValues(affiliations)
+	}},
+	{"countries", func(contribution MongoContribution) string {
+		var countries []string
+		for _, person := range contribution.Persons {
+			countries = append(countries, person.AffiliationLink.CountryName)
+		}
+		return uniqueValues(countries)
+	}},
+	{"funding_agency", func(contribution MongoContribution) string {
+		return contribution.FundingAgency
+	}},
+	{"duplicate", func(contribution MongoContribution) string {
+		if contribution.IsDuplicate {
+			return "yes"
+		}
+		return "no"
+	}},
+}
+
+// selectColumns picks the requested comma separated columns, or all of them
+// when none were requested
+func selectColumns(names string) ([]SpreadsheetColumn, error) {
+	if names == "" {
+		return spreadsheetColumns, nil
+	}
+	var columns []SpreadsheetColumn
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, column := range spreadsheetColumns {
+			if column.Name == name {
+				columns = append(columns, column)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column: %s", name)
+		}
+	}
+	return columns, nil
+}
+
+func spreadsheetRows(contributions []MongoContribution, columns []SpreadsheetColumn) [][]string {
+	var header []string
+	for _, column := range columns {
+		header = append(header, column.Name)
+	}
+	rows := [][]string{header}
+	for _, contribution := range contributions {
+		var row []string
+		for _, column := range columns {
+			row = append(row, column.Value(contribution))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// spreadsheetCell quotes a value which a spreadsheet would otherwise run as
+// a formula, such as a title starting with "=" or "-", with a leading "'".
+// Only CSV needs it, as XLSX inline strings are never read as formulas.
+func spreadsheetCell(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
+
+func csvExport(rows [][]string) (string, error) {
+	var out bytes.Buffer
+	writer := csv.NewWriter(&out)
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for index, value := range row {
+			cells[index] = spreadsheetCell(value)
+		}
+		if err := writer.Write(cells); err != nil {
+			return "", err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const xlsxRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Contributions" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const xlsxWorkbookRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+// xlsxColumn converts a zero based column index to its letters, e.g. 27 is AB
+func xlsxColumn(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxExport writes the rows to a single sheet workbook using inline strings
+func xlsxExport(rows [][]string) ([]byte, error) {
+	var sheet strings.Builder
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for rowIndex, row := range rows {
+		fmt.Fprintf(&sheet, `<row r="%d">`, rowIndex+1)
+		for columnIndex, value := range row {
+			var escaped bytes.Buffer
+			_ = xml.EscapeText(&escaped, []byte(value))
+			fmt.Fprintf(&sheet, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`,
+				xlsxColumn(columnIndex), rowIndex+1, escaped.String())
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	var out bytes.Buffer
+	archive := zip.NewWriter(&out)
+	for _, file := range []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRelationships},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRelationships},
+		{"xl/worksheets/sheet1.xml", sheet.String()},
+	} {
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write([]byte(file.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSpreadsheetCell(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"Beam loss monitors", "Beam loss monitors"},
+		{"=HYPERLINK(\"http://example.org\")", "'=HYPERLINK(\"http://example.org\")"},
+		{"+1 for the linac", "'+1 for the linac"},
+		{"-20 dB coupling", "'-20 dB coupling"},
+		{"@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"\t=1+1", "'\t=1+1"},
+		{"\r=1+1", "'\r=1+1"},
+		{"a = b + c", "a = b + c"},
+		{"Jean-Luc", "Jean-Luc"},
+	}
+	for _, test := range tests {
+		if got := spreadsheetCell(test.value); got != test.want {
+			t.Errorf("spreadsheetCell(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func formulaContribution() MongoContribution {
+	return MongoContribution{
+		Code:    "TUPA071",
+		Title:   "=cmd|'/c calc'!A1",
+		Authors: &[]MongoPerson{{FirstName: "@Ada", FamilyName: "Lovelace", Affiliation: "+ANSTO", DisplayOrder: 1}},
+	}
+}
+
+func TestCSVExportQuotesFormulas(t *testing.T) {
+	columns, err := selectColumns("code,title,authors,affiliations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csv, err := csvExport(spreadsheetRows([]MongoContribution{formulaContribution()}, columns))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "code,title,authors,affiliations\nTUPA071,'=cmd|'/c calc'!A1,'@Ada Lovelace,'+ANSTO\n"
+	if csv != want {
+		t.Errorf("csvExport = %q, want %q", csv, want)
+	}
+}
+
+func TestXLSXExportKeepsValues(t *testing.T) {
+	columns, err := selectColumns("title,affiliations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xlsx, err := xlsxExport(spreadsheetRows([]MongoContribution{formulaContribution()}, columns))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(xlsx), int64(len(xlsx)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet string
+	for _, file := range archive.File {
+		if file.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet = string(content)
+	}
+	for _, want := range []string{
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">=cmd|&#39;/c calc&#39;!A1</t></is></c>`,
+		`<c r="B2" t="inlineStr"><is><t xml:space="preserve">+ANSTO</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet does not contain %s:\n%s", want, sheet)
+		}
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	tests := []struct {
+		names string
+		want  []string
+		err   bool
+	}{
+		{"", []string{"code", "title", "type", "session", "presenter", "authors", "affiliations", "countries", "funding_agency", "duplicate"}, false},
+		{"code, title", []string{"code", "title"}, false},
+		{"title,code", []string{"title", "code"}, false},
+		{"code,email", nil, true},
+	}
+	for _, test := range tests {
+		columns, err := selectColumns(test.names)
+		if (err != nil) != test.err {
+			t.Errorf("selectColumns(%q): %v", test.names, err)
+			continue
+		}
+		var got []string
+		for _, column := range columns {
+			got = append(got, column.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("selectColumns(%q) = %v, want %v", test.names, got, test.want)
+		}
+	}
+}
+
+func TestUniqueValues(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{nil, ""},
+		{[]string{"CERN", "", "DESY", "CERN"}, "CERN; DESY"},
+		{[]string{"", ""}, ""},
+	}
+	for _, test := range tests {
+		if got := uniqueValues(test.values); got != test.want {
+			t.Errorf("uniqueValues(%q) = %q, want %q", test.values, got, test.want)
+		}
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"}
+	for index, want := range tests {
+		if got := xlsxColumn(index); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", index, got, want)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+#!/bin/sh
+# Fetches the Crossref deposit schemas, with the schemas they import, into
+# testdata/crossref for the schema check in crossref_test.go. They are
+# committed, so only run this to move to a new schema version.
+set -e
+cd "$(dirname "$0")"
+rm -rf crossref
+mkdir crossref
+curl -fsSL "https://gitlab.com/crossref/schema/-/archive/master/schema-master.tar.gz?path=schemas" |
+	tar -xz -C crossref --strip-components=2
+test -f crossref/crossref5.3.1.xsd
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/footnotes.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footnotes+xml"/>
+</Types>`
+
+const docxRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`
+
+const docxDocumentRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/footnotes" Target="footnotes.xml"/>
+</Relationships>`
+
+const docxNamespace = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+
+func escapeXML(text string) string {
+	var out bytes.Buffer
+	_ = xml.EscapeText(&out, []byte(text))
+	return out.String()
+}
+
+// docxRun is a run of text, optionally bold, capitalised or superscripted
+func docxRun(text string, properties ...string) string {
+	return fmt.Sprintf(`<w:r><w:rPr>%s</w:rPr><w:t xml:space="preserve">%s</w:t></w:r>`,
+		strings.Join(properties, ""), escapeXML(text))
+}
+
+func docxFootnoteReference(id int) string {
+	return fmt.Sprintf(`<w:r><w:rPr><w:vertAlign w:val="superscript"/></w:rPr><w:footnoteReference w:id="%d"/></w:r>`, id)
+}
+
+func docxParagraph(runs ...string) string {
+	return `<w:p><w:pPr><w:jc w:val="center"/></w:pPr>` + strings.Join(runs, "") + `</w:p>`
+}
+
+const (
+	docxBold        = `<w:b/>`
+	docxCaps        = `<w:caps/>`
+	docxSuperscript = `<w:vertAlign w:val="superscript"/>`
+	docxTitleSize   = `<w:sz w:val="28"/>`
+	docxAuthorSize  = `<w:sz w:val="24"/>`
+)
+
+// generatorPayloadToDocx builds a Word document containing the title and
+// author block of a paper in the style of the JACoW Word template
+func generatorPayloadToDocx(payloads []GeneratorPayload) ([]byte, error) {
+	var body []string
+	var footnotes []string
+	for _, payload := range payloads {
+		titleRuns := []string{docxRun(payload.Title, docxBold, docxCaps, docxTitleSize)}
+		var titleFootnotes []string
+		if payload.FundingAgency != "" {
+			titleFootnotes = append(titleFootnotes, "Work supported by "+payload.FundingAgency)
+		}
+		if payload.Footnotes != "" {
+			titleFootnotes = append(titleFootnotes, payload.Footnotes)
+		}
+		for _, footnote := range titleFootnotes {
+			// Ids 0 and 1 are taken by the separators
+			id := len(footnotes) + 2
+			footnotes = append(footnotes, fmt.Sprintf(`<w:footnote w:id="%d"><w:p>%s%s</w:p></w:footnote>`,
+				id, `<w:r><w:rPr><w:vertAlign w:val="superscript"/></w:rPr><w:footnoteRef/></w:r>`, docxRun(" "+footnote)))
+			titleRuns = append(titleRuns, docxFootnoteReference(id))
+		}
+		body = append(body, docxParagraph(titleRuns...))
+
+		authors, affiliations := paperAuthorBlock(payload)
+		var authorRuns []string
+		for index, author := range authors {
+			if index > 0 {
+				authorRuns = append(authorRuns, docxRun(", ", docxAuthorSize))
+			}
+			var superscripts []string
+			for _, number := range author.Affiliations {
+				superscripts = append(superscripts, fmt.Sprint(number))
+			}
+			authorRuns = append(authorRuns,
+				docxRun(author.Name, docxAuthorSize),
+				docxRun(strings.Join(superscripts, ","), docxAuthorSize, docxSuperscript))
+		}
+		body = append(body, docxParagraph(authorRuns...))
+
+		for _, affiliation := range affiliations {
+			body = append(body, docxParagraph(
+				docxRun(fmt.Sprint(affiliation.Number), docxAuthorSize, docxSuperscript),
+				docxRun(affiliation.Text, docxAuthorSize)))
+		}
+	}
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document ` + docxNamespace + `><w:body>` + strings.Join(body, "") + `</w:body></w:document>`
+	footnotesDocument := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:footnotes ` + docxNamespace + `>` +
+		`<w:footnote w:type="separator" w:id="0"><w:p><w:r><w:separator/></w:r></w:p></w:footnote>` +
+		`<w:footnote w:type="continuationSeparator" w:id="1"><w:p><w:r><w:continuationSeparator/></w:r></w:p></w:footnote>` +
+		strings.Join(footnotes, "") + `</w:footnotes>`
+
+	var out bytes.Buffer
+	archive := zip.NewWriter(&out)
+	for _, file := range []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRelationships},
+		{"word/_rels/document.xml.rels", docxDocumentRelationships},
+		{"word/document.xml", document},
+		{"word/footnotes.xml", footnotesDocument},
+	} {
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write([]byte(file.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
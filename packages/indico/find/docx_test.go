@@ -0,0 +1,122 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEscapeXML(t *testing.T) {
+	tests := map[string]string{
+		"Beam loss monitors":    "Beam loss monitors",
+		"R&D <linacs>":          "R&amp;D &lt;linacs&gt;",
+		`"quoted" and 'single'`: "&#34;quoted&#34; and &#39;single&#39;",
+		"Émile Müller":          "Émile Müller",
+	}
+	for text, want := range tests {
+		if got := escapeXML(text); got != want {
+			t.Errorf("escapeXML(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestDocxRun(t *testing.T) {
+	tests := []struct {
+		text       string
+		properties []string
+		want       string
+	}{
+		{"A. Lovelace", nil, `<w:r><w:rPr></w:rPr><w:t xml:space="preserve">A. Lovelace</w:t></w:r>`},
+		{"R&D", []string{docxBold, docxCaps}, `<w:r><w:rPr><w:b/><w:caps/></w:rPr><w:t xml:space="preserve">R&amp;D</w:t></w:r>`},
+	}
+	for _, test := range tests {
+		if got := docxRun(test.text, test.properties...); got != test.want {
+			t.Errorf("docxRun(%q) = %s, want %s", test.text, got, test.want)
+		}
+	}
+}
+
+// docxParagraphs unzips a document and returns the text of each paragraph
+// of one of its parts, failing if the part is missing or not well formed
+func docxParagraphs(t *testing.T, docx []byte, name string) []string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(docx), int64(len(docx)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paragraphs []string
+		var text strings.Builder
+		decoder := xml.NewDecoder(reader)
+		for {
+			token, err := decoder.Token()
+			if err == io.EOF {
+				return paragraphs
+			}
+			if err != nil {
+				t.Fatalf("%s is not well formed: %s", name, err.Error())
+			}
+			switch token := token.(type) {
+			case xml.CharData:
+				text.Write(token)
+			case xml.EndElement:
+				if token.Name.Local == "p" {
+					paragraphs = append(paragraphs, text.String())
+					text.Reset()
+				}
+			}
+		}
+	}
+	t.Fatalf("%s is not in the document", name)
+	return nil
+}
+
+func TestGeneratorPayloadToDocx(t *testing.T) {
+	payload := GeneratorPayload{
+		Title: "R&D of <linacs>",
+		Authors: map[int]GeneratorAuthor{
+			2: {FirstName: "Jean-Pierre", LastName: "Müller", Affiliations: []int{4, 1}},
+			1: {FirstName: "Ada", LastName: "Lovelace", Affiliations: []int{1}},
+		},
+		Organisations: map[int]GeneratorOrganisation{
+			1: {Name: "ANSTO", Location: "Clayton, Australia"},
+			4: {Name: "PSI"},
+		},
+		FundingAgency: "DOE",
+		Footnotes:     "ada@example.org",
+	}
+	docx, err := generatorPayloadToDocx([]GeneratorPayload{payload, {Title: "Second paper"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"R&D of <linacs>",
+		"A. Lovelace1, J.-P. Müller2,1",
+		"1ANSTO, Clayton, Australia",
+		"2PSI",
+		"Second paper",
+		"",
+	}
+	if got := docxParagraphs(t, docx, "word/document.xml"); !reflect.DeepEqual(got, want) {
+		t.Errorf("document paragraphs = %q, want %q", got, want)
+	}
+	want = []string{"", "", " Work supported by DOE", " ada@example.org"}
+	if got := docxParagraphs(t, docx, "word/footnotes.xml"); !reflect.DeepEqual(got, want) {
+		t.Errorf("footnote paragraphs = %q, want %q", got, want)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/_rels/document.xml.rels"} {
+		docxParagraphs(t, docx, name)
+	}
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type MongoContribution struct {
@@ -23,6 +26,8 @@ type MongoContribution struct {
 	Persons          []MongoDetailedPerson `bson:"persons"`
 	IsDuplicate      bool                  `bson:"is_duplicate"`
 	ContributionType string                `bson:"contribution_type"`
+	FundingAgency    string                `bson:"funding_agency"`
+	Footnotes        string                `bson:"footnotes"`
 }
 
 type MongoPerson struct {
@@ -42,6 +47,20 @@ type MongoAffiliationLink struct {
 	Postcode    string `bson:"postcode"`
 }
 
+// MongoAffiliation is an affiliation from the registry kept by the
+// contributions sync, with the canonical name and ROR id set by editors
+type MongoAffiliation struct {
+	ID            int      `bson:"_id"`
+	Name          string   `bson:"name"`
+	City          string   `bson:"city"`
+	CountryName   string   `bson:"countryName"`
+	CountryCode   string   `bson:"countryCode"`
+	Postcode      string   `bson:"postcode"`
+	CanonicalName string   `bson:"canonicalName"`
+	Aliases       []string `bson:"aliases"`
+	ROR           string   `bson:"ror"`
+}
+
 type MongoDetailedPerson struct {
 	ID        int    `bson:"person_id"`
 	FirstName string `bson:"first_name"`
@@ -51,11 +70,22 @@ type MongoDetailedPerson struct {
 	AuthorType      string               `bson:"author_type"`
 	Affiliation     string               `bson:"affiliation"`
 	AffiliationLink MongoAffiliationLink `bson:"affiliation_link"`
+	ORCID           string               `bson:"orcid,omitempty"`
+}
+
+// MongoConference holds the formatting settings editors can set on a
+// conference document
+type MongoConference struct {
+	ID                int               `bson:"_id"`
+	AffiliationFormat string            `bson:"affiliationFormat"`
+	CountryNames      map[string]string `bson:"countryNames"`
 }
 
 type Request struct {
-	Conference string `json:"conference"`
-	Code       string `json:"code"`
+	Conference string      `json:"conference"`
+	Code       string      `json:"code"`
+	Format     string      `json:"format"`
+	HTTP       HTTPRequest `json:"http"`
 }
 
 type Response struct {
@@ -64,119 +94,50 @@ type Response struct {
 	Body       string            `json:"body,omitempty"`
 }
 
-type GeneratorOrganisation struct {
-	Name     string `json:"name"`
-	Location string `json:"location"`
-	Zipcode  string `json:"zipcode"`
-}
-
-type GeneratorAuthor struct {
-	FirstName    string `json:"first_name"`
-	LastName     string `json:"last_name"`
-	Affiliations []int  `json:"affiliations"`
-}
-
-type GeneratorPayload struct {
-	Title         string                        `json:"title"`
-	Authors       map[int]GeneratorAuthor       `json:"authors"`
-	Organisations map[int]GeneratorOrganisation `json:"organisations"`
-}
-
-func getAuthorsAndOrganisations(mongoPersons []MongoPerson, affiliations []MongoAffiliationLink) (map[int]GeneratorAuthor, map[int]GeneratorOrganisation) {
-
-	authors := make(map[int]GeneratorAuthor)
-	uniqueOrganisations := make(map[int]GeneratorOrganisation)
-	organisationCount := 0
-	for _, mongoPerson := range mongoPersons {
-		var position int = -1
-		for index, organisation := range uniqueOrganisations {
-			if organisation.Name == mongoPerson.Affiliation {
-				position = index
-			}
-		}
-
-		if position == -1 {
-			uniqueOrganisations[organisationCount] = findAffiliationDetails(mongoPerson.Affiliation, affiliations)
-			position = organisationCount
-			organisationCount++
-		}
-
-		var affiliations []int
-		affiliations = append(affiliations, position)
-
-		author := GeneratorAuthor{
-			FirstName:    mongoPerson.FirstName,
-			LastName:     mongoPerson.FamilyName,
-			Affiliations: affiliations,
-		}
-
-		if _, ok := authors[mongoPerson.DisplayOrder]; ok {
-			for {
-				if _, ok := authors[mongoPerson.DisplayOrder+1]; ok {
-					mongoPerson.DisplayOrder++
-				} else {
-					break
-				}
-			}
-			authors[mongoPerson.DisplayOrder] = author
-		} else {
-			authors[mongoPerson.DisplayOrder] = author
-		}
+// findAffiliations looks up the affiliations linked by the persons of a
+// contribution in the affiliations registry, keyed by their indico id.
+// Affiliations not yet in the registry fall back to the link stored with the
+// person.
+func findAffiliations(collection *mongo.Collection, allPersons []MongoDetailedPerson) (map[int]MongoAffiliation, error) {
+	affiliations := linkedAffiliations(allPersons)
+	if len(affiliations) == 0 {
+		return affiliations, nil
 	}
 
-	return authors, uniqueOrganisations
-}
-
-func findAllAffiliationLinks(allPersons []MongoDetailedPerson) []MongoAffiliationLink {
-	uniqueAffiliations := make(map[int]MongoAffiliationLink)
-	for _, person := range allPersons {
-		uniqueAffiliations[person.AffiliationLink.ID] = person.AffiliationLink
+	cursor, findError := collection.Find(context.Background(), registeredAffiliationsFilter(affiliations))
+	if findError != nil {
+		return nil, fmt.Errorf("error finding affiliations: %s", findError.Error())
 	}
-	var allAffiliations []MongoAffiliationLink
-	for _, affiliation := range uniqueAffiliations {
-		allAffiliations = append(allAffiliations, affiliation)
+	var registered []MongoAffiliation
+	if err := cursor.All(context.Background(), &registered); err != nil {
+		return nil, fmt.Errorf("error decoding affiliations: %s", err.Error())
 	}
-	return allAffiliations
+	addRegisteredAffiliations(affiliations, registered)
+	return affiliations, nil
 }
 
-func findAffiliationDetails(name string, allAffiliations []MongoAffiliationLink) GeneratorOrganisation {
-	for _, affiliation := range allAffiliations {
-		if affiliation.Name == name {
-			return GeneratorOrganisation{
-				Name:     affiliation.Name,
-				Location: affiliation.City + ", " + affiliation.CountryName,
-				Zipcode:  affiliation.Postcode,
-			}
-		}
+// findIdentities returns the resolved identities of the persons of a
+// contribution, keyed by their indico person id
+func findIdentities(collection *mongo.Collection, contribution MongoContribution) (map[int]MongoAuthorIdentity, error) {
+	cursor, findError := collection.Find(context.Background(), identitiesFilter(contribution))
+	if findError != nil {
+		return nil, fmt.Errorf("error finding identities: %s", findError.Error())
 	}
-	return GeneratorOrganisation{
-		Name:     name,
-		Location: "",
-		Zipcode:  "",
+	var entries []MongoAuthorIdentity
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		return nil, fmt.Errorf("error decoding identities: %s", err.Error())
 	}
+	return identitiesByPerson(entries), nil
 }
 
-func mongoToGeneratorPayload(contribution MongoContribution) GeneratorPayload {
-	var mongoPersons []MongoPerson
-	mongoPersons = append(mongoPersons, *contribution.Presenters...)
-	mongoPersons = append(mongoPersons, *contribution.Authors...)
-	for i := 0; i < len(mongoPersons); i++ {
-		for j := 0; j < len(mongoPersons)-1; j++ {
-			if mongoPersons[j].DisplayOrder > mongoPersons[j+1].DisplayOrder {
-				mongoPersons[j], mongoPersons[j+1] = mongoPersons[j+1], mongoPersons[j]
-			}
-		}
-	}
-	affiliations := findAllAffiliationLinks(contribution.Persons)
-	authors, uniqueOrganisations := getAuthorsAndOrganisations(mongoPersons, affiliations)
-	return GeneratorPayload{
-		Title:         contribution.Title,
-		Authors:       authors,
-		Organisations: uniqueOrganisations,
-	}
+// Main checks the API key and rate limit of the request before responding
+func Main(in Request) (*Response, error) {
+	return authorized(in.HTTP, in.Conference, func() (*Response, error) {
+		return respond(in)
+	})
 }
 
-func Main(in Request) (*Response, error) {
+func respond(in Request) (*Response, error) {
 
 	// Convert in.Conference to int
 	conferenceId, err := strconv.Atoi(in.Conference)
@@ -191,7 +152,8 @@ func Main(in Request) (*Response, error) {
 		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
 	}
 
-	collection := client.Database("author-title").Collection("contributions")
+	database := client.Database("author-title")
+	collection := database.Collection("contributions")
 
 	cursor, findError := collection.Find(context.Background(), bson.D{
 		{"conferenceId", conferenceId},
@@ -207,9 +169,51 @@ func Main(in Request) (*Response, error) {
 		return nil, fmt.Errorf("error decoding documents: %s", err.Error())
 	}
 
+	// A conference without a document yet uses the default formatting
+	conference := MongoConference{ID: conferenceId}
+	err = database.Collection("conferences").FindOne(context.Background(), bson.D{{"_id", conferenceId}}).Decode(&conference)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("error finding conference: %s", err.Error())
+	}
+
 	var output []GeneratorPayload = make([]GeneratorPayload, 0)
 	for _, contribution := range contributions {
-		output = append(output, mongoToGeneratorPayload(contribution))
+		affiliations, err := findAffiliations(database.Collection("affiliations"), contribution.Persons)
+		if err != nil {
+			return nil, err
+		}
+		identities, err := findIdentities(database.Collection("author_index"), contribution)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, mongoToGeneratorPayload(contribution, conference, affiliations, identities))
+	}
+
+	if in.Format == "latex" {
+		var blocks []string
+		for _, payload := range output {
+			blocks = append(blocks, generatorPayloadToLatex(payload))
+		}
+		return &Response{
+			Body: strings.Join(blocks, "\n"),
+			Headers: map[string]string{
+				"Content-Type": "application/x-latex; charset=utf-8",
+			},
+		}, nil
+	}
+
+	if in.Format == "docx" {
+		docx, err := generatorPayloadToDocx(output)
+		if err != nil {
+			return nil, fmt.Errorf("error generating docx: %s", err.Error())
+		}
+		return &Response{
+			Body: base64.StdEncoding.EncodeToString(docx),
+			Headers: map[string]string{
+				"Content-Type":        "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+				"Content-Disposition": fmt.Sprintf("attachment; filename=\"%s.docx\"", in.Code),
+			},
+		}, nil
 	}
 
 	// Output as Json
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// latexAccents maps combining marks to the LaTeX command which adds them to
+// the preceding letter
+var latexAccents = map[rune]string{
+	'\u0300': "`",
+	'\u0301': "'",
+	'\u0302': "^",
+	'\u0303': "~",
+	'\u0304': "=",
+	'\u0306': "u",
+	'\u0307': ".",
+	'\u0308': "\"",
+	'\u030A': "r",
+	'\u030B': "H",
+	'\u030C': "v",
+	'\u0327': "c",
+	'\u0328': "k",
+}
+
+// latexLetters are letters without a decomposition which still need a
+// command in LaTeX
+var latexLetters = map[rune]string{
+	'ß': "{\\ss}",
+	'æ': "{\\ae}",
+	'Æ': "{\\AE}",
+	'œ': "{\\oe}",
+	'Œ': "{\\OE}",
+	'ø': "{\\o}",
+	'Ø': "{\\O}",
+	'ł': "{\\l}",
+	'Ł': "{\\L}",
+	'ı': "{\\i}",
+	'đ': "{\\dj}",
+	'Đ': "{\\DJ}",
+}
+
+var latexSpecials = map[rune]string{
+	'\\': "\\textbackslash{}",
+	'{':  "\\{",
+	'}':  "\\}",
+	'$':  "\\$",
+	'&':  "\\&",
+	'#':  "\\#",
+	'^':  "\\textasciicircum{}",
+	'_':  "\\_",
+	'%':  "\\%",
+	'~':  "\\textasciitilde{}",
+}
+
+// escapeLatex escapes LaTeX special characters and writes accented letters
+// using accent commands, so the output compiles without unicode support
+func escapeLatex(text string) string {
+	var out strings.Builder
+	runes := []rune(norm.NFD.String(text))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if special, ok := latexSpecials[r]; ok {
+			out.WriteString(special)
+			continue
+		}
+		if letter, ok := latexLetters[r]; ok {
+			out.WriteString(letter)
+			continue
+		}
+		var accents []string
+		cluster := []rune{r}
+		unmapped := false
+		for i+1 < len(runes) && unicode.Is(unicode.Mn, runes[i+1]) {
+			if accent, ok := latexAccents[runes[i+1]]; ok {
+				accents = append(accents, accent)
+			} else {
+				unmapped = true
+			}
+			cluster = append(cluster, runes[i+1])
+			i++
+		}
+		// A mark without a command, such as the dot below of Vietnamese,
+		// would be lost, so the letter is kept as it was written
+		if unmapped || len(accents) == 0 {
+			out.WriteString(norm.NFC.String(string(cluster)))
+			continue
+		}
+		letter := string(r)
+		if r == 'i' {
+			letter = "\\i"
+		} else if r == 'j' {
+			letter = "\\j"
+		}
+		for _, accent := range accents {
+			letter = "\\" + accent + "{" + letter + "}"
+		}
+		out.WriteString("{" + letter + "}")
+	}
+	return out.String()
+}
+
+// generatorPayloadToLatex renders the title and author block of a paper in
+// the style of the JACoW LaTeX template
+func generatorPayloadToLatex(payload GeneratorPayload) string {
+	var out strings.Builder
+
+	title := escapeLatex(payload.Title)
+	if payload.FundingAgency != "" {
+		title += "\\thanks{Work supported by " + escapeLatex(payload.FundingAgency) + "}"
+	}
+	if payload.Footnotes != "" {
+		title += "\\thanks{" + escapeLatex(payload.Footnotes) + "}"
+	}
+	fmt.Fprintf(&out, "\\title{%s}\n", title)
+
+	authors, affiliations := paperAuthorBlock(payload)
+	var names []string
+	for _, author := range authors {
+		var superscripts []string
+		for _, number := range author.Affiliations {
+			superscripts = append(superscripts, fmt.Sprint(number))
+		}
+		names = append(names, fmt.Sprintf("%s\\textsuperscript{%s}", escapeLatex(author.Name), strings.Join(superscripts, ",")))
+	}
+
+	var lines []string
+	lines = append(lines, strings.Join(names, ", "))
+	for _, affiliation := range affiliations {
+		lines = append(lines, fmt.Sprintf("\\textsuperscript{%d}%s", affiliation.Number, escapeLatex(affiliation.Text)))
+	}
+	fmt.Fprintf(&out, "\\author{%s}\n", strings.Join(lines, " \\\\\n\t"))
+
+	return out.String()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEscapeLatex(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Beam loss monitors", "Beam loss monitors"},
+		{"100% of R&D_1 costs $5 #2", "100\\% of R\\&D\\_1 costs \\$5 \\#2"},
+		{"{a}\\b", "\\{a\\}\\textbackslash{}b"},
+		{"x^2 ~ y", "x\\textasciicircum{}2 \\textasciitilde{} y"},
+		{"Émile Müller", "{\\'{E}}mile M{\\\"{u}}ller"},
+		{"Dvořák", "Dvo{\\v{r}}{\\'{a}}k"},
+		{"naïve", "na{\\\"{\\i}}ve"},
+		{"Ångström", "{\\r{A}}ngstr{\\\"{o}}m"},
+		{"François", "Fran{\\c{c}}ois"},
+		{"Łódź", "{\\L}{\\'{o}}d{\\'{z}}"},
+		{"Straße", "Stra{\\ss}e"},
+		{"Nguyễn", "Nguy{\\~{\\^{e}}}n"},
+		{"Иванова", "Иванова"},
+		{"Phạm Nguyệt", "Phạm Nguyệt"},
+		{"Trương", "Trương"},
+		{"Ṣàngó", "Ṣ{\\`{a}}ng{\\'{o}}"},
+	}
+	for _, test := range tests {
+		if got := escapeLatex(test.text); got != test.want {
+			t.Errorf("escapeLatex(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestGeneratorPayloadToLatex(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload GeneratorPayload
+		want    string
+	}{
+		{
+			name: "single author",
+			payload: GeneratorPayload{
+				Title:         "Beam loss monitors",
+				Authors:       map[int]GeneratorAuthor{1: {FirstName: "Ada", LastName: "Lovelace", Affiliations: []int{1}}},
+				Organisations: map[int]GeneratorOrganisation{1: {Name: "ANSTO", Location: "Clayton, Australia"}},
+			},
+			want: "\\title{Beam loss monitors}\n" +
+				"\\author{A. Lovelace\\textsuperscript{1} \\\\\n" +
+				"\t\\textsuperscript{1}ANSTO, Clayton, Australia}\n",
+		},
+		{
+			name: "funding, footnotes and shared affiliations",
+			payload: GeneratorPayload{
+				Title: "R&D of 100% duty cycle linacs",
+				Authors: map[int]GeneratorAuthor{
+					2: {FirstName: "Jean-Pierre", LastName: "Müller", Affiliations: []int{4, 1}},
+					1: {FirstName: "Ada", LastName: "Lovelace", Affiliations: []int{1}},
+				},
+				Organisations: map[int]GeneratorOrganisation{
+					1: {Name: "ANSTO", Location: "Clayton, Australia"},
+					4: {Name: "PSI"},
+				},
+				FundingAgency: "DOE",
+				Footnotes:     "ada@example.org",
+			},
+			want: "\\title{R\\&D of 100\\% duty cycle linacs\\thanks{Work supported by DOE}\\thanks{ada@example.org}}\n" +
+				"\\author{A. Lovelace\\textsuperscript{1}, J.-P. M{\\\"{u}}ller\\textsuperscript{2,1} \\\\\n" +
+				"\t\\textsuperscript{1}ANSTO, Clayton, Australia \\\\\n" +
+				"\t\\textsuperscript{2}PSI}\n",
+		},
+	}
+	for _, test := range tests {
+		if got := generatorPayloadToLatex(test.payload); got != test.want {
+			t.Errorf("%s: generatorPayloadToLatex =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
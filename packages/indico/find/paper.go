@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// PaperAuthor is an author as printed on a paper, with the numbers of the
+// affiliations they belong to
+type PaperAuthor struct {
+	Name         string
+	Affiliations []int
+}
+
+type PaperAffiliation struct {
+	Number int
+	Text   string
+}
+
+// initials shortens first names to the initials used by JACoW, keeping
+// hyphenated names together, e.g. "Jean-Pierre Paul" becomes "J.-P. P."
+func initials(firstName string) string {
+	var names []string
+	for _, name := range strings.Fields(firstName) {
+		var parts []string
+		for _, part := range strings.Split(name, "-") {
+			runes := []rune(part)
+			if len(runes) == 0 {
+				continue
+			}
+			parts = append(parts, string(runes[0])+".")
+		}
+		names = append(names, strings.Join(parts, "-"))
+	}
+	return strings.Join(names, " ")
+}
+
+func sortedAuthorKeys(authors map[int]GeneratorAuthor) []int {
+	var keys []int
+	for key := range authors {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// paperAuthorBlock orders the authors of a payload by display order and
+// numbers organisations from 1 in the order they are first referenced
+func paperAuthorBlock(payload GeneratorPayload) ([]PaperAuthor, []PaperAffiliation) {
+	numbers := make(map[int]int)
+	var authors []PaperAuthor
+	var affiliations []PaperAffiliation
+	for _, key := range sortedAuthorKeys(payload.Authors) {
+		author := payload.Authors[key]
+		paperAuthor := PaperAuthor{
+			Name: strings.TrimSpace(initials(author.FirstName) + " " + author.LastName),
+		}
+		for _, affiliation := range author.Affiliations {
+			if _, found := numbers[affiliation]; !found {
+				numbers[affiliation] = len(affiliations) + 1
+				organisation := payload.Organisations[affiliation]
+				var parts []string
+				for _, part := range []string{organisation.Name, organisation.Location} {
+					if part != "" {
+						parts = append(parts, part)
+					}
+				}
+				affiliations = append(affiliations, PaperAffiliation{
+					Number: numbers[affiliation],
+					Text:   strings.Join(parts, ", "),
+				})
+			}
+			paperAuthor.Affiliations = append(paperAuthor.Affiliations, numbers[affiliation])
+		}
+		authors = append(authors, paperAuthor)
+	}
+	return authors, affiliations
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInitials(t *testing.T) {
+	tests := map[string]string{
+		"":                 "",
+		"Ada":              "A.",
+		"J.":               "J.",
+		"Jean-Pierre Paul": "J.-P. P.",
+		"  Ada   Augusta ": "A. A.",
+		"Jean-":            "J.",
+		"Émile":            "É.",
+		"Ирина Алексеевна": "И. А.",
+		"小明":               "小.",
+	}
+	for firstName, want := range tests {
+		if got := initials(firstName); got != want {
+			t.Errorf("initials(%q) = %q, want %q", firstName, got, want)
+		}
+	}
+}
+
+func TestPaperAuthorBlock(t *testing.T) {
+	payload := GeneratorPayload{
+		Authors: map[int]GeneratorAuthor{
+			3: {LastName: "Wei", Affiliations: []int{9}},
+			2: {FirstName: "Jean-Pierre", LastName: "Müller", Affiliations: []int{4, 1}},
+			1: {FirstName: "Ada", LastName: "Lovelace", Affiliations: []int{1}},
+		},
+		Organisations: map[int]GeneratorOrganisation{
+			1: {Name: "ANSTO", Location: "Clayton, Australia"},
+			4: {Name: "PSI"},
+			9: {Location: "Beijing, China"},
+		},
+	}
+	authors, affiliations := paperAuthorBlock(payload)
+	wantAuthors := []PaperAuthor{
+		{Name: "A. Lovelace", Affiliations: []int{1}},
+		{Name: "J.-P. Müller", Affiliations: []int{2, 1}},
+		{Name: "Wei", Affiliations: []int{3}},
+	}
+	wantAffiliations := []PaperAffiliation{
+		{Number: 1, Text: "ANSTO, Clayton, Australia"},
+		{Number: 2, Text: "PSI"},
+		{Number: 3, Text: "Beijing, China"},
+	}
+	if !reflect.DeepEqual(authors, wantAuthors) {
+		t.Errorf("authors = %+v, want %+v", authors, wantAuthors)
+	}
+	if !reflect.DeepEqual(affiliations, wantAffiliations) {
+		t.Errorf("affiliations = %+v, want %+v", affiliations, wantAffiliations)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"strconv"
+	"testing"
+)
+
+// findReply is a contribution with two authors sharing an affiliation from
+// the registry and a presenter whose affiliation isn't in it
+func findReply(command string, collection string, body bson.Raw) []interface{} {
+	switch collection {
+	case "contributions":
+		return []interface{}{MongoContribution{
+			ID:    7,
+			Code:  "TUPA071",
+			Title: "Beam loss monitors for the storage ring",
+			Presenters: &[]MongoPerson{
+				{FirstName: "Ada", FamilyName: "Lovelace", Affiliation: "ANSTO", DisplayOrder: 1},
+			},
+			Authors: &[]MongoPerson{
+				{FirstName: "Ирина", FamilyName: "Иванова", Affiliation: "ANSTO", DisplayOrder: 2},
+				{FirstName: "Jean", FamilyName: "Dupont", Affiliation: "Unlisted Laboratory", DisplayOrder: 3},
+			},
+			ConferenceId: 41,
+			Persons: []MongoDetailedPerson{
+				{ID: 11, FirstName: "Ada", LastName: "Lovelace", AffiliationLink: MongoAffiliationLink{ID: 1, Name: "ANSTO"}},
+			},
+			FundingAgency: "Work supported by the Example Foundation",
+		}}
+	case "affiliations":
+		return []interface{}{MongoAffiliation{
+			ID:            1,
+			Name:          "ANSTO",
+			City:          "Clayton",
+			CountryName:   "Australia",
+			CountryCode:   "AU",
+			Postcode:      "3168",
+			CanonicalName: "Australian Nuclear Science and Technology Organisation",
+			ROR:           "https://ror.org/05j7fep28",
+		}}
+	case "author_index":
+		return []interface{}{
+			MongoAuthorIdentity{PersonID: 11, FirstName: "Ada", LastName: "Lovelace", AuthorID: "a1b2", ORCID: "0000-0002-1825-0097"},
+		}
+	}
+	return nil
+}
+
+// TestResponseFixture keeps testdata/response.json the same as the response
+// of find. Run go test -update after changing the output.
+func TestResponseFixture(t *testing.T) {
+	fakeMongo(t, findReply)
+	response, err := Main(Request{Conference: "41", Code: "TUPA071"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponse(t, "testdata/response.json", response)
+}
+
+// TestErrorFixtures keeps the fixtures of the responses authorized refuses
+// requests with, which are the same for every web function
+func TestErrorFixtures(t *testing.T) {
+	tests := []struct {
+		path    string
+		headers map[string]string
+		reply   mongoReply
+	}{
+		{"testdata/unauthorized.json", map[string]string{"X-API-Key": "imw_unknown"}, findReply},
+		{"testdata/forbidden.json", map[string]string{"X-API-Key": "imw_scoped"}, func(command string, collection string, body bson.Raw) []interface{} {
+			if collection == "api_keys" {
+				return []interface{}{MongoAPIKey{ID: hashAPIKey("imw_scoped"), Conferences: []int{58}}}
+			}
+			return nil
+		}},
+		{"testdata/too-many-requests.json", nil, func(command string, collection string, body bson.Raw) []interface{} {
+			if collection == "rate_limits" {
+				return []interface{}{bson.D{{"count", 61}}}
+			}
+			return nil
+		}},
+	}
+	for _, test := range tests {
+		fakeMongo(t, test.reply)
+		response, err := Main(Request{Conference: "41", Code: "TUPA071", HTTP: HTTPRequest{Headers: test.headers}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Retry-After counts down to the next minute, so is fixed in the
+		// fixture once it is checked
+		if retryAfter, ok := response.Headers["Retry-After"]; ok {
+			if seconds, err := strconv.Atoi(retryAfter); err != nil || seconds < 1 || seconds > 61 {
+				t.Errorf("Retry-After = %q, want the seconds to the next minute", retryAfter)
+			}
+			response.Headers["Retry-After"] = "60"
+		}
+		checkResponse(t, test.path, response)
+	}
+}
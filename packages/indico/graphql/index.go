@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+)
+
+// Request is a GraphQL request as POSTed by clients
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+	HTTP          HTTPRequest            `json:"http"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+// Main checks the API key and rate limit of the request before responding
+func Main(in Request) (*Response, error) {
+	return scopedAuthorized(in.HTTP, func(key MongoAPIKey) (*Response, error) {
+		return respond(in, key)
+	})
+}
+
+// respond runs the query with the key in its context, so the resolvers only
+// return the conferences a scoped key may access
+func respond(in Request, key MongoAPIKey) (*Response, error) {
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	database := client.Database("author-title")
+
+	// Errors in the query are returned in the result, as GraphQL clients expect
+	if err := checkQueryLimits(in); err != nil {
+		return graphqlError(err)
+	}
+	result := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  in.Query,
+		VariableValues: in.Variables,
+		OperationName:  in.OperationName,
+		Context:        context.WithValue(context.WithValue(context.Background(), databaseKey, database), apiKeyKey, key),
+	})
+
+	return graphqlResponse(result)
+}
+
+func graphqlError(err error) (*Response, error) {
+	return graphqlResponse(&graphql.Result{Errors: []gqlerrors.FormattedError{{Message: err.Error()}}})
+}
+
+func graphqlResponse(result *graphql.Result) (*Response, error) {
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling result: %s", err.Error())
+	}
+	return &Response{
+		Body: string(jsonBytes),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"strconv"
+)
+
+// Every object type can lead back to the others, so without limits a single
+// query could nest conference.contributions and session.contributions
+// without bound, each level running a find per parent object
+const (
+	maxQueryDepth = 4
+	maxListSize   = 500
+	maxQueryCost  = 5000
+)
+
+// pagedFields are the lists read from MongoDB, which take limit and offset
+var pagedFields = map[string]bool{
+	"conferences":   true,
+	"sessions":      true,
+	"contributions": true,
+}
+
+// readFields are the other fields which run a find for each parent object.
+// The rest are part of the document already read.
+var readFields = map[string]bool{
+	"conference":   true,
+	"contribution": true,
+	"session":      true,
+	"generator":    true,
+}
+
+// pageArgs adds limit and offset to the arguments of a paged list
+func pageArgs(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args["limit"] = &graphql.ArgumentConfig{
+		Type:        graphql.Int,
+		Description: fmt.Sprintf("at most %d, the default", maxListSize),
+	}
+	args["offset"] = &graphql.ArgumentConfig{Type: graphql.Int}
+	return args
+}
+
+// clampLimit keeps a requested list size within 1 and maxListSize
+func clampLimit(limit int) int {
+	if limit < 1 || limit > maxListSize {
+		return maxListSize
+	}
+	return limit
+}
+
+// page is the limit and offset of a paged list
+func page(p graphql.ResolveParams) (int64, int64) {
+	limit := maxListSize
+	if value, ok := p.Args["limit"].(int); ok {
+		limit = clampLimit(value)
+	}
+	var offset int64
+	if value, ok := p.Args["offset"].(int); ok && value > 0 {
+		offset = int64(value)
+	}
+	return int64(limit), offset
+}
+
+// queryLimits walks a query before it is run, returning the deepest nesting
+// of fields with selections and an estimate of the number of objects it
+// reads from MongoDB, where each paged list multiplies the cost of the fields
+// inside it
+type queryLimits struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+func (limits queryLimits) listSize(field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "limit" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if limit, err := strconv.Atoi(value.Value); err == nil {
+				return clampLimit(limit)
+			}
+		case *ast.Variable:
+			switch limit := limits.variables[value.Name.Value].(type) {
+			case float64:
+				return clampLimit(int(limit))
+			case int:
+				return clampLimit(limit)
+			}
+		}
+	}
+	return maxListSize
+}
+
+func (limits queryLimits) walk(selections *ast.SelectionSet, multiplier int, visited map[string]bool) (int, int) {
+	if selections == nil {
+		return 0, 0
+	}
+	depth, cost := 0, 0
+	add := func(childDepth int, childCost int) {
+		if childDepth > depth {
+			depth = childDepth
+		}
+		cost += childCost
+	}
+	for _, selection := range selections.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if selection.SelectionSet == nil {
+				continue
+			}
+			objects := multiplier
+			if pagedFields[selection.Name.Value] {
+				objects *= limits.listSize(selection)
+			}
+			// Past the maximum the cost only needs to stay over it, not overflow
+			if objects > maxQueryCost {
+				objects = maxQueryCost + 1
+			}
+			childDepth, childCost := limits.walk(selection.SelectionSet, objects, visited)
+			if pagedFields[selection.Name.Value] || readFields[selection.Name.Value] {
+				childCost += objects
+			}
+			add(childDepth+1, childCost)
+		case *ast.InlineFragment:
+			add(limits.walk(selection.SelectionSet, multiplier, visited))
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, found := limits.fragments[name]
+			if !found || visited[name] {
+				continue
+			}
+			visited[name] = true
+			add(limits.walk(fragment.SelectionSet, multiplier, visited))
+			delete(visited, name)
+		}
+		// A wide query can overflow the cost long before it is walked
+		if cost > maxQueryCost {
+			return depth, cost
+		}
+	}
+	return depth, cost
+}
+
+// checkQueryLimits rejects a query which is nested too deeply or would read
+// too many objects. Queries which don't parse are left to graphql.Do, which
+// reports the syntax error.
+func checkQueryLimits(in Request) error {
+	document, err := parser.Parse(parser.ParseParams{Source: in.Query})
+	if err != nil {
+		return nil
+	}
+	limits := queryLimits{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: in.Variables,
+	}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			limits.fragments[fragment.Name.Value] = fragment
+		}
+	}
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if in.OperationName != "" && (operation.Name == nil || operation.Name.Value != in.OperationName) {
+			continue
+		}
+		depth, cost := limits.walk(operation.SelectionSet, 1, make(map[string]bool))
+		if depth > maxQueryDepth {
+			return fmt.Errorf("query is nested %d levels deep, at most %d are allowed", depth, maxQueryDepth)
+		}
+		if cost > maxQueryCost {
+			return fmt.Errorf("query could read more than %d objects, use smaller limits on its lists", maxQueryCost)
+		}
+	}
+	return nil
+}
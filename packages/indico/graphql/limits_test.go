@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckQueryLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		operation string
+		rejected  string
+	}{
+		{
+			name:  "one conference",
+			query: `{ conference(id: 41) { name contributions { code title generator { authors { name affiliations } } } } }`,
+		},
+		{
+			name:  "readme example",
+			query: `{ conference(id: 41) { name sessions(limit: 50) { code contributions(limit: 50) { code title } } contributions(type: "Poster") { code persons { firstName affiliationLink { name } } generator { authors { firstName } organisations { name } } } } }`,
+		},
+		{
+			name:     "very deep",
+			query:    `{ conferences { sessions { contributions { session { contributions { session { contributions { session { contributions { code } } } } } } } } } }`,
+			rejected: "nested 9 levels",
+		},
+		{
+			name:  "contribution",
+			query: `{ contribution(conference: 41, code: "TUPA071") { title session { title } } }`,
+		},
+		{
+			name:     "too deep",
+			query:    `{ conference(id: 41) { contributions(limit: 1) { session { contributions(limit: 1) { conference { name } } } } } }`,
+			rejected: "nested 5 levels",
+		},
+		{
+			name:     "too deep through fragments",
+			query:    `{ conference(id: 41) { ...c } } fragment c on Conference { contributions(limit: 1) { ... on Contribution { session { contributions(limit: 1) { conference { name } } } } } }`,
+			rejected: "nested 5 levels",
+		},
+		{
+			name:     "nested lists",
+			query:    `{ conference(id: 41) { contributions { session { contributions { code } } } } }`,
+			rejected: "more than 5000 objects",
+		},
+		{
+			name:  "nested lists with limits",
+			query: `{ conference(id: 41) { contributions(limit: 10) { session { contributions(limit: 10) { code } } } } }`,
+		},
+		{
+			name:      "limit from a variable",
+			query:     `query q($limit: Int) { conferences(limit: $limit) { contributions(limit: $limit) { code } } }`,
+			variables: map[string]interface{}{"limit": float64(50)},
+		},
+		{
+			name:     "limit above the maximum",
+			query:    `{ conferences(limit: 100000) { sessions(limit: 100000) { title } } }`,
+			rejected: "more than 5000 objects",
+		},
+		{
+			name:      "only the named operation",
+			query:     `query small { conference(id: 41) { name } } query large { conferences { sessions { contributions { code } } } }`,
+			operation: "small",
+		},
+		{
+			name:  "syntax errors are left to graphql",
+			query: `{ conference(id: `,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkQueryLimits(Request{Query: test.query, Variables: test.variables, OperationName: test.operation})
+			if test.rejected == "" {
+				if err != nil {
+					t.Fatalf("rejected: %s", err.Error())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.rejected) {
+				t.Fatalf("got %v, want an error containing %q", err, test.rejected)
+			}
+		})
+	}
+}
+
+func TestClampLimit(t *testing.T) {
+	tests := map[int]int{
+		-1:     maxListSize,
+		0:      maxListSize,
+		1:      1,
+		50:     50,
+		500:    500,
+		501:    maxListSize,
+		100000: maxListSize,
+	}
+	for limit, want := range tests {
+		if got := clampLimit(limit); got != want {
+			t.Errorf("clampLimit(%d) = %d, want %d", limit, got, want)
+		}
+	}
+}
+
+func TestGraphqlErrorResponse(t *testing.T) {
+	response, err := graphqlError(checkQueryLimits(Request{
+		Query: `{ conferences { contributions { session { contributions { conference { name } } } } } }`,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(response.Body, `"errors":[{"message":"query is nested`) {
+		t.Fatalf("unexpected body: %s", response.Body)
+	}
+}
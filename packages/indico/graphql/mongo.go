@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// The json tags are the GraphQL field names
+
+type MongoConference struct {
+	ID                int               `bson:"_id" json:"id"`
+	Name              string            `bson:"name" json:"name"`
+	Start             time.Time         `bson:"start" json:"start"`
+	End               time.Time         `bson:"end" json:"end"`
+	Location          string            `bson:"location" json:"location"`
+	Category          string            `bson:"category" json:"category"`
+	Acronym           string            `bson:"acronym" json:"acronym"`
+	AffiliationFormat string            `bson:"affiliationFormat" json:"-"`
+	CountryNames      map[string]string `bson:"countryNames" json:"-"`
+}
+
+type MongoSession struct {
+	ID           string    `bson:"_id" json:"id"`
+	ConferenceId int       `bson:"conferenceId" json:"conferenceId"`
+	BlockID      string    `bson:"blockId" json:"blockId"`
+	Code         string    `bson:"code" json:"code"`
+	Title        string    `bson:"title" json:"title"`
+	Room         string    `bson:"room" json:"room"`
+	Start        time.Time `bson:"start" json:"start"`
+	End          time.Time `bson:"end" json:"end"`
+}
+
+type MongoContribution struct {
+	ID               int                   `bson:"_id" json:"id"`
+	ConferenceId     int                   `bson:"conferenceId" json:"conferenceId"`
+	Code             string                `bson:"code" json:"code"`
+	Title            string                `bson:"title" json:"title"`
+	Description      string                `bson:"description" json:"description"`
+	Presenters       *[]MongoPerson        `bson:"presenters,omitempty" json:"presenters"`
+	Authors          *[]MongoPerson        `bson:"authors,omitempty" json:"authors"`
+	Persons          []MongoDetailedPerson `bson:"persons" json:"persons"`
+	IsDuplicate      bool                  `bson:"is_duplicate" json:"isDuplicate"`
+	ContributionType string                `bson:"contribution_type" json:"type"`
+	FundingAgency    string                `bson:"funding_agency" json:"fundingAgency"`
+	Footnotes        string                `bson:"footnotes" json:"footnotes"`
+	Schedule         *MongoSchedule        `bson:"schedule,omitempty" json:"schedule"`
+}
+
+type MongoSchedule struct {
+	SessionID string    `bson:"sessionId" json:"sessionId"`
+	Room      string    `bson:"room" json:"room"`
+	Start     time.Time `bson:"start" json:"start"`
+	End       time.Time `bson:"end" json:"end"`
+}
+
+type MongoPerson struct {
+	FirstName    string `bson:"firstName" json:"firstName"`
+	FamilyName   string `bson:"familyName" json:"familyName"`
+	Affiliation  string `bson:"affiliation" json:"affiliation"`
+	DisplayOrder int    `bson:"displayOrder" json:"displayOrder"`
+}
+
+type MongoAffiliationLink struct {
+	ID          int    `bson:"id" json:"id"`
+	Name        string `bson:"name" json:"name"`
+	City        string `bson:"city" json:"city"`
+	CountryName string `bson:"country_name" json:"countryName"`
+	CountryCode string `bson:"country_code" json:"countryCode"`
+	Postcode    string `bson:"postcode" json:"postcode"`
+}
+
+type MongoDetailedPerson struct {
+	ID              int                  `bson:"person_id" json:"personId"`
+	FirstName       string               `bson:"first_name" json:"firstName"`
+	LastName        string               `bson:"last_name" json:"lastName"`
+	IsSpeaker       bool                 `bson:"is_speaker" json:"isSpeaker"`
+	AuthorType      string               `bson:"author_type" json:"authorType"`
+	Affiliation     string               `bson:"affiliation" json:"affiliation"`
+	AffiliationLink MongoAffiliationLink `bson:"affiliation_link" json:"affiliationLink"`
+}
+
+type MongoAffiliation struct {
+	ID            int      `bson:"_id"`
+	Name          string   `bson:"name"`
+	City          string   `bson:"city"`
+	CountryName   string   `bson:"countryName"`
+	CountryCode   string   `bson:"countryCode"`
+	Postcode      string   `bson:"postcode"`
+	CanonicalName string   `bson:"canonicalName"`
+	Aliases       []string `bson:"aliases"`
+	ROR           string   `bson:"ror"`
+}
+
+type contextKey string
+
+const (
+	databaseKey contextKey = "database"
+	apiKeyKey   contextKey = "apiKey"
+)
+
+func databaseFrom(ctx context.Context) *mongo.Database {
+	return ctx.Value(databaseKey).(*mongo.Database)
+}
+
+func apiKeyFrom(ctx context.Context) MongoAPIKey {
+	key, _ := ctx.Value(apiKeyKey).(MongoAPIKey)
+	return key
+}
+
+func findAll(ctx context.Context, collection string, filter bson.D, sort bson.D, results interface{}) error {
+	return find(ctx, collection, filter, options.Find().SetSort(sort), results)
+}
+
+// findPage finds one page of a paged list, as set by its limit and offset
+func findPage(p graphql.ResolveParams, collection string, filter bson.D, sort bson.D, results interface{}) error {
+	limit, offset := page(p)
+	return find(p.Context, collection, filter, options.Find().SetSort(sort).SetLimit(limit).SetSkip(offset), results)
+}
+
+func find(ctx context.Context, collection string, filter bson.D, findOptions *options.FindOptions, results interface{}) error {
+	cursor, findError := databaseFrom(ctx).Collection(collection).Find(ctx, filter, findOptions)
+	if findError != nil {
+		return fmt.Errorf("error finding %s: %s", collection, findError.Error())
+	}
+	if err := cursor.All(ctx, results); err != nil {
+		return fmt.Errorf("error decoding %s: %s", collection, err.Error())
+	}
+	return nil
+}
+
+// findOne returns false when there is no such document, which GraphQL
+// returns as null
+func findOne(ctx context.Context, collection string, filter bson.D, result interface{}) (bool, error) {
+	err := databaseFrom(ctx).Collection(collection).FindOne(ctx, filter).Decode(result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error finding %s: %s", collection, err.Error())
+	}
+	return true, nil
+}
+
+// findAffiliations looks up the affiliations linked by the persons of a
+// contribution in the affiliations registry, keyed by their indico id, like
+// find
+func findAffiliations(ctx context.Context, allPersons []MongoDetailedPerson) (map[int]MongoAffiliation, error) {
+	affiliations := linkedAffiliations(allPersons)
+	if len(affiliations) == 0 {
+		return affiliations, nil
+	}
+	var registered []MongoAffiliation
+	if err := findAll(ctx, "affiliations", registeredAffiliationsFilter(affiliations), bson.D{}, &registered); err != nil {
+		return nil, err
+	}
+	addRegisteredAffiliations(affiliations, registered)
+	return affiliations, nil
+}
+
+// findIdentities returns the resolved identities of the persons of a
+// contribution, keyed by their indico person id
+func findIdentities(ctx context.Context, contribution MongoContribution) (map[int]MongoAuthorIdentity, error) {
+	var entries []MongoAuthorIdentity
+	if err := findAll(ctx, "author_index", identitiesFilter(contribution), bson.D{}, &entries); err != nil {
+		return nil, err
+	}
+	return identitiesByPerson(entries), nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+// TestResponseFixture keeps testdata/response.json the same as the response
+// of graphql for a contribution with its generator block, and
+// testdata/errors.json for a query which fails. Run go test -update after
+// changing the output.
+func TestResponseFixture(t *testing.T) {
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		switch collection {
+		case "contributions":
+			return []interface{}{MongoContribution{
+				ID:           7,
+				ConferenceId: 41,
+				Code:         "TUPA071",
+				Title:        "Beam loss monitors for the storage ring",
+				Presenters:   &[]MongoPerson{{FirstName: "Ada", FamilyName: "Lovelace", Affiliation: "ANSTO", DisplayOrder: 1}},
+				Authors:      &[]MongoPerson{{FirstName: "Jean", FamilyName: "Dupont", Affiliation: "Unlisted Laboratory", DisplayOrder: 2}},
+				Persons: []MongoDetailedPerson{
+					{ID: 11, FirstName: "Ada", LastName: "Lovelace", AffiliationLink: MongoAffiliationLink{ID: 1, Name: "ANSTO"}},
+				},
+			}}
+		case "conferences":
+			return []interface{}{MongoConference{ID: 41, Name: "IPAC'24", Acronym: "IPAC'24"}}
+		case "affiliations":
+			return []interface{}{MongoAffiliation{
+				ID:            1,
+				Name:          "ANSTO",
+				City:          "Clayton",
+				CountryName:   "Australia",
+				CountryCode:   "AU",
+				CanonicalName: "Australian Nuclear Science and Technology Organisation",
+				ROR:           "https://ror.org/05j7fep28",
+			}}
+		case "author_index":
+			return []interface{}{MongoAuthorIdentity{PersonID: 11, AuthorID: "a1b2", ORCID: "0000-0002-1825-0097"}}
+		}
+		return nil
+	})
+	tests := []struct {
+		path  string
+		query string
+	}{
+		{
+			"testdata/response.json",
+			`{ contribution(conference: 41, code: "TUPA071") { code title conference { acronym } generator {
+				authors { position firstName lastName affiliations authorId orcid }
+				organisations { number name location ror }
+			} } }`,
+		},
+		{"testdata/errors.json", "{ __typename unknown }"},
+	}
+	for _, test := range tests {
+		response, err := Main(Request{Query: test.query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkResponse(t, test.path, response)
+	}
+}
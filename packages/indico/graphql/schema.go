@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"go.mongodb.org/mongo-driver/bson"
+	"sort"
+	"strconv"
+)
+
+var affiliationLinkType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "AffiliationLink",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.Int},
+		"name":        &graphql.Field{Type: graphql.String},
+		"city":        &graphql.Field{Type: graphql.String},
+		"countryName": &graphql.Field{Type: graphql.String},
+		"countryCode": &graphql.Field{Type: graphql.String},
+		"postcode":    &graphql.Field{Type: graphql.String},
+	},
+})
+
+// personType is a presenter or author from the timetable
+var personType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Person",
+	Fields: graphql.Fields{
+		"firstName":    &graphql.Field{Type: graphql.String},
+		"familyName":   &graphql.Field{Type: graphql.String},
+		"affiliation":  &graphql.Field{Type: graphql.String},
+		"displayOrder": &graphql.Field{Type: graphql.Int},
+	},
+})
+
+// detailedPersonType is a person from the contribution details. Emails are
+// deliberately not part of the schema.
+var detailedPersonType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "DetailedPerson",
+	Fields: graphql.Fields{
+		"personId":        &graphql.Field{Type: graphql.Int},
+		"firstName":       &graphql.Field{Type: graphql.String},
+		"lastName":        &graphql.Field{Type: graphql.String},
+		"isSpeaker":       &graphql.Field{Type: graphql.Boolean},
+		"authorType":      &graphql.Field{Type: graphql.String},
+		"affiliation":     &graphql.Field{Type: graphql.String},
+		"affiliationLink": &graphql.Field{Type: affiliationLinkType},
+	},
+})
+
+var scheduleType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Schedule",
+	Fields: graphql.Fields{
+		"sessionId": &graphql.Field{Type: graphql.String},
+		"room":      &graphql.Field{Type: graphql.String},
+		"start":     &graphql.Field{Type: graphql.DateTime},
+		"end":       &graphql.Field{Type: graphql.DateTime},
+	},
+})
+
+var generatorType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Generator",
+	Fields: graphql.Fields{
+		"title": &graphql.Field{Type: graphql.String},
+		"authors": &graphql.Field{Type: graphql.NewList(graphql.NewObject(graphql.ObjectConfig{
+			Name: "GeneratorAuthor",
+			Fields: graphql.Fields{
+				"position":     &graphql.Field{Type: graphql.Int, Description: "the display order"},
+				"firstName":    &graphql.Field{Type: graphql.String},
+				"lastName":     &graphql.Field{Type: graphql.String},
+				"affiliations": &graphql.Field{Type: graphql.NewList(graphql.Int)},
+				"authorId":     &graphql.Field{Type: graphql.String},
+				"orcid":        &graphql.Field{Type: graphql.String},
+			},
+		}))},
+		"organisations": &graphql.Field{Type: graphql.NewList(graphql.NewObject(graphql.ObjectConfig{
+			Name: "GeneratorOrganisation",
+			Fields: graphql.Fields{
+				"number":   &graphql.Field{Type: graphql.Int},
+				"name":     &graphql.Field{Type: graphql.String},
+				"location": &graphql.Field{Type: graphql.String},
+				"zipcode":  &graphql.Field{Type: graphql.String},
+				"ror":      &graphql.Field{Type: graphql.String},
+			},
+		}))},
+		"fundingAgency": &graphql.Field{Type: graphql.String},
+		"footnotes":     &graphql.Field{Type: graphql.String},
+	},
+})
+
+// Generator is the payload of find with its authors and organisations as
+// lists ordered by their keys, which GraphQL has no maps for
+
+type PositionedAuthor struct {
+	Position     int    `json:"position"`
+	FirstName    string `json:"firstName"`
+	LastName     string `json:"lastName"`
+	Affiliations []int  `json:"affiliations"`
+	AuthorID     string `json:"authorId"`
+	ORCID        string `json:"orcid"`
+}
+
+type NumberedOrganisation struct {
+	Number   int    `json:"number"`
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	Zipcode  string `json:"zipcode"`
+	ROR      string `json:"ror"`
+}
+
+type Generator struct {
+	Title         string                 `json:"title"`
+	Authors       []PositionedAuthor     `json:"authors"`
+	Organisations []NumberedOrganisation `json:"organisations"`
+	FundingAgency string                 `json:"fundingAgency"`
+	Footnotes     string                 `json:"footnotes"`
+}
+
+func generatorLists(payload GeneratorPayload) Generator {
+	output := Generator{
+		Title:         payload.Title,
+		Authors:       make([]PositionedAuthor, 0, len(payload.Authors)),
+		Organisations: make([]NumberedOrganisation, 0, len(payload.Organisations)),
+		FundingAgency: payload.FundingAgency,
+		Footnotes:     payload.Footnotes,
+	}
+	for position, author := range payload.Authors {
+		output.Authors = append(output.Authors, PositionedAuthor{
+			Position:     position,
+			FirstName:    author.FirstName,
+			LastName:     author.LastName,
+			Affiliations: author.Affiliations,
+			AuthorID:     author.AuthorID,
+			ORCID:        author.ORCID,
+		})
+	}
+	sort.Slice(output.Authors, func(i, j int) bool {
+		return output.Authors[i].Position < output.Authors[j].Position
+	})
+	for number, organisation := range payload.Organisations {
+		output.Organisations = append(output.Organisations, NumberedOrganisation{
+			Number:   number,
+			Name:     organisation.Name,
+			Location: organisation.Location,
+			Zipcode:  organisation.Zipcode,
+			ROR:      organisation.ROR,
+		})
+	}
+	sort.Slice(output.Organisations, func(i, j int) bool {
+		return output.Organisations[i].Number < output.Organisations[j].Number
+	})
+	return output
+}
+
+var conferenceType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Conference",
+	Fields: graphql.Fields{
+		"id":       &graphql.Field{Type: graphql.Int},
+		"name":     &graphql.Field{Type: graphql.String},
+		"start":    &graphql.Field{Type: graphql.DateTime},
+		"end":      &graphql.Field{Type: graphql.DateTime},
+		"location": &graphql.Field{Type: graphql.String},
+		"category": &graphql.Field{Type: graphql.String},
+		"acronym":  &graphql.Field{Type: graphql.String},
+	},
+})
+
+var sessionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Session",
+	Fields: graphql.Fields{
+		"id":      &graphql.Field{Type: graphql.String},
+		"blockId": &graphql.Field{Type: graphql.String},
+		"code":    &graphql.Field{Type: graphql.String},
+		"title":   &graphql.Field{Type: graphql.String},
+		"room":    &graphql.Field{Type: graphql.String},
+		"start":   &graphql.Field{Type: graphql.DateTime},
+		"end":     &graphql.Field{Type: graphql.DateTime},
+	},
+})
+
+var contributionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Contribution",
+	Fields: graphql.Fields{
+		"id":            &graphql.Field{Type: graphql.Int},
+		"code":          &graphql.Field{Type: graphql.String},
+		"title":         &graphql.Field{Type: graphql.String},
+		"description":   &graphql.Field{Type: graphql.String},
+		"type":          &graphql.Field{Type: graphql.String},
+		"isDuplicate":   &graphql.Field{Type: graphql.Boolean},
+		"fundingAgency": &graphql.Field{Type: graphql.String},
+		"footnotes":     &graphql.Field{Type: graphql.String},
+		"presenters": &graphql.Field{
+			Type: graphql.NewList(personType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return timetablePersons(p.Source.(MongoContribution).Presenters), nil
+			},
+		},
+		"authors": &graphql.Field{
+			Type: graphql.NewList(personType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return timetablePersons(p.Source.(MongoContribution).Authors), nil
+			},
+		},
+		"persons": &graphql.Field{Type: graphql.NewList(detailedPersonType)},
+		"schedule": &graphql.Field{
+			Type: scheduleType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if schedule := p.Source.(MongoContribution).Schedule; schedule != nil {
+					return *schedule, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+func timetablePersons(persons *[]MongoPerson) []MongoPerson {
+	if persons == nil {
+		return []MongoPerson{}
+	}
+	return *persons
+}
+
+// contributionsField lists the contributions of a conference, optionally
+// filtered by code, type or session
+var contributionsField = &graphql.Field{
+	Type: graphql.NewList(contributionType),
+	Args: pageArgs(graphql.FieldConfigArgument{
+		"code":    &graphql.ArgumentConfig{Type: graphql.String},
+		"type":    &graphql.ArgumentConfig{Type: graphql.String},
+		"session": &graphql.ArgumentConfig{Type: graphql.String, Description: "session code"},
+	}),
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		filter := bson.D{{"conferenceId", p.Source.(MongoConference).ID}}
+		if code, ok := p.Args["code"].(string); ok {
+			filter = append(filter, bson.E{"code", code})
+		}
+		if contributionType, ok := p.Args["type"].(string); ok {
+			filter = append(filter, bson.E{"contribution_type", contributionType})
+		}
+		if session, ok := p.Args["session"].(string); ok {
+			filter = append(filter, bson.E{"schedule.sessionCode", session})
+		}
+		var contributions = make([]MongoContribution, 0)
+		err := findPage(p, "contributions", filter, bson.D{{"code", 1}}, &contributions)
+		return contributions, err
+	},
+}
+
+// checkConference refuses the conference arguments a scoped API key can't
+// access, which fails only the field asked for them
+func checkConference(ctx context.Context, id int) error {
+	if !allowedConference(apiKeyFrom(ctx), strconv.Itoa(id)) {
+		return fmt.Errorf("this API key can't access conference %d", id)
+	}
+	return nil
+}
+
+func conferenceById(p graphql.ResolveParams, id int) (interface{}, error) {
+	if err := checkConference(p.Context, id); err != nil {
+		return nil, err
+	}
+	var conference MongoConference
+	found, err := findOne(p.Context, "conferences", bson.D{{"_id", id}}, &conference)
+	if !found || err != nil {
+		return nil, err
+	}
+	return conference, nil
+}
+
+var queryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"conferences": &graphql.Field{
+			Type: graphql.NewList(conferenceType),
+			Args: pageArgs(graphql.FieldConfigArgument{}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				var conferences = make([]MongoConference, 0)
+				filter := scopeFilter(apiKeyFrom(p.Context), "_id")
+				err := findPage(p, "conferences", filter, bson.D{{"start", -1}}, &conferences)
+				return conferences, err
+			},
+		},
+		"conference": &graphql.Field{
+			Type: conferenceType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return conferenceById(p, p.Args["id"].(int))
+			},
+		},
+		"contribution": &graphql.Field{
+			Type: contributionType,
+			Args: graphql.FieldConfigArgument{
+				"conference": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"code":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if err := checkConference(p.Context, p.Args["conference"].(int)); err != nil {
+					return nil, err
+				}
+				var contribution MongoContribution
+				filter := bson.D{{"conferenceId", p.Args["conference"].(int)}, {"code", p.Args["code"].(string)}}
+				found, err := findOne(p.Context, "contributions", filter, &contribution)
+				if !found || err != nil {
+					return nil, err
+				}
+				return contribution, nil
+			},
+		},
+	},
+})
+
+var schema graphql.Schema
+
+// init completes the types which refer to each other, which can't be done
+// in their declarations, then builds the schema
+func init() {
+	conferenceType.AddFieldConfig("sessions", &graphql.Field{
+		Type: graphql.NewList(sessionType),
+		Args: pageArgs(graphql.FieldConfigArgument{}),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			var sessions = make([]MongoSession, 0)
+			err := findPage(p, "sessions", bson.D{{"conferenceId", p.Source.(MongoConference).ID}}, bson.D{{"start", 1}}, &sessions)
+			return sessions, err
+		},
+	})
+	conferenceType.AddFieldConfig("contributions", contributionsField)
+
+	sessionType.AddFieldConfig("contributions", &graphql.Field{
+		Type: graphql.NewList(contributionType),
+		Args: pageArgs(graphql.FieldConfigArgument{}),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			session := p.Source.(MongoSession)
+			filter := bson.D{{"conferenceId", session.ConferenceId}, {"schedule.sessionId", session.ID}}
+			var contributions = make([]MongoContribution, 0)
+			err := findPage(p, "contributions", filter, bson.D{{"schedule.start", 1}, {"code", 1}}, &contributions)
+			return contributions, err
+		},
+	})
+
+	contributionType.AddFieldConfig("conference", &graphql.Field{
+		Type: conferenceType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return conferenceById(p, p.Source.(MongoContribution).ConferenceId)
+		},
+	})
+	contributionType.AddFieldConfig("session", &graphql.Field{
+		Type: sessionType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			contribution := p.Source.(MongoContribution)
+			if contribution.Schedule == nil || contribution.Schedule.SessionID == "" {
+				return nil, nil
+			}
+			var session MongoSession
+			found, err := findOne(p.Context, "sessions", bson.D{{"_id", contribution.Schedule.SessionID}}, &session)
+			if !found || err != nil {
+				return nil, err
+			}
+			return session, nil
+		},
+	})
+	contributionType.AddFieldConfig("generator", &graphql.Field{
+		Type:        generatorType,
+		Description: "the title and author block as returned by find",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			contribution := p.Source.(MongoContribution)
+			conference := MongoConference{ID: contribution.ConferenceId}
+			if _, err := findOne(p.Context, "conferences", bson.D{{"_id", contribution.ConferenceId}}, &conference); err != nil {
+				return nil, err
+			}
+			affiliations, err := findAffiliations(p.Context, contribution.Persons)
+			if err != nil {
+				return nil, err
+			}
+			identities, err := findIdentities(p.Context, contribution)
+			if err != nil {
+				return nil, err
+			}
+			return generatorLists(mongoToGeneratorPayload(contribution, conference, affiliations, identities)), nil
+		},
+	})
+
+	var err error
+	schema, err = graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+)
+
+func TestTimetablePersons(t *testing.T) {
+	if got := timetablePersons(nil); got == nil || len(got) != 0 {
+		t.Errorf("timetablePersons(nil) = %#v, want an empty list", got)
+	}
+	persons := []MongoPerson{{FirstName: "Ada", FamilyName: "Lovelace"}}
+	if got := timetablePersons(&persons); !reflect.DeepEqual(got, persons) {
+		t.Errorf("timetablePersons = %+v, want %+v", got, persons)
+	}
+}
+
+func TestGeneratorLists(t *testing.T) {
+	payload := GeneratorPayload{
+		Title: "Beam loss monitors",
+		Authors: map[int]GeneratorAuthor{
+			3: {FirstName: "Jean", LastName: "Dupont", Affiliations: []int{1}},
+			1: {FirstName: "Li", LastName: "Wei", Affiliations: []int{0}, AuthorID: "a1b2", ORCID: "0000-0002-1825-0097"},
+		},
+		Organisations: map[int]GeneratorOrganisation{
+			1: {Name: "Unlisted Laboratory"},
+			0: {Name: "ANSTO", Location: "Clayton, Australia", ROR: "https://ror.org/05j7fep28"},
+		},
+		FundingAgency: "DOE",
+	}
+	want := Generator{
+		Title: "Beam loss monitors",
+		Authors: []PositionedAuthor{
+			{Position: 1, FirstName: "Li", LastName: "Wei", Affiliations: []int{0}, AuthorID: "a1b2", ORCID: "0000-0002-1825-0097"},
+			{Position: 3, FirstName: "Jean", LastName: "Dupont", Affiliations: []int{1}},
+		},
+		Organisations: []NumberedOrganisation{
+			{Number: 0, Name: "ANSTO", Location: "Clayton, Australia", ROR: "https://ror.org/05j7fep28"},
+			{Number: 1, Name: "Unlisted Laboratory"},
+		},
+		FundingAgency: "DOE",
+	}
+	if got := generatorLists(payload); !reflect.DeepEqual(got, want) {
+		t.Errorf("generatorLists =\n%+v\nwant\n%+v", got, want)
+	}
+
+	empty := generatorLists(GeneratorPayload{Title: "Untitled"})
+	if empty.Authors == nil || empty.Organisations == nil {
+		t.Errorf("generatorLists of a payload without authors = %+v, want empty lists", empty)
+	}
+}
+
+func TestScopedKey(t *testing.T) {
+	var filter bson.Raw
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		switch collection {
+		case "api_keys":
+			return []interface{}{MongoAPIKey{ID: hashAPIKey("imw_scoped"), Conferences: []int{58}}}
+		case "conferences":
+			filter = body.Lookup("filter").Document()
+			return []interface{}{MongoConference{ID: 58, Acronym: "IPAC'25"}}
+		}
+		return nil
+	})
+	response, err := Main(Request{
+		Query: `{ conferences { id } conference(id: 41) { id } contribution(conference: 41, code: "TUPA071") { code } }`,
+		HTTP:  HTTPRequest{Headers: map[string]string{"X-API-Key": "imw_scoped"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != 0 {
+		t.Fatalf("a scoped key gets %d, want its conferences", response.StatusCode)
+	}
+	var got bson.D
+	if err := bson.Unmarshal(filter, &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (bson.D{{"_id", bson.D{{"$in", bson.A{int32(58)}}}}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("conferences filter = %v, want %v", got, want)
+	}
+	var result struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(response.Body), &result); err != nil {
+		t.Fatal(err)
+	}
+	if conferences, ok := result.Data["conferences"].([]interface{}); !ok || len(conferences) != 1 {
+		t.Errorf("conferences = %v, want the conference of the key", result.Data["conferences"])
+	}
+	if result.Data["conference"] != nil || result.Data["contribution"] != nil {
+		t.Errorf("data = %v, want no conference 41", result.Data)
+	}
+	want := "this API key can't access conference 41"
+	if len(result.Errors) != 2 || result.Errors[0].Message != want || result.Errors[1].Message != want {
+		t.Errorf("errors = %+v, want %q for both fields", result.Errors, want)
+	}
+}
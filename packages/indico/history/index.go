@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"strconv"
+	"time"
+)
+
+type MongoFieldChange struct {
+	Field string      `bson:"field" json:"field"`
+	Old   interface{} `bson:"old" json:"old"`
+	New   interface{} `bson:"new" json:"new"`
+}
+
+type MongoContributionHistory struct {
+	ContributionID int                `bson:"contributionId" json:"contribution_id"`
+	ConferenceId   int                `bson:"conferenceId" json:"conference_id"`
+	Code           string             `bson:"code" json:"code"`
+	Job            string             `bson:"job" json:"job"`
+	SyncedAt       time.Time          `bson:"syncedAt" json:"synced_at"`
+	Changes        []MongoFieldChange `bson:"changes" json:"changes"`
+}
+
+type Request struct {
+	Conference string      `json:"conference"`
+	Code       string      `json:"code"`
+	HTTP       HTTPRequest `json:"http"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+// withoutEmails removes every email from a decoded change. History recorded
+// before emails were left out of it may still hold them.
+func withoutEmails(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.M:
+		delete(v, "email")
+		for key, field := range v {
+			v[key] = withoutEmails(field)
+		}
+	case bson.A:
+		for i, item := range v {
+			v[i] = withoutEmails(item)
+		}
+	}
+	return value
+}
+
+// historyResponse is the json of the history with any emails removed
+func historyResponse(history []MongoContributionHistory) (*Response, error) {
+	for i := range history {
+		for j := range history[i].Changes {
+			history[i].Changes[j].Old = withoutEmails(history[i].Changes[j].Old)
+			history[i].Changes[j].New = withoutEmails(history[i].Changes[j].New)
+		}
+	}
+	jsonBytes, err := json.Marshal(history)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling documents: %s", err.Error())
+	}
+	return &Response{
+		Body: string(jsonBytes),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
+
+// Main checks the API key and rate limit of the request before responding
+func Main(in Request) (*Response, error) {
+	return authorized(in.HTTP, in.Conference, func() (*Response, error) {
+		return respond(in)
+	})
+}
+
+func respond(in Request) (*Response, error) {
+	conferenceId, err := strconv.Atoi(in.Conference)
+	if err != nil {
+		return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+	}
+
+	// Changes hold arbitrary documents, decode them as maps so they marshal
+	// to readable json
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH")).SetBSONOptions(&options.BSONOptions{
+		DefaultDocumentM: true,
+	})
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	collection := client.Database("author-title").Collection("contribution_history")
+
+	findOptions := options.Find().SetSort(bson.D{{"syncedAt", -1}}).SetProjection(bson.D{
+		{"changes.old.email", 0},
+		{"changes.new.email", 0},
+	})
+	cursor, findError := collection.Find(context.Background(), bson.D{
+		{"conferenceId", conferenceId},
+		{"code", in.Code},
+	}, findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding history: %s", findError.Error())
+	}
+	defer func(cursor *mongo.Cursor, ctx context.Context) {
+		_ = cursor.Close(ctx)
+	}(cursor, context.Background())
+
+	var history = make([]MongoContributionHistory, 0)
+	if err := cursor.All(context.Background(), &history); err != nil {
+		return nil, fmt.Errorf("error decoding documents: %s", err.Error())
+	}
+
+	return historyResponse(history)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeHistory decodes a stored history document the way respond does,
+// with nested documents as maps
+func decodeHistory(t *testing.T, document interface{}) MongoContributionHistory {
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder.DefaultDocumentM()
+	var history MongoContributionHistory
+	if err := decoder.Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	return history
+}
+
+func TestHistoryResponseWithoutEmails(t *testing.T) {
+	person := func(name string, email string) bson.D {
+		return bson.D{{"first_name", name}, {"last_name", "Smith"}, {"email", email}}
+	}
+	tests := []struct {
+		name    string
+		changes bson.A
+	}{
+		{
+			name: "persons",
+			changes: bson.A{bson.D{
+				{"field", "persons"},
+				{"old", bson.A{person("Jane", "jane@example.org")}},
+				{"new", bson.A{person("Janet", "janet@example.org"), person("John", "john@example.org")}},
+			}},
+		},
+		{
+			name: "presenters and authors",
+			changes: bson.A{
+				bson.D{{"field", "presenters"}, {"old", nil}, {"new", bson.A{person("Jane", "jane@example.org")}}},
+				bson.D{{"field", "authors"}, {"old", bson.A{}}, {"new", bson.A{person("John", "john@example.org")}}},
+			},
+		},
+		{
+			name: "hashed emails",
+			changes: bson.A{bson.D{
+				{"field", "persons"},
+				{"old", bson.A{}},
+				{"new", bson.A{person("Jane", "hmac:5b26064bbe849dabc6e30cd26694c2cf44dc41a9171fbdae13c722e55f9ff041")}},
+			}},
+		},
+		{
+			name:    "title",
+			changes: bson.A{bson.D{{"field", "title"}, {"old", "Old title"}, {"new", "New title"}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			history := decodeHistory(t, bson.D{
+				{"contributionId", 1},
+				{"conferenceId", 41},
+				{"code", "TUPA071"},
+				{"job", "contributions"},
+				{"syncedAt", time.Date(2024, 5, 19, 0, 0, 0, 0, time.UTC)},
+				{"changes", test.changes},
+			})
+			response, err := historyResponse([]MongoContributionHistory{history})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, leaked := range []string{"email", "@example.org", "hmac:"} {
+				if strings.Contains(response.Body, leaked) {
+					t.Errorf("response contains %q: %s", leaked, response.Body)
+				}
+			}
+			if !strings.Contains(response.Body, `"code":"TUPA071"`) {
+				t.Errorf("response is missing the history: %s", response.Body)
+			}
+		})
+	}
+}
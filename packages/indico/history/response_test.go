@@ -0,0 +1,33 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+// TestResponseFixture keeps testdata/response.json the same as the response
+// of history. Run go test -update after changing the output.
+func TestResponseFixture(t *testing.T) {
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		if collection != "contribution_history" {
+			return nil
+		}
+		return []interface{}{bson.D{
+			{"contributionId", 7},
+			{"conferenceId", 41},
+			{"code", "TUPA071"},
+			{"job", "contributions"},
+			{"syncedAt", time.Date(2024, 5, 19, 2, 0, 0, 0, time.UTC)},
+			{"changes", bson.A{
+				bson.D{{"field", "title"}, {"old", "Beam loss monitors"}, {"new", "Beam loss monitors for the storage ring"}},
+				bson.D{{"field", "persons"}, {"old", bson.A{}}, {"new", bson.A{bson.D{{"first_name", "Ada"}, {"last_name", "Lovelace"}, {"email", "ada@example.org"}}}}},
+			}},
+		}}
+	})
+	response, err := Main(Request{Conference: "41", Code: "TUPA071"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponse(t, "testdata/response.json", response)
+}
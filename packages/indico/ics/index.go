@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type MongoConference struct {
+	ID       int    `bson:"_id"`
+	Name     string `bson:"name"`
+	Location string `bson:"location"`
+}
+
+type MongoContribution struct {
+	ID          int            `bson:"_id"`
+	Code        string         `bson:"code"`
+	Title       string         `bson:"title"`
+	Description string         `bson:"description"`
+	Presenters  *[]MongoPerson `bson:"presenters,omitempty"`
+	Schedule    *MongoSchedule `bson:"schedule,omitempty"`
+}
+
+type MongoPerson struct {
+	FirstName  string `bson:"firstName"`
+	FamilyName string `bson:"familyName"`
+}
+
+type MongoSchedule struct {
+	SessionCode  string    `bson:"sessionCode"`
+	SessionTitle string    `bson:"sessionTitle"`
+	SessionStart time.Time `bson:"sessionStart"`
+	SessionEnd   time.Time `bson:"sessionEnd"`
+	Room         string    `bson:"room"`
+	Start        time.Time `bson:"start"`
+	End          time.Time `bson:"end"`
+}
+
+type Request struct {
+	Conference string      `json:"conference"`
+	Session    string      `json:"session"`
+	Presenter  string      `json:"presenter"`
+	Per        string      `json:"per"`
+	HTTP       HTTPRequest `json:"http"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+}
+
+var icsEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	";", "\\;",
+	",", "\\,",
+	"\r\n", "\\n",
+	"\n", "\\n",
+)
+
+// foldLine splits content lines longer than 75 octets as required by
+// RFC 5545, without breaking multi-byte characters
+func foldLine(line string) string {
+	var out strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > 75 {
+			out.WriteString("\r\n ")
+			length = 1
+		}
+		out.WriteRune(r)
+		length += size
+	}
+	return out.String()
+}
+
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func presenterNames(contribution MongoContribution) []string {
+	var names []string
+	if contribution.Presenters != nil {
+		for _, presenter := range *contribution.Presenters {
+			names = append(names, strings.TrimSpace(presenter.FirstName+" "+presenter.FamilyName))
+		}
+	}
+	return names
+}
+
+func hasPresenter(contribution MongoContribution, presenter string) bool {
+	for _, name := range presenterNames(contribution) {
+		if strings.Contains(strings.ToLower(name), strings.ToLower(presenter)) {
+			return true
+		}
+	}
+	return false
+}
+
+func contributionEvents(conference MongoConference, contributions []MongoContribution) []CalendarEvent {
+	var events []CalendarEvent
+	for _, contribution := range contributions {
+		schedule := contribution.Schedule
+		summary := contribution.Title
+		if contribution.Code != "" {
+			summary = contribution.Code + ": " + summary
+		}
+		description := strings.Join(presenterNames(contribution), ", ")
+		if schedule.SessionTitle != "" {
+			description = strings.TrimSpace(description + "\n" + schedule.SessionTitle)
+		}
+		events = append(events, CalendarEvent{
+			UID:         fmt.Sprintf("contribution-%d-%d@indico.jacow.org", conference.ID, contribution.ID),
+			Summary:     summary,
+			Description: description,
+			Location:    schedule.Room,
+			Start:       schedule.Start,
+			End:         schedule.End,
+		})
+	}
+	return events
+}
+
+// sessionEvents creates one event per session block, listing the
+// contributions presented in it
+func sessionEvents(conference MongoConference, contributions []MongoContribution) []CalendarEvent {
+	blocks := make(map[string]*CalendarEvent)
+	var keys []string
+	for _, contribution := range contributions {
+		schedule := contribution.Schedule
+		key := schedule.SessionCode + "-" + icsTime(schedule.SessionStart)
+		block, found := blocks[key]
+		if !found {
+			summary := schedule.SessionTitle
+			if schedule.SessionCode != "" {
+				summary = schedule.SessionCode + ": " + summary
+			}
+			block = &CalendarEvent{
+				UID:      fmt.Sprintf("session-%d-%s@indico.jacow.org", conference.ID, strings.ToLower(key)),
+				Summary:  summary,
+				Location: schedule.Room,
+				Start:    schedule.SessionStart,
+				End:      schedule.SessionEnd,
+			}
+			blocks[key] = block
+			keys = append(keys, key)
+		}
+		line := contribution.Title
+		if contribution.Code != "" {
+			line = contribution.Code + " " + line
+		}
+		block.Description = strings.TrimSpace(block.Description + "\n" + line)
+	}
+	var events []CalendarEvent
+	for _, key := range keys {
+		events = append(events, *blocks[key])
+	}
+	return events
+}
+
+func calendar(conference MongoConference, events []CalendarEvent, now time.Time) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//JACoW//indico-middleware//EN",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:" + icsEscaper.Replace(conference.Name),
+	}
+	for _, event := range events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+event.UID,
+			"DTSTAMP:"+icsTime(now),
+			"DTSTART:"+icsTime(event.Start),
+			"DTEND:"+icsTime(event.End),
+			"SUMMARY:"+icsEscaper.Replace(event.Summary),
+		)
+		if event.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+icsEscaper.Replace(event.Description))
+		}
+		if event.Location != "" {
+			lines = append(lines, "LOCATION:"+icsEscaper.Replace(event.Location))
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	var out strings.Builder
+	for _, line := range lines {
+		out.WriteString(foldLine(line) + "\r\n")
+	}
+	return out.String()
+}
+
+// Main checks the API key and rate limit of the request before responding
+func Main(in Request) (*Response, error) {
+	return authorized(in.HTTP, in.Conference, func() (*Response, error) {
+		return respond(in)
+	})
+}
+
+func respond(in Request) (*Response, error) {
+	conferenceId, err := strconv.Atoi(in.Conference)
+	if err != nil {
+		return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+	}
+
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	database := client.Database("author-title")
+
+	var conference MongoConference
+	if err := database.Collection("conferences").FindOne(context.Background(), bson.D{{"_id", conferenceId}}).Decode(&conference); err != nil {
+		return nil, fmt.Errorf("error finding conference: %s", err.Error())
+	}
+
+	filter := bson.D{
+		{"conferenceId", conferenceId},
+		{"schedule", bson.D{{"$exists", true}}},
+	}
+	if in.Session != "" {
+		filter = append(filter, bson.E{"schedule.sessionCode", in.Session})
+	}
+	cursor, findError := database.Collection("contributions").Find(context.Background(), filter)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding documents: %s", findError.Error())
+	}
+	var contributions []MongoContribution
+	if err := cursor.All(context.Background(), &contributions); err != nil {
+		return nil, fmt.Errorf("error decoding documents: %s", err.Error())
+	}
+
+	var scheduled []MongoContribution
+	for _, contribution := range contributions {
+		if contribution.Schedule.Start.IsZero() {
+			continue
+		}
+		if in.Presenter != "" && !hasPresenter(contribution, in.Presenter) {
+			continue
+		}
+		scheduled = append(scheduled, contribution)
+	}
+	sort.SliceStable(scheduled, func(i, j int) bool {
+		if !scheduled[i].Schedule.Start.Equal(scheduled[j].Schedule.Start) {
+			return scheduled[i].Schedule.Start.Before(scheduled[j].Schedule.Start)
+		}
+		return scheduled[i].Code < scheduled[j].Code
+	})
+
+	var events []CalendarEvent
+	if in.Per == "session" {
+		events = sessionEvents(conference, scheduled)
+	} else {
+		events = contributionEvents(conference, scheduled)
+	}
+
+	return &Response{
+		Body: calendar(conference, events, time.Now()),
+		Headers: map[string]string{
+			"Content-Type":        "text/calendar; charset=utf-8",
+			"Content-Disposition": fmt.Sprintf("attachment; filename=\"%d.ics\"", conferenceId),
+		},
+	}, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestFoldLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:Beam loss monitors"},
+		{"exactly 75 octets", "SUMMARY:" + strings.Repeat("a", 67)},
+		{"76 octets", "SUMMARY:" + strings.Repeat("a", 68)},
+		{"several folds", "DESCRIPTION:" + strings.Repeat("abcdefghij", 20)},
+		{"multi-byte", "SUMMARY:" + strings.Repeat("Иванова ", 20)},
+		{"wide characters", "LOCATION:" + strings.Repeat("北京高能物理研究所", 10)},
+	}
+	for _, test := range tests {
+		folded := foldLine(test.line)
+		for _, line := range strings.Split(folded, "\r\n") {
+			if len(line) > 75 {
+				t.Errorf("%s: line of %d octets: %q", test.name, len(line), line)
+			}
+			if !utf8.ValidString(line) {
+				t.Errorf("%s: a character was split: %q", test.name, line)
+			}
+		}
+		if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != test.line {
+			t.Errorf("%s: unfolding %q gives %q", test.name, folded, unfolded)
+		}
+		if len(test.line) <= 75 && folded != test.line {
+			t.Errorf("%s: foldLine(%q) = %q, want it unchanged", test.name, test.line, folded)
+		}
+	}
+}
+
+func TestICSTime(t *testing.T) {
+	zurich := time.FixedZone("CEST", 2*60*60)
+	tests := map[time.Time]string{
+		time.Date(2024, 5, 19, 9, 30, 0, 0, time.UTC): "20240519T093000Z",
+		time.Date(2024, 5, 19, 1, 15, 5, 0, zurich):   "20240518T231505Z",
+	}
+	for value, want := range tests {
+		if got := icsTime(value); got != want {
+			t.Errorf("icsTime(%s) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestHasPresenter(t *testing.T) {
+	contribution := MongoContribution{Presenters: &[]MongoPerson{
+		{FirstName: "Ada", FamilyName: "Lovelace"},
+		{FamilyName: "Иванова"},
+	}}
+	if got, want := presenterNames(contribution), []string{"Ada Lovelace", "Иванова"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("presenterNames = %q, want %q", got, want)
+	}
+	tests := map[string]bool{
+		"lovelace":     true,
+		"Ada Lovelace": true,
+		"ИВАНОВА":      true,
+		"Dupont":       false,
+	}
+	for presenter, want := range tests {
+		if got := hasPresenter(contribution, presenter); got != want {
+			t.Errorf("hasPresenter(%q) = %v, want %v", presenter, got, want)
+		}
+	}
+	if hasPresenter(MongoContribution{}, "Ada") {
+		t.Error("a contribution without presenters has a presenter")
+	}
+}
+
+func testContributions() []MongoContribution {
+	session := MongoSchedule{
+		SessionCode:  "TUPA",
+		SessionTitle: "Tuesday Poster Session",
+		SessionStart: time.Date(2024, 5, 21, 16, 0, 0, 0, time.UTC),
+		SessionEnd:   time.Date(2024, 5, 21, 18, 0, 0, 0, time.UTC),
+		Room:         "Exhibition Hall",
+		Start:        time.Date(2024, 5, 21, 16, 0, 0, 0, time.UTC),
+		End:          time.Date(2024, 5, 21, 18, 0, 0, 0, time.UTC),
+	}
+	first, second := session, session
+	return []MongoContribution{
+		{
+			ID:         7,
+			Code:       "TUPA071",
+			Title:      "Beam loss monitors",
+			Presenters: &[]MongoPerson{{FirstName: "Ada", FamilyName: "Lovelace"}},
+			Schedule:   &first,
+		},
+		{ID: 8, Title: "Untitled poster", Schedule: &second},
+	}
+}
+
+func TestContributionEvents(t *testing.T) {
+	contributions := testContributions()
+	want := []CalendarEvent{
+		{
+			UID:         "contribution-41-7@indico.jacow.org",
+			Summary:     "TUPA071: Beam loss monitors",
+			Description: "Ada Lovelace\nTuesday Poster Session",
+			Location:    "Exhibition Hall",
+			Start:       contributions[0].Schedule.Start,
+			End:         contributions[0].Schedule.End,
+		},
+		{
+			UID:         "contribution-41-8@indico.jacow.org",
+			Summary:     "Untitled poster",
+			Description: "Tuesday Poster Session",
+			Location:    "Exhibition Hall",
+			Start:       contributions[1].Schedule.Start,
+			End:         contributions[1].Schedule.End,
+		},
+	}
+	if got := contributionEvents(MongoConference{ID: 41}, contributions); !reflect.DeepEqual(got, want) {
+		t.Errorf("contributionEvents = %+v, want %+v", got, want)
+	}
+}
+
+func TestSessionEvents(t *testing.T) {
+	contributions := testContributions()
+	want := []CalendarEvent{{
+		UID:         "session-41-tupa-20240521t160000z@indico.jacow.org",
+		Summary:     "TUPA: Tuesday Poster Session",
+		Description: "TUPA071 Beam loss monitors\nUntitled poster",
+		Location:    "Exhibition Hall",
+		Start:       contributions[0].Schedule.SessionStart,
+		End:         contributions[0].Schedule.SessionEnd,
+	}}
+	if got := sessionEvents(MongoConference{ID: 41}, contributions); !reflect.DeepEqual(got, want) {
+		t.Errorf("sessionEvents = %+v, want %+v", got, want)
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	conference := MongoConference{ID: 41, Name: "IPAC'24, Nashville"}
+	events := []CalendarEvent{{
+		UID:         "contribution-41-7@indico.jacow.org",
+		Summary:     "TUPA071: Loss; gain, and \\ noise",
+		Description: "Ada Lovelace\nTuesday Poster Session",
+		Start:       time.Date(2024, 5, 21, 16, 0, 0, 0, time.UTC),
+		End:         time.Date(2024, 5, 21, 18, 0, 0, 0, time.UTC),
+	}}
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//JACoW//indico-middleware//EN",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:IPAC'24\\, Nashville",
+		"BEGIN:VEVENT",
+		"UID:contribution-41-7@indico.jacow.org",
+		"DTSTAMP:20240601T120000Z",
+		"DTSTART:20240521T160000Z",
+		"DTEND:20240521T180000Z",
+		"SUMMARY:TUPA071: Loss\\; gain\\, and \\\\ noise",
+		"DESCRIPTION:Ada Lovelace\\nTuesday Poster Session",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if got := calendar(conference, events, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)); got != want {
+		t.Errorf("calendar =\n%s\nwant\n%s", got, want)
+	}
+}
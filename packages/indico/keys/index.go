@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MongoAPIKey is stored by the SHA-256 of the key, so the key itself is only
+// ever shown when it is created
+type MongoAPIKey struct {
+	ID          string    `bson:"_id" json:"id"`
+	Name        string    `bson:"name" json:"name"`
+	Conferences []int     `bson:"conferences" json:"conferences"`
+	RateLimit   int64     `bson:"rateLimit" json:"rate_limit"`
+	Disabled    bool      `bson:"disabled" json:"disabled"`
+	CreatedAt   time.Time `bson:"createdAt" json:"created_at"`
+}
+
+type Request struct {
+	Action      string `json:"action"`
+	Name        string `json:"name"`
+	Conferences string `json:"conferences"`
+	RateLimit   string `json:"rate_limit"`
+	ID          string `json:"id"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+type CreatedKey struct {
+	MongoAPIKey
+	Key string `json:"key"`
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func newAPIKey() (string, error) {
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("error generating API key: %s", err.Error())
+	}
+	return "imw_" + hex.EncodeToString(random), nil
+}
+
+func parseConferences(conferences string) ([]int, error) {
+	var ids = make([]int, 0)
+	for _, field := range strings.Split(conferences, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func createKey(collection *mongo.Collection, in Request) (*CreatedKey, error) {
+	if in.Name == "" {
+		return nil, errors.New("a name is required to create an API key")
+	}
+	conferences, err := parseConferences(in.Conferences)
+	if err != nil {
+		return nil, err
+	}
+	var rateLimit int64
+	if in.RateLimit != "" {
+		rateLimit, err = strconv.ParseInt(in.RateLimit, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error converting rate limit to int: %s", err.Error())
+		}
+	}
+	key, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	apiKey := MongoAPIKey{
+		ID:          hashAPIKey(key),
+		Name:        in.Name,
+		Conferences: conferences,
+		RateLimit:   rateLimit,
+		CreatedAt:   time.Now(),
+	}
+	if _, err := collection.InsertOne(context.Background(), apiKey); err != nil {
+		return nil, fmt.Errorf("error inserting API key: %s", err.Error())
+	}
+	return &CreatedKey{MongoAPIKey: apiKey, Key: key}, nil
+}
+
+// revokeKey disables a key by its id, or a unique prefix of it
+func revokeKey(collection *mongo.Collection, id string) (*MongoAPIKey, error) {
+	if len(id) < 8 || strings.Trim(id, "0123456789abcdef") != "" {
+		return nil, errors.New("give at least 8 characters of the id of the key to revoke")
+	}
+	filter := bson.D{{"_id", bson.D{{"$regex", "^" + id}}}}
+	count, err := collection.CountDocuments(context.Background(), filter)
+	if err != nil {
+		return nil, fmt.Errorf("error finding API key: %s", err.Error())
+	}
+	if count != 1 {
+		return nil, fmt.Errorf("%d API keys match %s", count, id)
+	}
+	var apiKey MongoAPIKey
+	err = collection.FindOneAndUpdate(context.Background(), filter,
+		bson.D{{"$set", bson.D{{"disabled", true}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("error revoking API key: %s", err.Error())
+	}
+	return &apiKey, nil
+}
+
+func Main(in Request) (*Response, error) {
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	collection := client.Database("author-title").Collection("api_keys")
+
+	var output interface{}
+	var err error
+	switch in.Action {
+	case "create":
+		output, err = createKey(collection, in)
+	case "revoke":
+		output, err = revokeKey(collection, in.ID)
+	case "list", "":
+		cursor, findError := collection.Find(context.Background(), bson.D{}, options.Find().SetSort(bson.D{{"createdAt", 1}}))
+		if findError != nil {
+			return nil, fmt.Errorf("error finding API keys: %s", findError.Error())
+		}
+		var keys = make([]MongoAPIKey, 0)
+		if err := cursor.All(context.Background(), &keys); err != nil {
+			return nil, fmt.Errorf("error decoding API keys: %s", err.Error())
+		}
+		output = keys
+	default:
+		return nil, fmt.Errorf("unknown action: %s", in.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling documents: %s", err.Error())
+	}
+	return &Response{
+		Body: string(jsonBytes),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestParseConferences(t *testing.T) {
+	tests := []struct {
+		conferences string
+		want        []int
+		err         bool
+	}{
+		{"", []int{}, false},
+		{"41", []int{41}, false},
+		{" 41, 42 ,,", []int{41, 42}, false},
+		{"41,ipac24", nil, true},
+	}
+	for _, test := range tests {
+		got, err := parseConferences(test.conferences)
+		if (err != nil) != test.err {
+			t.Errorf("parseConferences(%q) error %v", test.conferences, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseConferences(%q) = %v, want %v", test.conferences, got, test.want)
+		}
+	}
+}
+
+func TestNewAPIKey(t *testing.T) {
+	format := regexp.MustCompile(`^imw_[0-9a-f]{48}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		key, err := newAPIKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !format.MatchString(key) {
+			t.Errorf("newAPIKey() = %q, want imw_ and 48 hex digits", key)
+		}
+		if seen[key] {
+			t.Errorf("newAPIKey() returned %q twice", key)
+		}
+		seen[key] = true
+	}
+}
+
+func TestHashAPIKey(t *testing.T) {
+	// The web functions look keys up by this hash, see access.go
+	want := "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"
+	if got := hashAPIKey("secret"); got != want {
+		t.Errorf("hashAPIKey = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"strconv"
+	"time"
+)
+
+type MongoConference struct {
+	ID  int       `bson:"_id"`
+	End time.Time `bson:"end"`
+}
+
+type Request struct {
+	Conference string `json:"conference"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+type PurgedConference struct {
+	Conference    int   `json:"conference"`
+	Contributions int64 `json:"contributions"`
+	History       int64 `json:"history"`
+	Identities    int64 `json:"identities"`
+	AuthorIndex   int64 `json:"authorIndex"`
+}
+
+// retentionFromEnv reads PII_RETENTION_DAYS. Unlike the other day settings
+// it has no default, as purging everything is never what is wanted.
+func retentionFromEnv() (time.Duration, error) {
+	days, err := strconv.Atoi(os.Getenv("PII_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		return 0, errors.New("PII_RETENTION_DAYS must be set to a number of days to purge personal data")
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// emailsFilter selects the contributions which still hold an email of their
+// persons, presenters or authors
+func emailsFilter() bson.D {
+	var fields bson.A
+	for _, field := range []string{"persons", "presenters", "authors"} {
+		fields = append(fields, bson.D{{field + ".email", bson.D{{"$gt", ""}}}})
+	}
+	return bson.D{{"$or", fields}}
+}
+
+// conferencesWithEmails lists the conferences with contributions which hold
+// emails, which is the case again after a purged conference is synced
+func conferencesWithEmails(database *mongo.Database) ([]int, error) {
+	values, err := database.Collection("contributions").Distinct(context.Background(), "conferenceId", emailsFilter())
+	if err != nil {
+		return nil, fmt.Errorf("error finding conferences with emails: %s", err.Error())
+	}
+	var conferences = make([]int, 0)
+	for _, value := range values {
+		switch id := value.(type) {
+		case int32:
+			conferences = append(conferences, int(id))
+		case int64:
+			conferences = append(conferences, int(id))
+		}
+	}
+	return conferences, nil
+}
+
+// expiredConferencesFilter selects, among the conferences which still hold
+// emails, those which ended more than the retention period ago. Pinned
+// conferences, and those which ended within CONFERENCE_GRACE_DAYS, are still
+// synced, so purging them would be undone by the next sync.
+func expiredConferencesFilter(now time.Time, retention time.Duration, withEmails []int) bson.D {
+	if grace := daysFromEnv("CONFERENCE_GRACE_DAYS"); grace > retention {
+		retention = grace
+	}
+	return bson.D{
+		{"_id", bson.D{{"$in", withEmails}}},
+		{"end", bson.D{{"$lt", now.Add(-retention)}}},
+		{"pinned", bson.D{{"$ne", true}}},
+	}
+}
+
+// onlyConference keeps the conference asked for, if it holds emails
+func onlyConference(conferences []int, conferenceId int) []int {
+	for _, id := range conferences {
+		if id == conferenceId {
+			return []int{conferenceId}
+		}
+	}
+	return make([]int, 0)
+}
+
+// purgeConference removes the emails of the persons of every contribution and
+// of the identities they were resolved to, the conference's author index and
+// the contribution history, which holds earlier copies of those persons
+func purgeConference(database *mongo.Database, conferenceId int, now time.Time) (*PurgedConference, error) {
+	purged := &PurgedConference{Conference: conferenceId}
+	contributions := database.Collection("contributions")
+	personIds, err := contributions.Distinct(context.Background(), "persons.person_id", bson.D{{"conferenceId", conferenceId}})
+	if err != nil {
+		return nil, fmt.Errorf("error finding persons of conference %d: %s", conferenceId, err.Error())
+	}
+	for _, field := range []string{"persons", "presenters", "authors"} {
+		filter := bson.D{
+			{"conferenceId", conferenceId},
+			{field + ".email", bson.D{{"$exists", true}}},
+		}
+		update := bson.D{{"$unset", bson.D{{field + ".$[].email", ""}}}}
+		result, err := contributions.UpdateMany(context.Background(), filter, update)
+		if err != nil {
+			return nil, fmt.Errorf("error purging %s of conference %d: %s", field, conferenceId, err.Error())
+		}
+		purged.Contributions += result.ModifiedCount
+	}
+
+	// Identities are shared between conferences, so only their emails go.
+	// Those of persons in conferences still synced are added back by the
+	// next sync.
+	if len(personIds) > 0 {
+		result, err := database.Collection("identities").UpdateMany(context.Background(),
+			bson.D{{"personIds", bson.D{{"$in", personIds}}}, {"emails.0", bson.D{{"$exists", true}}}},
+			bson.D{{"$set", bson.D{{"emails", bson.A{}}}}},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error purging identities of conference %d: %s", conferenceId, err.Error())
+		}
+		purged.Identities = result.ModifiedCount
+	}
+
+	result, err := database.Collection("author_index").DeleteMany(context.Background(), bson.D{{"conferenceId", conferenceId}})
+	if err != nil {
+		return nil, fmt.Errorf("error purging author index of conference %d: %s", conferenceId, err.Error())
+	}
+	purged.AuthorIndex = result.DeletedCount
+
+	result, err = database.Collection("contribution_history").DeleteMany(context.Background(), bson.D{{"conferenceId", conferenceId}})
+	if err != nil {
+		return nil, fmt.Errorf("error purging history of conference %d: %s", conferenceId, err.Error())
+	}
+	purged.History = result.DeletedCount
+
+	_, err = database.Collection("conferences").UpdateOne(context.Background(),
+		bson.D{{"_id", conferenceId}},
+		bson.D{{"$set", bson.D{{"piiPurgedAt", now}}}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error marking conference %d as purged: %s", conferenceId, err.Error())
+	}
+	return purged, nil
+}
+
+func Main(in Request) (*Response, error) {
+	retention, err := retentionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	database := client.Database("author-title")
+
+	run := MongoSyncRun{Job: "purge", Start: time.Now(), Conferences: make([]int, 0)}
+	withEmails, err := conferencesWithEmails(database)
+	if err != nil {
+		return nil, err
+	}
+	if in.Conference != "" {
+		conferenceId, err := strconv.Atoi(in.Conference)
+		if err != nil {
+			return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+		}
+		withEmails = onlyConference(withEmails, conferenceId)
+	}
+	filter := expiredConferencesFilter(run.Start, retention, withEmails)
+	cursor, findError := database.Collection("conferences").Find(context.Background(), filter)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding conferences: %s", findError.Error())
+	}
+	var conferences []MongoConference
+	if err := cursor.All(context.Background(), &conferences); err != nil {
+		return nil, fmt.Errorf("error decoding conferences: %s", err.Error())
+	}
+
+	var output = make([]PurgedConference, 0)
+	for _, conference := range conferences {
+		purged, err := purgeConference(database, conference.ID, run.Start)
+		if err != nil {
+			run.Errors = append(run.Errors, err.Error())
+			continue
+		}
+		run.Conferences = append(run.Conferences, conference.ID)
+		run.Updated += purged.Contributions + purged.Identities
+		run.Deleted += purged.History + purged.AuthorIndex
+		output = append(output, *purged)
+	}
+
+	run.End = time.Now()
+	if err := recordSyncRun(run); err != nil {
+		fmt.Printf("error recording sync run: %s", err.Error())
+	}
+
+	jsonBytes, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling documents: %s", err.Error())
+	}
+	return &Response{
+		Body: string(jsonBytes),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetentionFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		err   bool
+	}{
+		{"", 0, true},
+		{"0", 0, true},
+		{"-30", 0, true},
+		{"a year", 0, true},
+		{"365", 365 * 24 * time.Hour, false},
+	}
+	for _, test := range tests {
+		t.Setenv("PII_RETENTION_DAYS", test.value)
+		got, err := retentionFromEnv()
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("PII_RETENTION_DAYS=%q: got %s, %v", test.value, got, err)
+		}
+	}
+}
+
+// cutoff is the end a conference must be before to be selected by filter
+func cutoff(t *testing.T, filter bson.D) time.Time {
+	t.Helper()
+	for _, element := range filter {
+		if element.Key != "end" {
+			continue
+		}
+		for _, condition := range element.Value.(bson.D) {
+			if condition.Key == "$lt" {
+				return condition.Value.(time.Time)
+			}
+		}
+	}
+	t.Fatalf("no end condition in %v", filter)
+	return time.Time{}
+}
+
+func TestExpiredConferencesFilter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 2, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		name      string
+		grace     string
+		retention time.Duration
+		want      time.Time
+	}{
+		{"no grace", "", 30 * day, now.Add(-30 * day)},
+		{"grace within retention", "7", 30 * day, now.Add(-30 * day)},
+		{"grace equal to retention", "30", 30 * day, now.Add(-30 * day)},
+		{"grace beyond retention", "90", 30 * day, now.Add(-90 * day)},
+		{"invalid grace", "-5", 30 * day, now.Add(-30 * day)},
+	}
+	for _, test := range tests {
+		t.Setenv("CONFERENCE_GRACE_DAYS", test.grace)
+		filter := expiredConferencesFilter(now, test.retention, []int{41, 58})
+		if got := cutoff(t, filter); !got.Equal(test.want) {
+			t.Errorf("%s: conferences ending before %s are purged, want %s", test.name, got, test.want)
+		}
+		var pinned, withEmails bool
+		for _, element := range filter {
+			pinned = pinned || element.Key == "pinned"
+			withEmails = withEmails || (element.Key == "_id" && reflect.DeepEqual(element.Value, bson.D{{"$in", []int{41, 58}}}))
+		}
+		if !pinned || !withEmails {
+			t.Errorf("%s: pinned conferences and those without emails are not skipped: %v", test.name, filter)
+		}
+	}
+}
+
+func TestEmailsFilter(t *testing.T) {
+	want := bson.D{{"$or", bson.A{
+		bson.D{{"persons.email", bson.D{{"$gt", ""}}}},
+		bson.D{{"presenters.email", bson.D{{"$gt", ""}}}},
+		bson.D{{"authors.email", bson.D{{"$gt", ""}}}},
+	}}}
+	if got := emailsFilter(); !reflect.DeepEqual(got, want) {
+		t.Errorf("emailsFilter = %v, want %v", got, want)
+	}
+}
+
+func TestOnlyConference(t *testing.T) {
+	if got := onlyConference([]int{41, 58}, 58); !reflect.DeepEqual(got, []int{58}) {
+		t.Errorf("onlyConference(58) = %v, want [58]", got)
+	}
+	if got := onlyConference([]int{41, 58}, 7); got == nil || len(got) != 0 {
+		t.Errorf("onlyConference(7) = %#v, want no conferences", got)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"strconv"
+	"time"
+)
+
+type LastSynced struct {
+	Conference int       `bson:"conference" json:"conference"`
+	Job        string    `bson:"job" json:"job"`
+	End        time.Time `bson:"end" json:"end"`
+}
+
+type Request struct {
+	Conference string      `json:"conference"`
+	Job        string      `json:"job"`
+	Limit      string      `json:"limit"`
+	HTTP       HTTPRequest `json:"http"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+type RunsPayload struct {
+	Runs       []MongoSyncRun `json:"runs"`
+	LastSynced []LastSynced   `json:"last_synced"`
+}
+
+func runsFilter(in Request) (bson.D, error) {
+	filter := bson.D{}
+	if in.Conference != "" {
+		conferenceId, err := strconv.Atoi(in.Conference)
+		if err != nil {
+			return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+		}
+		filter = append(filter, bson.E{"conferences", conferenceId})
+	}
+	if in.Job != "" {
+		filter = append(filter, bson.E{"job", in.Job})
+	}
+	return filter, nil
+}
+
+func recentRuns(collection *mongo.Collection, filter bson.D, limit int64) ([]MongoSyncRun, error) {
+	findOptions := options.Find().SetSort(bson.D{{"start", -1}}).SetLimit(limit)
+	cursor, findError := collection.Find(context.Background(), filter, findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding sync runs: %s", findError.Error())
+	}
+	defer func(cursor *mongo.Cursor, ctx context.Context) {
+		_ = cursor.Close(ctx)
+	}(cursor, context.Background())
+	var runs = make([]MongoSyncRun, 0)
+	if err := cursor.All(context.Background(), &runs); err != nil {
+		return nil, fmt.Errorf("error decoding sync runs: %s", err.Error())
+	}
+	return runs, nil
+}
+
+// lastSynced finds when each job last finished a run for each conference
+func lastSynced(collection *mongo.Collection, filter bson.D) ([]LastSynced, error) {
+	pipeline := mongo.Pipeline{
+		{{"$match", filter}},
+		{{"$unwind", "$conferences"}},
+		// Once unwound, only keep the conference which was asked for
+		{{"$match", filter}},
+		{{"$group", bson.D{
+			{"_id", bson.D{{"conference", "$conferences"}, {"job", "$job"}}},
+			{"end", bson.D{{"$max", "$end"}}},
+		}}},
+		{{"$project", bson.D{
+			{"_id", 0},
+			{"conference", "$_id.conference"},
+			{"job", "$_id.job"},
+			{"end", 1},
+		}}},
+		{{"$sort", bson.D{{"conference", 1}, {"job", 1}}}},
+	}
+	cursor, aggregateError := collection.Aggregate(context.Background(), pipeline)
+	if aggregateError != nil {
+		return nil, fmt.Errorf("error aggregating sync runs: %s", aggregateError.Error())
+	}
+	defer func(cursor *mongo.Cursor, ctx context.Context) {
+		_ = cursor.Close(ctx)
+	}(cursor, context.Background())
+	var synced = make([]LastSynced, 0)
+	if err := cursor.All(context.Background(), &synced); err != nil {
+		return nil, fmt.Errorf("error decoding last synced: %s", err.Error())
+	}
+	return synced, nil
+}
+
+// runsLimit is the number of runs requested, 20 by default and kept within 1
+// and 100
+func runsLimit(value string) (int64, error) {
+	if value == "" {
+		return 20, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error converting limit to int: %s", err.Error())
+	}
+	if limit < 1 {
+		return 1, nil
+	}
+	if limit > 100 {
+		return 100, nil
+	}
+	return limit, nil
+}
+
+// Main checks the API key and rate limit of the request before responding
+func Main(in Request) (*Response, error) {
+	return authorized(in.HTTP, in.Conference, func() (*Response, error) {
+		return respond(in)
+	})
+}
+
+func respond(in Request) (*Response, error) {
+	filter, err := runsFilter(in)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := runsLimit(in.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	collection := client.Database("author-title").Collection("sync_runs")
+
+	runs, err := recentRuns(collection, filter, limit)
+	if err != nil {
+		return nil, err
+	}
+	synced, err := lastSynced(collection, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := json.Marshal(RunsPayload{
+		Runs:       runs,
+		LastSynced: synced,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling documents: %s", err.Error())
+	}
+	return &Response{
+		Body: string(jsonBytes),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+)
+
+func TestRunsFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Request
+		want bson.D
+		err  bool
+	}{
+		{"all runs", Request{}, bson.D{}, false},
+		{"conference", Request{Conference: "41"}, bson.D{{"conferences", 41}}, false},
+		{"job", Request{Job: "timetables"}, bson.D{{"job", "timetables"}}, false},
+		{"conference and job", Request{Conference: "41", Job: "contributions"}, bson.D{{"conferences", 41}, {"job", "contributions"}}, false},
+		{"invalid conference", Request{Conference: "ipac24"}, nil, true},
+	}
+	for _, test := range tests {
+		got, err := runsFilter(test.in)
+		if (err != nil) != test.err {
+			t.Errorf("%s: runsFilter error %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: runsFilter = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRunsLimit(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+		err   bool
+	}{
+		{"", 20, false},
+		{"5", 5, false},
+		{"1000", 100, false},
+		{"0", 1, false},
+		{"-3", 1, false},
+		{"ten", 0, true},
+	}
+	for _, test := range tests {
+		got, err := runsLimit(test.value)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("runsLimit(%q) = %d, %v, want %d", test.value, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+// TestResponseFixture keeps testdata/response.json the same as the response
+// of runs. Run go test -update after changing the output.
+func TestResponseFixture(t *testing.T) {
+	start := time.Date(2024, 5, 19, 2, 0, 0, 0, time.UTC)
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		if collection != "sync_runs" {
+			return nil
+		}
+		// The runs are found, and when each conference was last synced is
+		// aggregated from them
+		if command == "aggregate" {
+			return []interface{}{LastSynced{Conference: 41, Job: "contributions", End: start.Add(95 * time.Second)}}
+		}
+		return []interface{}{
+			MongoSyncRun{Job: "contributions", Start: start, End: start.Add(95 * time.Second), Conferences: []int{41}, Inserted: 3, Updated: 12, Errors: []string{}},
+			MongoSyncRun{Job: "timetables", Start: start, End: start.Add(40 * time.Second), Conferences: []int{41}, Deleted: 1, Errors: []string{"error fetching timetable of 58: timeout"}},
+		}
+	})
+	response, err := Main(Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponse(t, "testdata/response.json", response)
+}
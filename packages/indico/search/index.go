@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type MongoConference struct {
+	ID    int       `bson:"_id"`
+	Name  string    `bson:"name"`
+	Start time.Time `bson:"start"`
+}
+
+type MongoContribution struct {
+	ID               int                   `bson:"_id"`
+	ConferenceId     int                   `bson:"conferenceId"`
+	Code             string                `bson:"code"`
+	Title            string                `bson:"title"`
+	Description      string                `bson:"description"`
+	ContributionType string                `bson:"contribution_type"`
+	Presenters       *[]MongoPerson        `bson:"presenters,omitempty"`
+	Authors          *[]MongoPerson        `bson:"authors,omitempty"`
+	Persons          []MongoDetailedPerson `bson:"persons"`
+	Score            float64               `bson:"score"`
+}
+
+type MongoPerson struct {
+	FirstName  string `bson:"firstName"`
+	FamilyName string `bson:"familyName"`
+}
+
+type MongoDetailedPerson struct {
+	FirstName string `bson:"first_name"`
+	LastName  string `bson:"last_name"`
+}
+
+type Request struct {
+	Query      string      `json:"query"`
+	Conference string      `json:"conference"`
+	Type       string      `json:"type"`
+	From       string      `json:"from"`
+	To         string      `json:"to"`
+	Limit      string      `json:"limit"`
+	HTTP       HTTPRequest `json:"http"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+type SearchResult struct {
+	ConferenceID     int       `json:"conference_id"`
+	ConferenceName   string    `json:"conference_name"`
+	ConferenceDate   time.Time `json:"conference_date"`
+	ContributionID   int       `json:"contribution_id"`
+	Code             string    `json:"code"`
+	Title            string    `json:"title"`
+	ContributionType string    `json:"contribution_type"`
+	Authors          []string  `json:"authors"`
+	Score            float64   `json:"score"`
+	Highlights       []string  `json:"highlights"`
+}
+
+func parseDate(name string, value string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return date, fmt.Errorf("error parsing %s date: %s", name, err.Error())
+	}
+	return date, nil
+}
+
+// conferencesInRange finds the conferences which start within the from and
+// to dates, either of which may be empty
+func conferencesInRange(collection *mongo.Collection, in Request) (map[int]MongoConference, error) {
+	filter := bson.D{}
+	start := bson.D{}
+	if in.From != "" {
+		from, err := parseDate("from", in.From)
+		if err != nil {
+			return nil, err
+		}
+		start = append(start, bson.E{"$gte", from})
+	}
+	if in.To != "" {
+		to, err := parseDate("to", in.To)
+		if err != nil {
+			return nil, err
+		}
+		start = append(start, bson.E{"$lt", to.AddDate(0, 0, 1)})
+	}
+	if len(start) > 0 {
+		filter = append(filter, bson.E{"start", start})
+	}
+
+	cursor, findError := collection.Find(context.Background(), filter)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding conferences: %s", findError.Error())
+	}
+	var conferences []MongoConference
+	if err := cursor.All(context.Background(), &conferences); err != nil {
+		return nil, fmt.Errorf("error decoding conferences: %s", err.Error())
+	}
+	byId := make(map[int]MongoConference)
+	for _, conference := range conferences {
+		byId[conference.ID] = conference
+	}
+	return byId, nil
+}
+
+func searchFilter(in Request, conferences map[int]MongoConference) (bson.D, error) {
+	filter := bson.D{{"$text", bson.D{{"$search", in.Query}}}}
+	if in.Conference != "" {
+		conferenceId, err := strconv.Atoi(in.Conference)
+		if err != nil {
+			return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+		}
+		filter = append(filter, bson.E{"conferenceId", conferenceId})
+	}
+	if in.From != "" || in.To != "" {
+		ids := bson.A{}
+		for id := range conferences {
+			ids = append(ids, id)
+		}
+		// In an $and, so it can be combined with a single conference
+		filter = append(filter, bson.E{"$and", bson.A{bson.D{{"conferenceId", bson.D{{"$in", ids}}}}}})
+	}
+	if in.Type != "" {
+		filter = append(filter, bson.E{"contribution_type", in.Type})
+	}
+	return filter, nil
+}
+
+func authorNames(contribution MongoContribution) []string {
+	var names []string
+	if len(contribution.Persons) > 0 {
+		for _, person := range contribution.Persons {
+			names = append(names, strings.TrimSpace(person.FirstName+" "+person.LastName))
+		}
+		return names
+	}
+	for _, persons := range []*[]MongoPerson{contribution.Presenters, contribution.Authors} {
+		if persons == nil {
+			continue
+		}
+		for _, person := range *persons {
+			names = append(names, strings.TrimSpace(person.FirstName+" "+person.FamilyName))
+		}
+	}
+	return names
+}
+
+// searchLimit is the number of results requested, 20 by default and kept
+// within 1 and 100
+func searchLimit(value string) (int64, error) {
+	if value == "" {
+		return 20, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error converting limit to int: %s", err.Error())
+	}
+	if limit < 1 {
+		return 1, nil
+	}
+	if limit > 100 {
+		return 100, nil
+	}
+	return limit, nil
+}
+
+// Main checks the API key and rate limit of the request before responding
+func Main(in Request) (*Response, error) {
+	return authorized(in.HTTP, in.Conference, func() (*Response, error) {
+		return respond(in)
+	})
+}
+
+func respond(in Request) (*Response, error) {
+	if strings.TrimSpace(in.Query) == "" {
+		return nil, errors.New("a query is required")
+	}
+	limit, err := searchLimit(in.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	database := client.Database("author-title")
+	collection := database.Collection("contributions")
+
+	conferences, err := conferencesInRange(database.Collection("conferences"), in)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := searchFilter(in, conferences)
+	if err != nil {
+		return nil, err
+	}
+
+	score := bson.D{{"score", bson.D{{"$meta", "textScore"}}}}
+	findOptions := options.Find().
+		SetProjection(bson.D{
+			{"conferenceId", 1},
+			{"code", 1},
+			{"title", 1},
+			{"description", 1},
+			{"contribution_type", 1},
+			{"presenters", 1},
+			{"authors", 1},
+			{"persons.first_name", 1},
+			{"persons.last_name", 1},
+			{"score", bson.D{{"$meta", "textScore"}}},
+		}).
+		SetSort(score).
+		SetLimit(limit)
+	cursor, findError := collection.Find(context.Background(), filter, findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("error searching contributions: %s", findError.Error())
+	}
+	var contributions []MongoContribution
+	if err := cursor.All(context.Background(), &contributions); err != nil {
+		return nil, fmt.Errorf("error decoding contributions: %s", err.Error())
+	}
+
+	terms := queryTerms(in.Query)
+	var output = make([]SearchResult, 0)
+	for _, contribution := range contributions {
+		conference := conferences[contribution.ConferenceId]
+		authors := authorNames(contribution)
+		output = append(output, SearchResult{
+			ConferenceID:     contribution.ConferenceId,
+			ConferenceName:   conference.Name,
+			ConferenceDate:   conference.Start,
+			ContributionID:   contribution.ID,
+			Code:             contribution.Code,
+			Title:            contribution.Title,
+			ContributionType: contribution.ContributionType,
+			Authors:          authors,
+			Score:            contribution.Score,
+			Highlights:       highlights(contribution, authors, terms),
+		})
+	}
+
+	jsonBytes, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling documents: %s", err.Error())
+	}
+	return &Response{
+		Body: string(jsonBytes),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
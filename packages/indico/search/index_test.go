@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchLimit(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+		err   bool
+	}{
+		{"", 20, false},
+		{"5", 5, false},
+		{"100", 100, false},
+		{"101", 100, false},
+		{"0", 1, false},
+		{"-3", 1, false},
+		{"ten", 0, true},
+	}
+	for _, test := range tests {
+		got, err := searchLimit(test.value)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("searchLimit(%q) = %d, %v, want %d", test.value, got, err, test.want)
+		}
+	}
+}
+
+func TestAuthorNames(t *testing.T) {
+	tests := []struct {
+		name         string
+		contribution MongoContribution
+		want         []string
+	}{
+		{"none", MongoContribution{}, nil},
+		{
+			"persons first",
+			MongoContribution{
+				Persons:    []MongoDetailedPerson{{FirstName: "Ada", LastName: "Lovelace"}},
+				Presenters: &[]MongoPerson{{FirstName: "Jean", FamilyName: "Dupont"}},
+			},
+			[]string{"Ada Lovelace"},
+		},
+		{
+			"timetable persons",
+			MongoContribution{
+				Presenters: &[]MongoPerson{{FirstName: "Jean", FamilyName: "Dupont"}},
+				Authors:    &[]MongoPerson{{FirstName: "", FamilyName: "Иванова"}},
+			},
+			[]string{"Jean Dupont", "Иванова"},
+		},
+	}
+	for _, test := range tests {
+		got := authorNames(test.contribution)
+		if strings.Join(got, "|") != strings.Join(test.want, "|") {
+			t.Errorf("%s: authorNames = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSearchFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Request
+		keys []string
+		err  bool
+	}{
+		{"query", Request{Query: "beam"}, []string{"$text"}, false},
+		{"conference", Request{Query: "beam", Conference: "41"}, []string{"$text", "conferenceId"}, false},
+		{"dates and type", Request{Query: "beam", From: "2023-01-01", Type: "Poster"}, []string{"$text", "$and", "contribution_type"}, false},
+		{"bad conference", Request{Query: "beam", Conference: "IPAC"}, nil, true},
+	}
+	for _, test := range tests {
+		filter, err := searchFilter(test.in, map[int]MongoConference{41: {ID: 41}})
+		if (err != nil) != test.err {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		var keys []string
+		for _, element := range filter {
+			keys = append(keys, element.Key)
+		}
+		if strings.Join(keys, ",") != strings.Join(test.keys, ",") {
+			t.Errorf("%s: filter has %v, want %v", test.name, keys, test.keys)
+		}
+	}
+}
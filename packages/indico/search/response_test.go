@@ -0,0 +1,35 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+// TestResponseFixture keeps testdata/response.json the same as the response
+// of search. Run go test -update after changing the output.
+func TestResponseFixture(t *testing.T) {
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		switch collection {
+		case "conferences":
+			return []interface{}{MongoConference{ID: 41, Name: "IPAC'24", Start: time.Date(2024, 5, 19, 0, 0, 0, 0, time.UTC)}}
+		case "contributions":
+			return []interface{}{MongoContribution{
+				ID:               7,
+				ConferenceId:     41,
+				Code:             "TUPA071",
+				Title:            "Beam loss monitors for the storage ring",
+				Description:      "The new beam loss monitors cover the whole ring.",
+				ContributionType: "Poster Presentation",
+				Presenters:       &[]MongoPerson{{FirstName: "Ada", FamilyName: "Lovelace"}},
+				Score:            2.5,
+			}}
+		}
+		return nil
+	})
+	response, err := Main(Request{Query: "beam loss"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponse(t, "testdata/response.json", response)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const snippetLength = 200
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// queryTerms splits a search into lowercase words, dropping the quotes of
+// phrases and negated words, which are never highlighted
+func queryTerms(query string) []string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		term := strings.ToLower(strings.Trim(field, `"`))
+		term = strings.TrimFunc(term, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		// A rough stem, so "magnets" highlights "magnet" as the text index
+		// would match it
+		if len(term) > 3 {
+			term = strings.TrimSuffix(term, "s")
+		}
+		if term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func matchesTerm(word string, terms []string) bool {
+	word = strings.ToLower(word)
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// highlight HTML escapes text and wraps the words matching the search in
+// <mark>, returning whether anything matched
+func highlight(text string, terms []string) (string, bool) {
+	var out strings.Builder
+	matched := false
+	word := []rune{}
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		escaped := html.EscapeString(string(word))
+		if matchesTerm(string(word), terms) {
+			out.WriteString("<mark>" + escaped + "</mark>")
+			matched = true
+		} else {
+			out.WriteString(escaped)
+		}
+		word = word[:0]
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		out.WriteString(html.EscapeString(string(r)))
+	}
+	flush()
+	return out.String(), matched
+}
+
+// window cuts a snippet of the description around the first match
+func window(text string, terms []string) string {
+	runes := []rune(text)
+	if len(runes) <= snippetLength {
+		return text
+	}
+	lower := strings.ToLower(text)
+	first := -1
+	for _, term := range terms {
+		if index := strings.Index(lower, term); index >= 0 && (first == -1 || index < first) {
+			first = index
+		}
+	}
+	start := 0
+	if first > 0 {
+		start = len([]rune(lower[:first])) - snippetLength/4
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetLength
+	if end > len(runes) {
+		end = len(runes)
+		start = end - snippetLength
+	}
+	snippet := strings.TrimSpace(string(runes[start:end]))
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet = snippet + "…"
+	}
+	return snippet
+}
+
+// highlights returns the title, matching author names and a snippet of the
+// description, each with the search words marked, for the fields matched
+func highlights(contribution MongoContribution, authors []string, terms []string) []string {
+	var out = make([]string, 0)
+	if title, matched := highlight(contribution.Title, terms); matched {
+		out = append(out, title)
+	}
+	for _, author := range authors {
+		if name, matched := highlight(author, terms); matched {
+			out = append(out, name)
+		}
+	}
+	description := strings.Join(strings.Fields(html.UnescapeString(tagPattern.ReplaceAllString(contribution.Description, " "))), " ")
+	if snippet, matched := highlight(window(description, terms), terms); matched {
+		out = append(out, snippet)
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQueryTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"Beam loss", []string{"beam", "los"}},
+		{`"beam loss" monitor`, []string{"beam", "los", "monitor"}},
+		{"magnets -quadrupole", []string{"magnet"}},
+		{"gas (rf)", []string{"gas", "rf"}},
+		{"Ускорители", []string{"ускорители"}},
+	}
+	for _, test := range tests {
+		got := queryTerms(test.query)
+		if strings.Join(got, "|") != strings.Join(test.want, "|") {
+			t.Errorf("queryTerms(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text    string
+		terms   []string
+		want    string
+		matched bool
+	}{
+		{"Beam loss monitors", []string{"beam"}, "<mark>Beam</mark> loss monitors", true},
+		{"Magnets & <kickers>", []string{"magnet"}, "<mark>Magnets</mark> &amp; &lt;kickers&gt;", true},
+		{"Beam loss", []string{"rf"}, "Beam loss", false},
+		{"Ионный источник", []string{"ионн"}, "<mark>Ионный</mark> источник", true},
+	}
+	for _, test := range tests {
+		got, matched := highlight(test.text, test.terms)
+		if got != test.want || matched != test.matched {
+			t.Errorf("highlight(%q, %q) = %q, %t, want %q, %t", test.text, test.terms, got, matched, test.want, test.matched)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	short := "A short description of the beam loss monitors."
+	if got := window(short, []string{"beam"}); got != short {
+		t.Errorf("window of a short text = %q", got)
+	}
+
+	long := strings.Repeat("filler ", 60) + "beam loss " + strings.Repeat("filler ", 60)
+	got := window(long, []string{"beam"})
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("window of the middle of a long text is not cut on both sides: %q", got)
+	}
+	if !strings.Contains(got, "beam loss") {
+		t.Errorf("window does not contain the match: %q", got)
+	}
+	if length := len([]rune(got)); length > snippetLength+2 {
+		t.Errorf("window is %d runes long", length)
+	}
+
+	start := window("beam "+long, []string{"beam"})
+	if strings.HasPrefix(start, "…") || !strings.HasPrefix(start, "beam") {
+		t.Errorf("window of a match at the start is cut before it: %q", start)
+	}
+}
+
+func TestHighlights(t *testing.T) {
+	contribution := MongoContribution{
+		Title:       "Beam loss monitors",
+		Description: "<p>New monitors for the storage ring &amp; booster.</p>",
+	}
+	got := highlights(contribution, []string{"Ada Lovelace", "Jean Monitor"}, queryTerms("monitors"))
+	want := []string{
+		"Beam loss <mark>monitors</mark>",
+		"Jean <mark>Monitor</mark>",
+		"New <mark>monitors</mark> for the storage ring &amp; booster.",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("highlights = %q, want %q", got, want)
+	}
+	if got := highlights(contribution, nil, queryTerms("linac")); len(got) != 0 {
+		t.Errorf("highlights without a match = %q", got)
+	}
+}
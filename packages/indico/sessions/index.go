@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"strconv"
+	"time"
+)
+
+type MongoSession struct {
+	ID           string    `bson:"_id"`
+	ConferenceId int       `bson:"conferenceId"`
+	Code         string    `bson:"code"`
+	Title        string    `bson:"title"`
+	Room         string    `bson:"room"`
+	Start        time.Time `bson:"start"`
+	End          time.Time `bson:"end"`
+}
+
+type MongoContribution struct {
+	ID       int            `bson:"_id"`
+	Code     string         `bson:"code"`
+	Title    string         `bson:"title"`
+	Schedule *MongoSchedule `bson:"schedule,omitempty"`
+}
+
+type MongoSchedule struct {
+	SessionID string    `bson:"sessionId"`
+	Room      string    `bson:"room"`
+	Start     time.Time `bson:"start"`
+	End       time.Time `bson:"end"`
+}
+
+type Request struct {
+	Conference string      `json:"conference"`
+	Code       string      `json:"code"`
+	Session    string      `json:"session"`
+	HTTP       HTTPRequest `json:"http"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+type ContributionSlot struct {
+	Code  string    `json:"code"`
+	Title string    `json:"title"`
+	Room  string    `json:"room"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type SessionPayload struct {
+	Code          string             `json:"code"`
+	Title         string             `json:"title"`
+	Room          string             `json:"room"`
+	Start         time.Time          `json:"start"`
+	End           time.Time          `json:"end"`
+	Contributions []ContributionSlot `json:"contributions"`
+}
+
+// Main checks the API key and rate limit of the request before responding
+func Main(in Request) (*Response, error) {
+	return authorized(in.HTTP, in.Conference, func() (*Response, error) {
+		return respond(in)
+	})
+}
+
+func respond(in Request) (*Response, error) {
+	conferenceId, err := strconv.Atoi(in.Conference)
+	if err != nil {
+		return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+	}
+
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	database := client.Database("author-title")
+
+	contributionFilter := bson.D{
+		{"conferenceId", conferenceId},
+		{"schedule.sessionId", bson.D{{"$exists", true}}},
+	}
+	if in.Code != "" {
+		contributionFilter = append(contributionFilter, bson.E{"code", in.Code})
+	}
+	findOptions := options.Find().SetSort(bson.D{{"schedule.start", 1}, {"code", 1}})
+	cursor, findError := database.Collection("contributions").Find(context.Background(), contributionFilter, findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding contributions: %s", findError.Error())
+	}
+	var contributions []MongoContribution
+	if err := cursor.All(context.Background(), &contributions); err != nil {
+		return nil, fmt.Errorf("error decoding contributions: %s", err.Error())
+	}
+
+	sessionFilter := bson.D{{"conferenceId", conferenceId}}
+	if in.Session != "" {
+		sessionFilter = append(sessionFilter, bson.E{"code", in.Session})
+	}
+	if in.Code != "" {
+		// Only the session the contribution is presented in
+		ids := bson.A{}
+		for _, contribution := range contributions {
+			ids = append(ids, contribution.Schedule.SessionID)
+		}
+		sessionFilter = append(sessionFilter, bson.E{"_id", bson.D{{"$in", ids}}})
+	}
+	cursor, findError = database.Collection("sessions").Find(context.Background(), sessionFilter, options.Find().SetSort(bson.D{{"start", 1}}))
+	if findError != nil {
+		return nil, fmt.Errorf("error finding sessions: %s", findError.Error())
+	}
+	var sessions []MongoSession
+	if err := cursor.All(context.Background(), &sessions); err != nil {
+		return nil, fmt.Errorf("error decoding sessions: %s", err.Error())
+	}
+
+	slots := make(map[string][]ContributionSlot)
+	for _, contribution := range contributions {
+		sessionId := contribution.Schedule.SessionID
+		slots[sessionId] = append(slots[sessionId], ContributionSlot{
+			Code:  contribution.Code,
+			Title: contribution.Title,
+			Room:  contribution.Schedule.Room,
+			Start: contribution.Schedule.Start,
+			End:   contribution.Schedule.End,
+		})
+	}
+
+	var output = make([]SessionPayload, 0)
+	for _, session := range sessions {
+		sessionSlots := slots[session.ID]
+		if sessionSlots == nil {
+			sessionSlots = make([]ContributionSlot, 0)
+		}
+		output = append(output, SessionPayload{
+			Code:          session.Code,
+			Title:         session.Title,
+			Room:          session.Room,
+			Start:         session.Start,
+			End:           session.End,
+			Contributions: sessionSlots,
+		})
+	}
+
+	jsonBytes, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling documents: %s", err.Error())
+	}
+	return &Response{
+		Body: string(jsonBytes),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+// TestResponseFixture keeps testdata/response.json the same as the response
+// of sessions. Run go test -update after changing the output.
+func TestResponseFixture(t *testing.T) {
+	start := time.Date(2024, 5, 21, 9, 0, 0, 0, time.UTC)
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		switch collection {
+		case "contributions":
+			return []interface{}{MongoContribution{
+				ID:       7,
+				Code:     "TUPA071",
+				Title:    "Beam loss monitors for the storage ring",
+				Schedule: &MongoSchedule{SessionID: "41-tupa", Room: "Poster Hall", Start: start, End: start.Add(2 * time.Hour)},
+			}}
+		case "sessions":
+			return []interface{}{MongoSession{
+				ID:           "41-tupa",
+				ConferenceId: 41,
+				Code:         "TUPA",
+				Title:        "Tuesday Poster Session",
+				Room:         "Poster Hall",
+				Start:        start,
+				End:          start.Add(2 * time.Hour),
+			}}
+		}
+		return nil
+	})
+	response, err := Main(Request{Conference: "41"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponse(t, "testdata/response.json", response)
+}
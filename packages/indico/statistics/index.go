@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"strconv"
+)
+
+type ConferenceTypeCount struct {
+	ConferenceID     int    `bson:"conferenceId" json:"conference_id"`
+	ConferenceName   string `bson:"conferenceName" json:"conference_name"`
+	ContributionType string `bson:"contributionType" json:"contribution_type"`
+	Contributions    int64  `bson:"contributions" json:"contributions"`
+}
+
+type AffiliationCount struct {
+	AffiliationID int    `bson:"affiliationId" json:"affiliation_id,omitempty"`
+	Name          string `bson:"name" json:"name"`
+	Authors       int64  `bson:"authors" json:"authors"`
+	Contributions int64  `bson:"contributions" json:"contributions"`
+}
+
+type CountryCount struct {
+	CountryCode   string  `bson:"countryCode" json:"country_code"`
+	CountryName   string  `bson:"countryName" json:"country_name"`
+	Authors       int64   `bson:"authors" json:"authors"`
+	Contributions int64   `bson:"contributions" json:"contributions"`
+	Share         float64 `bson:"share" json:"share"`
+}
+
+type YearRepeatRate struct {
+	Year          int     `bson:"year" json:"year"`
+	Authors       int64   `bson:"authors" json:"authors"`
+	RepeatAuthors int64   `bson:"repeatAuthors" json:"repeat_authors"`
+	RepeatRate    float64 `bson:"repeatRate" json:"repeat_rate"`
+}
+
+type Request struct {
+	Conference string      `json:"conference"`
+	Limit      string      `json:"limit"`
+	HTTP       HTTPRequest `json:"http"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+type StatisticsPayload struct {
+	ContributionsByType []ConferenceTypeCount `json:"contributions_by_type"`
+	DistinctAuthors     int64                 `json:"distinct_authors"`
+	TopAffiliations     []AffiliationCount    `json:"top_affiliations"`
+	Countries           []CountryCount        `json:"countries"`
+	RepeatAuthors       []YearRepeatRate      `json:"repeat_authors"`
+}
+
+// authorKey identifies an author by their resolved identity, or by their
+// normalised name when the author_index entry has none
+var authorKey = bson.D{{"$ifNull", bson.A{"$authorId", bson.D{{"$concat", bson.A{"name:", "$normalisedName"}}}}}}
+
+func aggregate(collection *mongo.Collection, pipeline mongo.Pipeline, results interface{}) error {
+	cursor, aggregateError := collection.Aggregate(context.Background(), pipeline)
+	if aggregateError != nil {
+		return fmt.Errorf("error aggregating %s: %s", collection.Name(), aggregateError.Error())
+	}
+	defer func(cursor *mongo.Cursor, ctx context.Context) {
+		_ = cursor.Close(ctx)
+	}(cursor, context.Background())
+	if err := cursor.All(context.Background(), results); err != nil {
+		return fmt.Errorf("error decoding %s statistics: %s", collection.Name(), err.Error())
+	}
+	return nil
+}
+
+// contributionsByType counts the contributions of each type per conference
+func contributionsByType(database *mongo.Database, match bson.D) ([]ConferenceTypeCount, error) {
+	pipeline := mongo.Pipeline{
+		{{"$match", match}},
+		{{"$group", bson.D{
+			{"_id", bson.D{{"conferenceId", "$conferenceId"}, {"contributionType", "$contribution_type"}}},
+			{"contributions", bson.D{{"$sum", 1}}},
+		}}},
+		{{"$lookup", bson.D{
+			{"from", "conferences"},
+			{"localField", "_id.conferenceId"},
+			{"foreignField", "_id"},
+			{"as", "conference"},
+		}}},
+		{{"$project", bson.D{
+			{"_id", 0},
+			{"conferenceId", "$_id.conferenceId"},
+			{"conferenceName", bson.D{{"$first", "$conference.name"}}},
+			{"contributionType", bson.D{{"$ifNull", bson.A{"$_id.contributionType", ""}}}},
+			{"contributions", 1},
+		}}},
+		{{"$sort", bson.D{{"conferenceId", 1}, {"contributions", -1}}}},
+	}
+	var counts = make([]ConferenceTypeCount, 0)
+	err := aggregate(database.Collection("contributions"), pipeline, &counts)
+	return counts, err
+}
+
+// distinctAuthors counts the people in the author index
+func distinctAuthors(database *mongo.Database, match bson.D) (int64, error) {
+	pipeline := mongo.Pipeline{
+		{{"$match", match}},
+		{{"$group", bson.D{{"_id", authorKey}}}},
+		{{"$count", "authors"}},
+	}
+	var counts []struct {
+		Authors int64 `bson:"authors"`
+	}
+	if err := aggregate(database.Collection("author_index"), pipeline, &counts); err != nil {
+		return 0, err
+	}
+	if len(counts) == 0 {
+		return 0, nil
+	}
+	return counts[0].Authors, nil
+}
+
+// topAffiliations ranks affiliations by the number of distinct authors
+func topAffiliations(database *mongo.Database, match bson.D, limit int64) ([]AffiliationCount, error) {
+	pipeline := mongo.Pipeline{
+		{{"$match", match}},
+		{{"$match", bson.D{{"affiliation", bson.D{{"$ne", ""}}}}}},
+		{{"$group", bson.D{
+			{"_id", bson.D{{"$ifNull", bson.A{"$affiliationId", "$affiliation"}}}},
+			{"affiliationId", bson.D{{"$first", "$affiliationId"}}},
+			{"name", bson.D{{"$first", "$affiliation"}}},
+			{"authors", bson.D{{"$addToSet", authorKey}}},
+			{"contributions", bson.D{{"$addToSet", "$contributionId"}}},
+		}}},
+		// Use the canonical name from the affiliations registry when there is one
+		{{"$lookup", bson.D{
+			{"from", "affiliations"},
+			{"localField", "affiliationId"},
+			{"foreignField", "_id"},
+			{"as", "registered"},
+		}}},
+		{{"$project", bson.D{
+			{"_id", 0},
+			{"affiliationId", 1},
+			{"name", bson.D{{"$ifNull", bson.A{bson.D{{"$first", "$registered.canonicalName"}}, "$name"}}}},
+			{"authors", bson.D{{"$size", "$authors"}}},
+			{"contributions", bson.D{{"$size", "$contributions"}}},
+		}}},
+		{{"$sort", bson.D{{"authors", -1}, {"contributions", -1}, {"name", 1}}}},
+		{{"$limit", limit}},
+	}
+	var counts = make([]AffiliationCount, 0)
+	err := aggregate(database.Collection("author_index"), pipeline, &counts)
+	return counts, err
+}
+
+// countries counts distinct authors and contributions by the country of
+// their affiliation in the affiliations registry. Authors are counted once
+// per country, without regard to anything else about them.
+func countries(database *mongo.Database, match bson.D) ([]CountryCount, error) {
+	pipeline := mongo.Pipeline{
+		{{"$match", match}},
+		{{"$lookup", bson.D{
+			{"from", "affiliations"},
+			{"localField", "affiliationId"},
+			{"foreignField", "_id"},
+			{"as", "registered"},
+		}}},
+		{{"$group", bson.D{
+			{"_id", bson.D{{"$ifNull", bson.A{bson.D{{"$first", "$registered.countryCode"}}, ""}}}},
+			{"countryName", bson.D{{"$first", bson.D{{"$first", "$registered.countryName"}}}}},
+			{"authors", bson.D{{"$addToSet", authorKey}}},
+			{"contributions", bson.D{{"$addToSet", "$contributionId"}}},
+		}}},
+		{{"$project", bson.D{
+			{"_id", 0},
+			{"countryCode", "$_id"},
+			{"countryName", bson.D{{"$ifNull", bson.A{"$countryName", ""}}}},
+			{"authors", bson.D{{"$size", "$authors"}}},
+			{"contributions", bson.D{{"$size", "$contributions"}}},
+		}}},
+		{{"$setWindowFields", bson.D{
+			{"output", bson.D{{"total", bson.D{{"$sum", "$authors"}}}}},
+		}}},
+		{{"$set", bson.D{{"share", bson.D{{"$divide", bson.A{"$authors", "$total"}}}}}}},
+		{{"$unset", "total"}},
+		{{"$sort", bson.D{{"authors", -1}, {"countryCode", 1}}}},
+	}
+	var counts = make([]CountryCount, 0)
+	err := aggregate(database.Collection("author_index"), pipeline, &counts)
+	return counts, err
+}
+
+// repeatAuthorsPipeline finds, for each year, how many of its authors already
+// appeared at a conference in an earlier year. The first year of each author
+// is found over every conference before the match, so a single conference
+// still counts the authors who came to earlier ones.
+func repeatAuthorsPipeline(match bson.D) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{"$lookup", bson.D{
+			{"from", "conferences"},
+			{"localField", "conferenceId"},
+			{"foreignField", "_id"},
+			{"as", "conference"},
+		}}},
+		{{"$unwind", "$conference"}},
+		{{"$set", bson.D{{"year", bson.D{{"$year", "$conference.start"}}}}}},
+		{{"$setWindowFields", bson.D{
+			{"partitionBy", authorKey},
+			{"output", bson.D{{"firstYear", bson.D{{"$min", "$year"}}}}},
+		}}},
+		{{"$match", match}},
+		{{"$group", bson.D{
+			{"_id", bson.D{{"author", authorKey}, {"year", "$year"}}},
+			{"firstYear", bson.D{{"$first", "$firstYear"}}},
+		}}},
+		{{"$group", bson.D{
+			{"_id", "$_id.year"},
+			{"authors", bson.D{{"$sum", 1}}},
+			{"repeatAuthors", bson.D{{"$sum", bson.D{{"$cond", bson.A{bson.D{{"$gt", bson.A{"$_id.year", "$firstYear"}}}, 1, 0}}}}}},
+		}}},
+		{{"$project", bson.D{
+			{"_id", 0},
+			{"year", "$_id"},
+			{"authors", 1},
+			{"repeatAuthors", 1},
+			{"repeatRate", bson.D{{"$divide", bson.A{"$repeatAuthors", "$authors"}}}},
+		}}},
+		{{"$sort", bson.D{{"year", 1}}}},
+	}
+}
+
+func repeatAuthors(database *mongo.Database, match bson.D) ([]YearRepeatRate, error) {
+	var rates = make([]YearRepeatRate, 0)
+	err := aggregate(database.Collection("author_index"), repeatAuthorsPipeline(match), &rates)
+	return rates, err
+}
+
+// affiliationsLimit is the number of top affiliations requested, 20 by
+// default and kept within 1 and 100, as MongoDB refuses a $limit which isn't
+// positive
+func affiliationsLimit(value string) (int64, error) {
+	if value == "" {
+		return 20, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error converting limit to int: %s", err.Error())
+	}
+	if limit < 1 {
+		return 1, nil
+	}
+	if limit > 100 {
+		return 100, nil
+	}
+	return limit, nil
+}
+
+// Main checks the API key and rate limit of the request before responding
+func Main(in Request) (*Response, error) {
+	return authorized(in.HTTP, in.Conference, func() (*Response, error) {
+		return respond(in)
+	})
+}
+
+func respond(in Request) (*Response, error) {
+	match := bson.D{}
+	if in.Conference != "" {
+		conferenceId, err := strconv.Atoi(in.Conference)
+		if err != nil {
+			return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+		}
+		match = append(match, bson.E{"conferenceId", conferenceId})
+	}
+	limit, err := affiliationsLimit(in.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	database := client.Database("author-title")
+
+	var output StatisticsPayload
+	if output.ContributionsByType, err = contributionsByType(database, match); err != nil {
+		return nil, err
+	}
+	if output.DistinctAuthors, err = distinctAuthors(database, match); err != nil {
+		return nil, err
+	}
+	if output.TopAffiliations, err = topAffiliations(database, match, limit); err != nil {
+		return nil, err
+	}
+	if output.Countries, err = countries(database, match); err != nil {
+		return nil, err
+	}
+	if output.RepeatAuthors, err = repeatAuthors(database, match); err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling statistics: %s", err.Error())
+	}
+	return &Response{
+		Body: string(jsonBytes),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
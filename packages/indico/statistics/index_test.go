@@ -0,0 +1,45 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+)
+
+func TestAffiliationsLimit(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+		err   bool
+	}{
+		{"", 20, false},
+		{"1", 1, false},
+		{"50", 50, false},
+		{"100", 100, false},
+		{"5000", 100, false},
+		{"0", 1, false},
+		{"-1", 1, false},
+		{"twenty", 0, true},
+	}
+	for _, test := range tests {
+		got, err := affiliationsLimit(test.value)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("affiliationsLimit(%q) = %d, %v, want %d", test.value, got, err, test.want)
+		}
+	}
+}
+
+func TestRepeatAuthorsPipeline(t *testing.T) {
+	match := bson.D{{"conferenceId", 41}}
+	pipeline := repeatAuthorsPipeline(match)
+	stages := make(map[string]int)
+	for index, stage := range pipeline {
+		stages[stage[0].Key] = index
+	}
+	if got := pipeline[stages["$match"]][0].Value; !reflect.DeepEqual(got, match) {
+		t.Errorf("$match = %v, want %v", got, match)
+	}
+	if stages["$match"] < stages["$setWindowFields"] {
+		t.Error("the conference is matched before the first year of each author is found")
+	}
+}
@@ -0,0 +1,28 @@
+//go:build cli
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// main lets the function run outside of the serverless runtime. The request
+// is read as JSON from stdin and the response is written as JSON to stdout.
+func main() {
+	var in Request
+	if err := json.NewDecoder(os.Stdin).Decode(&in); err != nil {
+		fmt.Fprintf(os.Stderr, "error decoding request: %s\n", err.Error())
+		os.Exit(1)
+	}
+	response, err := Main(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(response); err != nil {
+		fmt.Fprintf(os.Stderr, "error encoding response: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+	"testing"
+)
+
+// TestResponseFixture keeps testdata/response.json the same as the response
+// of statistics. Run go test -update after changing the output.
+func TestResponseFixture(t *testing.T) {
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		// Each report is an aggregation, told apart by its pipeline
+		pipeline := body.Lookup("pipeline").String()
+		switch {
+		case collection == "contributions":
+			return []interface{}{ConferenceTypeCount{ConferenceID: 41, ConferenceName: "IPAC'24", ContributionType: "Poster Presentation", Contributions: 1520}}
+		case strings.Contains(pipeline, `"$count"`):
+			return []interface{}{bson.D{{"authors", 4210}}}
+		case strings.Contains(pipeline, `"$setWindowFields"`):
+			return []interface{}{YearRepeatRate{Year: 2024, Authors: 4210, RepeatAuthors: 1800, RepeatRate: 0.4276}}
+		case strings.Contains(pipeline, `"countryCode"`):
+			return []interface{}{CountryCount{CountryCode: "CH", CountryName: "Switzerland", Authors: 330, Contributions: 130, Share: 0.078}}
+		case collection == "author_index":
+			return []interface{}{AffiliationCount{AffiliationID: 1, Name: "CERN", Authors: 310, Contributions: 122}}
+		}
+		return nil
+	})
+	response, err := Main(Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponse(t, "testdata/response.json", response)
+}
@@ -10,8 +10,11 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"reflect"
+	"strconv"
 	"sync"
 	"time"
+	_ "time/tzdata"
 )
 
 func fetch(url string) (string, error) {
@@ -37,7 +40,8 @@ func fetch(url string) (string, error) {
 }
 
 type Request struct {
-	Name string `json:"name"`
+	Name       string `json:"name"`
+	Conference string `json:"conference"`
 }
 
 type Response struct {
@@ -47,8 +51,7 @@ type Response struct {
 }
 
 type MongoConference struct {
-	ID  int       `bson:"_id"`
-	End time.Time `bson:"end"`
+	ID int `bson:"_id"`
 }
 
 type MongoContribution struct {
@@ -59,14 +62,53 @@ type MongoContribution struct {
 	Presenters   *[]MongoPerson `bson:"presenters,omitempty"`
 	Authors      *[]MongoPerson `bson:"authors,omitempty"`
 	ConferenceId int            `bson:"conferenceId"`
+	Schedule     *MongoSchedule `bson:"schedule,omitempty"`
+}
+
+// MongoSchedule is when and where a contribution is presented, along with
+// the session block it belongs to
+type MongoSchedule struct {
+	SessionID    string    `bson:"sessionId"`
+	SessionCode  string    `bson:"sessionCode"`
+	SessionTitle string    `bson:"sessionTitle"`
+	SessionStart time.Time `bson:"sessionStart"`
+	SessionEnd   time.Time `bson:"sessionEnd"`
+	Room         string    `bson:"room"`
+	Start        time.Time `bson:"start"`
+	End          time.Time `bson:"end"`
 }
 
 type MongoPerson struct {
-	FirstName    string `bson:"firstName"`
-	FamilyName   string `bson:"familyName"`
-	Affiliation  string `bson:"affiliation"`
-	DisplayOrder int    `bson:"displayOrder"`
-	Email        string `bson:"email"`
+	FirstName    string `bson:"firstName" json:"firstName"`
+	FamilyName   string `bson:"familyName" json:"familyName"`
+	Affiliation  string `bson:"affiliation" json:"affiliation"`
+	DisplayOrder int    `bson:"displayOrder" json:"displayOrder"`
+	Email        string `bson:"email" json:"-"` // protected by EMAIL_POLICY
+}
+
+// MongoHistoryPerson is a MongoPerson as recorded in contribution history,
+// which never includes the email
+type MongoHistoryPerson struct {
+	FirstName    string `bson:"firstName" json:"firstName"`
+	FamilyName   string `bson:"familyName" json:"familyName"`
+	Affiliation  string `bson:"affiliation" json:"affiliation"`
+	DisplayOrder int    `bson:"displayOrder" json:"displayOrder"`
+	Email        string `bson:"-" json:"-"`
+}
+
+// historyPersons drops the emails of persons, so a change of email alone is
+// not a change and is never recorded or sent to webhooks
+func historyPersons(persons *[]MongoPerson) *[]MongoHistoryPerson {
+	if persons == nil {
+		return nil
+	}
+	var public = make([]MongoHistoryPerson, 0, len(*persons))
+	for _, person := range *persons {
+		historyPerson := MongoHistoryPerson(person)
+		historyPerson.Email = ""
+		public = append(public, historyPerson)
+	}
+	return &public
 }
 
 type Timetable struct {
@@ -88,6 +130,10 @@ type TimetableEntry struct {
 	Description string             `json:"description"`
 	Presenters  *[]TimetableAuthor `json:"presenters,omitempty"`
 	Authors     *[]TimetableAuthor `json:"authors,omitempty"`
+	Room        string             `json:"room"`
+	StartDate   TimetableDate      `json:"startDate"`
+	EndDate     TimetableDate      `json:"endDate"`
+	Session     *TimetableSession  `json:"-"`
 }
 
 type TimetableDate struct {
@@ -100,11 +146,87 @@ type TimetableSession struct {
 	ID        string                    `json:"id"`
 	Title     string                    `json:"title"`
 	Code      string                    `json:"code"`
+	Room      string                    `json:"room"`
 	StartDate TimetableDate             `json:"startDate"`
 	EndDate   TimetableDate             `json:"endDate"`
 	Entries   map[string]TimetableEntry `json:"entries"`
 }
 
+// parseTimetableDate reads a timetable date in its own timezone, returning
+// the zero time when it is missing
+func parseTimetableDate(date TimetableDate) time.Time {
+	location, err := time.LoadLocation(date.Tz)
+	if err != nil {
+		location = time.UTC
+	}
+	parsed, err := time.ParseInLocation("2006-01-02 15:04:05", date.Date+" "+date.Time, location)
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed
+}
+
+type MongoSession struct {
+	ID           string    `bson:"_id"`
+	ConferenceId int       `bson:"conferenceId"`
+	BlockID      string    `bson:"blockId"`
+	Code         string    `bson:"code"`
+	Title        string    `bson:"title"`
+	Room         string    `bson:"room"`
+	Start        time.Time `bson:"start"`
+	End          time.Time `bson:"end"`
+}
+
+// sessionID identifies a session block, whose ids are only unique within a
+// conference
+func sessionID(conferenceId int, session TimetableSession) string {
+	return fmt.Sprintf("%d-%s", conferenceId, session.ID)
+}
+
+func timetableSessionToMongoSession(session TimetableSession, conferenceId int) MongoSession {
+	return MongoSession{
+		ID:           sessionID(conferenceId, session),
+		ConferenceId: conferenceId,
+		BlockID:      session.ID,
+		Code:         session.Code,
+		Title:        session.Title,
+		Room:         session.Room,
+		Start:        parseTimetableDate(session.StartDate),
+		End:          parseTimetableDate(session.EndDate),
+	}
+}
+
+func timetableEntryToMongoSchedule(entry TimetableEntry, conferenceId int) *MongoSchedule {
+	if entry.Session == nil {
+		return nil
+	}
+	room := entry.Room
+	if room == "" {
+		room = entry.Session.Room
+	}
+	return &MongoSchedule{
+		SessionID:    sessionID(conferenceId, *entry.Session),
+		SessionCode:  entry.Session.Code,
+		SessionTitle: entry.Session.Title,
+		SessionStart: parseTimetableDate(entry.Session.StartDate),
+		SessionEnd:   parseTimetableDate(entry.Session.EndDate),
+		Room:         room,
+		Start:        parseTimetableDate(entry.StartDate),
+		End:          parseTimetableDate(entry.EndDate),
+	}
+}
+
+// contributionUpdate sets the fields of a stored contribution from the
+// timetable. An empty schedule is left out of the $set, so it is unset to
+// remove a contribution from a session it was taken out of.
+func contributionUpdate(contribution MongoContribution) bson.D {
+	update := bson.D{{"$set", contribution}}
+	if contribution.Schedule == nil {
+		update = append(update, bson.E{"$unset", bson.D{{"schedule", ""}}})
+	}
+	return update
+}
+
 func timetableAuthorToMongoPerson(author TimetableAuthor) MongoPerson {
 	displayOrder := 0
 	if len(author.DisplayOrder) > 0 {
@@ -117,7 +239,7 @@ func timetableAuthorToMongoPerson(author TimetableAuthor) MongoPerson {
 		FamilyName:   author.FamilyName,
 		Affiliation:  author.Affiliation,
 		DisplayOrder: displayOrder,
-		Email:        author.Email,
+		Email:        protectEmail(author.Email),
 	}
 }
 
@@ -149,7 +271,32 @@ func timetableEntryToMongoContribution(entry TimetableEntry, conferenceId int) M
 		Presenters:   presenters,
 		Authors:      authors,
 		ConferenceId: conferenceId,
+		Schedule:     timetableEntryToMongoSchedule(entry, conferenceId),
+	}
+}
+
+// contributionChanges lists the fields of a contribution which differ
+// between what is stored and what was fetched from the timetable
+func contributionChanges(existing MongoContribution, updated MongoContribution) []MongoFieldChange {
+	var changes []MongoFieldChange
+	compare := func(field string, old interface{}, new interface{}) {
+		if !reflect.DeepEqual(old, new) {
+			changes = append(changes, MongoFieldChange{Field: field, Old: old, New: new})
+		}
 	}
+	compare("code", existing.Code, updated.Code)
+	compare("title", existing.Title, updated.Title)
+	compare("description", existing.Description, updated.Description)
+	compare("presenters", historyPersons(existing.Presenters), historyPersons(updated.Presenters))
+	compare("authors", historyPersons(existing.Authors), historyPersons(updated.Authors))
+	return changes
+}
+
+// webhookFields are the changes which are sent to webhooks
+var webhookFields = map[string]bool{
+	"title":      true,
+	"presenters": true,
+	"authors":    true,
 }
 
 func currentConferences() ([]int, error) {
@@ -162,8 +309,8 @@ func currentConferences() ([]int, error) {
 
 	collection := client.Database("author-title").Collection("conferences")
 
-	// Print the ID of all items in this collection
-	cursor, findError := collection.Find(context.Background(), bson.D{})
+	findOptions := options.Find().SetProjection(bson.D{{"_id", 1}})
+	cursor, findError := collection.Find(context.Background(), activeConferencesFilter(time.Now()), findOptions)
 	if findError != nil {
 		return nil, fmt.Errorf("error finding conferences: %s", findError.Error())
 	}
@@ -172,7 +319,6 @@ func currentConferences() ([]int, error) {
 	}(cursor, context.Background())
 
 	var ids []int
-	// Iterate through the cursor to access the retrieved documents
 	for cursor.Next(context.Background()) {
 		var conference MongoConference
 
@@ -180,14 +326,25 @@ func currentConferences() ([]int, error) {
 			return nil, fmt.Errorf("error decoding conference: %s", decodeErr.Error())
 		}
 
-		if conference.End.After(time.Now()) {
-			ids = append(ids, conference.ID)
-		}
+		ids = append(ids, conference.ID)
 	}
 
 	return ids, nil
 }
 
+// requestedConferences returns the conference asked for in the request, or
+// every current conference when none was given
+func requestedConferences(in Request) ([]int, error) {
+	if in.Conference == "" {
+		return currentConferences()
+	}
+	conferenceId, err := strconv.Atoi(in.Conference)
+	if err != nil {
+		return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+	}
+	return []int{conferenceId}, nil
+}
+
 func findSessions(timetableContent string) (map[int]TimetableEntry, error) {
 	var timetable Timetable
 	if err := json.Unmarshal([]byte(timetableContent), &timetable); err != nil {
@@ -197,8 +354,10 @@ func findSessions(timetableContent string) (map[int]TimetableEntry, error) {
 	for _, day := range timetable.Results {
 		for _, room := range day {
 			for _, session := range room {
+				session := session
 				for _, entry := range session.Entries {
 					if entry.ID != 0 {
+						entry.Session = &session
 						entries[entry.ID] = entry
 					}
 				}
@@ -208,7 +367,40 @@ func findSessions(timetableContent string) (map[int]TimetableEntry, error) {
 	return entries, nil
 }
 
-func uploadTimetable(id int, collection mongo.Collection, wg *sync.WaitGroup) error {
+// uploadSessions replaces the sessions of a conference with the session
+// blocks of its timetable
+func uploadSessions(id int, database *mongo.Database, entries map[int]TimetableEntry) error {
+	sessions := make(map[string]MongoSession)
+	for _, entry := range entries {
+		if entry.Session != nil {
+			session := timetableSessionToMongoSession(*entry.Session, id)
+			sessions[session.ID] = session
+		}
+	}
+
+	var operations []mongo.WriteModel
+	ids := bson.A{}
+	for _, session := range sessions {
+		operation := mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{"_id", session.ID}}).
+			SetReplacement(session).
+			SetUpsert(true)
+		operations = append(operations, operation)
+		ids = append(ids, session.ID)
+	}
+	operations = append(operations, mongo.NewDeleteManyModel().SetFilter(bson.D{
+		{"conferenceId", id},
+		{"_id", bson.D{{"$nin", ids}}},
+	}))
+
+	_, err := database.Collection("sessions").BulkWrite(context.Background(), operations, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return fmt.Errorf("error bulk writing sessions: %s", err.Error())
+	}
+	return nil
+}
+
+func uploadTimetable(id int, collection mongo.Collection, run *syncRun, wg *sync.WaitGroup) error {
 	defer wg.Done()
 
 	cursor, findError := collection.Find(context.Background(), bson.D{{"conferenceId", id}})
@@ -248,18 +440,33 @@ func uploadTimetable(id int, collection mongo.Collection, wg *sync.WaitGroup) er
 		return nil
 	}
 
+	if err := uploadSessions(id, collection.Database(), entries); err != nil {
+		return err
+	}
+
+	var history []MongoContributionHistory
+	syncedAt := time.Now()
+
 	for _, entry := range entries {
 		mongoContribution := timetableEntryToMongoContribution(entry, id)
-		if _, found := existingContributions[mongoContribution.ID]; !found {
+		if existing, found := existingContributions[mongoContribution.ID]; !found {
 			operation := mongo.NewInsertOneModel().SetDocument(mongoContribution)
 			operations = append(operations, operation)
 		} else {
 			filter := bson.D{{"_id", entry.ID}}
-			update := bson.D{
-				{"$set", timetableEntryToMongoContribution(entry, id)},
-			}
-			operation := mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update)
+			operation := mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(contributionUpdate(mongoContribution))
 			operations = append(operations, operation)
+
+			if changes := contributionChanges(existing, mongoContribution); len(changes) > 0 {
+				history = append(history, MongoContributionHistory{
+					ContributionID: mongoContribution.ID,
+					ConferenceId:   id,
+					Code:           mongoContribution.Code,
+					Job:            "timetables",
+					SyncedAt:       syncedAt,
+					Changes:        changes,
+				})
+			}
 		}
 	}
 
@@ -272,20 +479,53 @@ func uploadTimetable(id int, collection mongo.Collection, wg *sync.WaitGroup) er
 	}
 
 	bulkWriteOptions := options.BulkWrite().SetOrdered(false)
-	_, err = collection.BulkWrite(context.Background(), operations, bulkWriteOptions)
+	result, err := collection.BulkWrite(context.Background(), operations, bulkWriteOptions)
 
 	if err != nil {
 		return fmt.Errorf("error bulk writing: %s", err.Error())
 	}
+	run.addResult(result)
+
+	if err := insertHistory(collection.Database(), history); err != nil {
+		return err
+	}
+	for _, change := range history {
+		if err := notifyWebhooks(collection.Database(), change); err != nil {
+			run.addError(fmt.Errorf("contribution %d: %s", change.ContributionID, err.Error()))
+		}
+	}
 	return nil
 }
 
-func Main(in Request) (*Response, error) {
+// syncRun collects the outcome of a run from the concurrent uploads
+type syncRun struct {
+	sync.Mutex
+	MongoSyncRun
+}
+
+func (run *syncRun) addResult(result *mongo.BulkWriteResult) {
+	run.Lock()
+	defer run.Unlock()
+	run.Inserted += result.InsertedCount
+	run.Updated += result.ModifiedCount
+	run.Deleted += result.DeletedCount
+}
 
-	ids, err := currentConferences()
+func (run *syncRun) addError(err error) {
+	run.Lock()
+	defer run.Unlock()
+	run.Errors = append(run.Errors, err.Error())
+}
+
+func syncTimetables(in Request, run *syncRun) (*Response, error) {
+	if _, err := emailPolicyFromEnv(); err != nil {
+		return nil, err
+	}
+	ids, err := requestedConferences(in)
 	if err != nil {
-		return nil, fmt.Errorf("error finding current conferences: %s", err.Error())
+		return nil, fmt.Errorf("error finding conferences: %s", err.Error())
 	}
+	run.Conferences = ids
 
 	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
 
@@ -302,8 +542,9 @@ func Main(in Request) (*Response, error) {
 		wg.Add(1)
 		id := id
 		go func() {
-			err = uploadTimetable(id, *collection, &wg)
+			err := uploadTimetable(id, *collection, run, &wg)
 			if err != nil {
+				run.addError(fmt.Errorf("conference %d: %s", id, err.Error()))
 				fmt.Printf("Error uploading timetable: %s", err.Error())
 			}
 		}()
@@ -314,4 +555,17 @@ func Main(in Request) (*Response, error) {
 	return &Response{
 		Body: fmt.Sprintf("%d Timetables downloaded", len(ids)),
 	}, nil
-}
\ No newline at end of file
+}
+
+func Main(in Request) (*Response, error) {
+	run := &syncRun{MongoSyncRun: MongoSyncRun{Job: "timetables", Start: time.Now()}}
+	response, err := syncTimetables(in, run)
+	if err != nil {
+		run.addError(err)
+	}
+	run.End = time.Now()
+	if recordErr := recordSyncRun(run.MongoSyncRun); recordErr != nil {
+		fmt.Printf("error recording sync run: %s", recordErr.Error())
+	}
+	return response, err
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContributionChanges(t *testing.T) {
+	jane := MongoPerson{FirstName: "Jane", FamilyName: "Smith", Affiliation: "CERN", Email: "hmac:aa"}
+	janeNewEmail := jane
+	janeNewEmail.Email = "hmac:bb"
+	janet := jane
+	janet.FirstName = "Janet"
+
+	tests := []struct {
+		name     string
+		existing MongoContribution
+		updated  MongoContribution
+		fields   []string
+	}{
+		{
+			name:     "unchanged",
+			existing: MongoContribution{Title: "A", Authors: &[]MongoPerson{jane}},
+			updated:  MongoContribution{Title: "A", Authors: &[]MongoPerson{jane}},
+		},
+		{
+			name:     "email only",
+			existing: MongoContribution{Presenters: &[]MongoPerson{jane}, Authors: &[]MongoPerson{jane}},
+			updated:  MongoContribution{Presenters: &[]MongoPerson{janeNewEmail}, Authors: &[]MongoPerson{janeNewEmail}},
+		},
+		{
+			name:     "title",
+			existing: MongoContribution{Title: "A"},
+			updated:  MongoContribution{Title: "B"},
+			fields:   []string{"title"},
+		},
+		{
+			name:     "author name",
+			existing: MongoContribution{Authors: &[]MongoPerson{jane}},
+			updated:  MongoContribution{Authors: &[]MongoPerson{janet}},
+			fields:   []string{"authors"},
+		},
+		{
+			name:     "presenters added",
+			existing: MongoContribution{},
+			updated:  MongoContribution{Presenters: &[]MongoPerson{jane}},
+			fields:   []string{"presenters"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes := contributionChanges(test.existing, test.updated)
+			var fields []string
+			for _, change := range changes {
+				fields = append(fields, change.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(test.fields, ",") {
+				t.Fatalf("changed fields are %v, want %v", fields, test.fields)
+			}
+			// The changes are stored as history, which must not hold emails
+			raw, err := bson.Marshal(MongoContributionHistory{Changes: changes})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(bson.Raw(raw).String(), "email") || strings.Contains(bson.Raw(raw).String(), "hmac:") {
+				t.Errorf("history holds an email: %s", bson.Raw(raw).String())
+			}
+		})
+	}
+}
+
+func TestRequestedConferences(t *testing.T) {
+	tests := []struct {
+		conference string
+		want       []int
+		err        bool
+	}{
+		{"41", []int{41}, false},
+		{" 41", nil, true},
+		{"IPAC", nil, true},
+	}
+	for _, test := range tests {
+		got, err := requestedConferences(Request{Conference: test.conference})
+		if (err != nil) != test.err || len(got) != len(test.want) || (len(got) > 0 && got[0] != test.want[0]) {
+			t.Errorf("requestedConferences(%q) = %v, %v, want %v", test.conference, got, err, test.want)
+		}
+	}
+}
+
+func TestParseTimetableDate(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Skip("no timezone database")
+	}
+	tests := []struct {
+		name string
+		date TimetableDate
+		want time.Time
+	}{
+		{"timezone", TimetableDate{"2024-05-21", "16:00:00", "Europe/Zurich"}, time.Date(2024, 5, 21, 16, 0, 0, 0, zurich)},
+		{"unknown timezone", TimetableDate{"2024-05-21", "16:00:00", "Mars/Olympus"}, time.Date(2024, 5, 21, 16, 0, 0, 0, time.UTC)},
+		{"no timezone", TimetableDate{"2024-05-21", "16:00:00", ""}, time.Date(2024, 5, 21, 16, 0, 0, 0, time.UTC)},
+		{"missing", TimetableDate{}, time.Time{}},
+		{"invalid", TimetableDate{"21/05/2024", "4pm", "UTC"}, time.Time{}},
+	}
+	for _, test := range tests {
+		if got := parseTimetableDate(test.date); !got.Equal(test.want) {
+			t.Errorf("%s: parseTimetableDate(%+v) = %s, want %s", test.name, test.date, got, test.want)
+		}
+	}
+}
+
+const testTimetable = `{"results": {"41": {"20240521": {"s1": {
+	"id": "s1", "title": "Tuesday Poster Session", "code": "TUPA", "room": "Exhibition Hall",
+	"startDate": {"date": "2024-05-21", "time": "16:00:00", "tz": "UTC"},
+	"endDate": {"date": "2024-05-21", "time": "18:00:00", "tz": "UTC"},
+	"entries": {
+		"c7": {"contributionId": 7, "code": "TUPA071", "title": "Beam loss monitors",
+			"startDate": {"date": "2024-05-21", "time": "16:00:00", "tz": "UTC"},
+			"endDate": {"date": "2024-05-21", "time": "18:00:00", "tz": "UTC"}},
+		"c8": {"contributionId": 8, "code": "TUPA072", "title": "Beam position monitors", "room": "Board 72",
+			"startDate": {"date": "2024-05-21", "time": "16:00:00", "tz": "UTC"},
+			"endDate": {"date": "2024-05-21", "time": "18:00:00", "tz": "UTC"}},
+		"b1": {"title": "Coffee break"}
+	}
+}}}}}`
+
+func TestFindSessions(t *testing.T) {
+	entries, err := findSessions(testTimetable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("found %d entries, want the 2 contributions: %+v", len(entries), entries)
+	}
+	start := time.Date(2024, 5, 21, 16, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 5, 21, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		id   int
+		want MongoSchedule
+	}{
+		{7, MongoSchedule{"41-s1", "TUPA", "Tuesday Poster Session", start, end, "Exhibition Hall", start, end}},
+		{8, MongoSchedule{"41-s1", "TUPA", "Tuesday Poster Session", start, end, "Board 72", start, end}},
+	}
+	for _, test := range tests {
+		schedule := timetableEntryToMongoSchedule(entries[test.id], 41)
+		if schedule == nil || !reflect.DeepEqual(*schedule, test.want) {
+			t.Errorf("schedule of %d = %+v, want %+v", test.id, schedule, test.want)
+		}
+	}
+
+	want := MongoSession{
+		ID:           "41-s1",
+		ConferenceId: 41,
+		BlockID:      "s1",
+		Code:         "TUPA",
+		Title:        "Tuesday Poster Session",
+		Room:         "Exhibition Hall",
+		Start:        start,
+		End:          end,
+	}
+	if got := timetableSessionToMongoSession(*entries[7].Session, 41); !reflect.DeepEqual(got, want) {
+		t.Errorf("session = %+v, want %+v", got, want)
+	}
+
+	if timetableEntryToMongoSchedule(TimetableEntry{ID: 9}, 41) != nil {
+		t.Error("an entry outside a session has a schedule")
+	}
+	if _, err := findSessions("<html>"); err == nil {
+		t.Error("an invalid timetable was parsed")
+	}
+}
+
+func TestContributionUpdate(t *testing.T) {
+	scheduled := MongoContribution{ID: 7, Schedule: &MongoSchedule{SessionCode: "TUPA"}}
+	if got, want := contributionUpdate(scheduled), (bson.D{{"$set", scheduled}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("contributionUpdate of a scheduled contribution = %v, want %v", got, want)
+	}
+	unscheduled := MongoContribution{ID: 7}
+	want := bson.D{{"$set", unscheduled}, {"$unset", bson.D{{"schedule", ""}}}}
+	if got := contributionUpdate(unscheduled); !reflect.DeepEqual(got, want) {
+		t.Errorf("contributionUpdate of an unscheduled contribution = %v, want %v", got, want)
+	}
+}
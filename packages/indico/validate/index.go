@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type MongoContribution struct {
+	ID           int                   `bson:"_id"`
+	Code         string                `bson:"code"`
+	Presenters   *[]MongoPerson        `bson:"presenters,omitempty"`
+	Authors      *[]MongoPerson        `bson:"authors,omitempty"`
+	ConferenceId int                   `bson:"conferenceId"`
+	Persons      []MongoDetailedPerson `bson:"persons"`
+}
+
+type MongoPerson struct {
+	FirstName    string `bson:"firstName"`
+	FamilyName   string `bson:"familyName"`
+	Affiliation  string `bson:"affiliation"`
+	DisplayOrder int    `bson:"displayOrder"`
+	Email        string `bson:"email"`
+}
+
+type MongoAffiliationLink struct {
+	ID   int    `bson:"id"`
+	Name string `bson:"name"`
+}
+
+type MongoDetailedPerson struct {
+	ID              int                  `bson:"person_id"`
+	FirstName       string               `bson:"first_name"`
+	LastName        string               `bson:"last_name"`
+	Affiliation     string               `bson:"affiliation"`
+	AffiliationLink MongoAffiliationLink `bson:"affiliation_link"`
+}
+
+type Request struct {
+	Conference string      `json:"conference"`
+	HTTP       HTTPRequest `json:"http"`
+}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+type Issue struct {
+	ContributionID int    `json:"contribution_id"`
+	Code           string `json:"code"`
+	Severity       string `json:"severity"`
+	Check          string `json:"check"`
+	Message        string `json:"message"`
+}
+
+func fullName(firstName string, lastName string) string {
+	return strings.TrimSpace(firstName + " " + lastName)
+}
+
+func normaliseName(firstName string, lastName string) string {
+	return strings.ToLower(strings.Join(strings.Fields(fullName(firstName, lastName)), " "))
+}
+
+// isAllCaps reports whether a name is written entirely in capitals, ignoring
+// single letters such as initials
+func isAllCaps(name string) bool {
+	longest, letters := 0, 0
+	for _, r := range name {
+		if !unicode.IsLetter(r) {
+			letters = 0
+			continue
+		}
+		if !unicode.IsUpper(r) {
+			return false
+		}
+		letters++
+		if letters > longest {
+			longest = letters
+		}
+	}
+	return longest > 1
+}
+
+// timetablePersons are the presenters and authors of a contribution. A
+// presenter is usually also an author, so each person is only listed once,
+// known by their email or, without one, their name.
+func timetablePersons(contribution MongoContribution) []MongoPerson {
+	var persons []MongoPerson
+	seen := make(map[string]bool)
+	for _, list := range []*[]MongoPerson{contribution.Presenters, contribution.Authors} {
+		if list == nil {
+			continue
+		}
+		for _, person := range *list {
+			key := "name:" + normaliseName(person.FirstName, person.FamilyName)
+			if person.Email != "" {
+				key = "email:" + person.Email
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			persons = append(persons, person)
+		}
+	}
+	return persons
+}
+
+func nameIssues(contribution MongoContribution) []Issue {
+	var issues []Issue
+	for _, person := range timetablePersons(contribution) {
+		name := fullName(person.FirstName, person.FamilyName)
+		if person.FirstName == "" || person.FamilyName == "" {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Check:    "missing_name",
+				Message:  fmt.Sprintf("\"%s\" is missing a first or family name", name),
+			})
+		}
+		if isAllCaps(person.FirstName) || isAllCaps(person.FamilyName) {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Check:    "all_caps_name",
+				Message:  fmt.Sprintf("\"%s\" is written in capitals", name),
+			})
+		}
+	}
+	return issues
+}
+
+func displayOrderIssues(contribution MongoContribution) []Issue {
+	names := make(map[int]map[string]bool)
+	for _, person := range timetablePersons(contribution) {
+		if names[person.DisplayOrder] == nil {
+			names[person.DisplayOrder] = make(map[string]bool)
+		}
+		// A presenter is usually also listed as an author
+		names[person.DisplayOrder][normaliseName(person.FirstName, person.FamilyName)] = true
+	}
+	var orders []int
+	for order, people := range names {
+		if len(people) > 1 {
+			orders = append(orders, order)
+		}
+	}
+	sort.Ints(orders)
+	var issues []Issue
+	for _, order := range orders {
+		var people []string
+		for name := range names[order] {
+			people = append(people, name)
+		}
+		sort.Strings(people)
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Check:    "display_order_collision",
+			Message:  fmt.Sprintf("%s share display order %d", strings.Join(people, ", "), order),
+		})
+	}
+	return issues
+}
+
+func personsMismatchIssues(contribution MongoContribution) []Issue {
+	// Details are only available once the contributions sync has run
+	if len(contribution.Persons) == 0 {
+		return nil
+	}
+	timetable := make(map[string]bool)
+	for _, person := range timetablePersons(contribution) {
+		timetable[normaliseName(person.FirstName, person.FamilyName)] = true
+	}
+	detailed := make(map[string]bool)
+	for _, person := range contribution.Persons {
+		detailed[normaliseName(person.FirstName, person.LastName)] = true
+	}
+
+	var issues []Issue
+	for _, name := range sortedKeys(timetable) {
+		if !detailed[name] {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Check:    "persons_mismatch",
+				Message:  fmt.Sprintf("\"%s\" is in the timetable but not in the contribution's persons", name),
+			})
+		}
+	}
+	for _, name := range sortedKeys(detailed) {
+		if !timetable[name] {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Check:    "persons_mismatch",
+				Message:  fmt.Sprintf("\"%s\" is in the contribution's persons but not in the timetable", name),
+			})
+		}
+	}
+	return issues
+}
+
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// commonSpellings finds the most used spelling of each linked affiliation
+// across the whole conference
+func commonSpellings(contributions []MongoContribution) map[int]string {
+	counts := make(map[int]map[string]int)
+	for _, contribution := range contributions {
+		for _, person := range contribution.Persons {
+			id := person.AffiliationLink.ID
+			if id == 0 || person.Affiliation == "" {
+				continue
+			}
+			if counts[id] == nil {
+				counts[id] = make(map[string]int)
+			}
+			counts[id][person.Affiliation]++
+		}
+	}
+	spellings := make(map[int]string)
+	for id, spellingCounts := range counts {
+		best := ""
+		for spelling, count := range spellingCounts {
+			if count > spellingCounts[best] || (count == spellingCounts[best] && spelling < best) {
+				best = spelling
+			}
+		}
+		spellings[id] = best
+	}
+	return spellings
+}
+
+func affiliationSpellingIssues(contribution MongoContribution, spellings map[int]string) []Issue {
+	var issues []Issue
+	for _, person := range contribution.Persons {
+		common, found := spellings[person.AffiliationLink.ID]
+		if !found || person.Affiliation == "" || person.Affiliation == common {
+			continue
+		}
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Check:    "inconsistent_affiliation",
+			Message: fmt.Sprintf("%s's affiliation \"%s\" is usually spelt \"%s\"",
+				fullName(person.FirstName, person.LastName), person.Affiliation, common),
+		})
+	}
+	return issues
+}
+
+func validate(contributions []MongoContribution) []Issue {
+	spellings := commonSpellings(contributions)
+	var issues = make([]Issue, 0)
+	for _, contribution := range contributions {
+		var contributionIssues []Issue
+		contributionIssues = append(contributionIssues, nameIssues(contribution)...)
+		contributionIssues = append(contributionIssues, displayOrderIssues(contribution)...)
+		contributionIssues = append(contributionIssues, personsMismatchIssues(contribution)...)
+		contributionIssues = append(contributionIssues, affiliationSpellingIssues(contribution, spellings)...)
+		for _, issue := range contributionIssues {
+			issue.ContributionID = contribution.ID
+			issue.Code = contribution.Code
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// Main checks the API key and rate limit of the request before responding
+func Main(in Request) (*Response, error) {
+	return authorized(in.HTTP, in.Conference, func() (*Response, error) {
+		return respond(in)
+	})
+}
+
+func respond(in Request) (*Response, error) {
+	conferenceId, err := strconv.Atoi(in.Conference)
+	if err != nil {
+		return nil, fmt.Errorf("error converting conference id to int: %s", err.Error())
+	}
+
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	collection := client.Database("author-title").Collection("contributions")
+
+	findOptions := options.Find().SetSort(bson.D{{"code", 1}})
+	cursor, findError := collection.Find(context.Background(), bson.D{{"conferenceId", conferenceId}}, findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding documents: %s", findError.Error())
+	}
+	var contributions []MongoContribution
+	if err := cursor.All(context.Background(), &contributions); err != nil {
+		return nil, fmt.Errorf("error decoding documents: %s", err.Error())
+	}
+
+	jsonBytes, err := json.Marshal(validate(contributions))
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling documents: %s", err.Error())
+	}
+	return &Response{
+		Body: string(jsonBytes),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
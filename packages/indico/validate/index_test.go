@@ -0,0 +1,183 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// messages lists the check and message of each issue
+func messages(issues []Issue) []string {
+	var list []string
+	for _, issue := range issues {
+		list = append(list, issue.Check+": "+issue.Message)
+	}
+	return list
+}
+
+func TestIsAllCaps(t *testing.T) {
+	tests := map[string]bool{
+		"":         false,
+		"J.":       false,
+		"J. R.":    false,
+		"SMITH":    true,
+		"Smith":    false,
+		"McDONALD": false,
+		"O'NEILL":  true,
+		"ÉMILE":    true,
+		"ИВАНОВА":  true,
+		"Иванова":  false,
+		"李":        false,
+	}
+	for name, want := range tests {
+		if got := isAllCaps(name); got != want {
+			t.Errorf("isAllCaps(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestTimetablePersons(t *testing.T) {
+	ada := MongoPerson{FirstName: "Ada", FamilyName: "LOVELACE", Email: "hmac:ada"}
+	contribution := MongoContribution{
+		Presenters: &[]MongoPerson{ada},
+		Authors: &[]MongoPerson{
+			{FirstName: "A.", FamilyName: "Lovelace", Email: "hmac:ada"},
+			{FirstName: "Jean", FamilyName: "Dupont"},
+			{FirstName: " jean", FamilyName: "DUPONT"},
+			{FirstName: "Jean", FamilyName: "Dupont", Email: "hmac:jean"},
+		},
+	}
+	var got []string
+	for _, person := range timetablePersons(contribution) {
+		got = append(got, person.FirstName+" "+person.FamilyName)
+	}
+	if want := []string{"Ada LOVELACE", "Jean Dupont", "Jean Dupont"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("timetablePersons = %q, want %q", got, want)
+	}
+	issues := messages(nameIssues(contribution))
+	if want := []string{`all_caps_name: "Ada LOVELACE" is written in capitals`}; !reflect.DeepEqual(issues, want) {
+		t.Errorf("nameIssues = %q, want %q", issues, want)
+	}
+}
+
+func TestNameIssues(t *testing.T) {
+	tests := []struct {
+		name    string
+		persons []MongoPerson
+		want    []string
+	}{
+		{"complete names", []MongoPerson{{FirstName: "Ada", FamilyName: "Lovelace"}, {FirstName: "J.", FamilyName: "Dupont"}}, nil},
+		{"missing family name", []MongoPerson{{FirstName: "Ada"}}, []string{`missing_name: "Ada" is missing a first or family name`}},
+		{"missing first name", []MongoPerson{{FamilyName: "Lovelace"}}, []string{`missing_name: "Lovelace" is missing a first or family name`}},
+		{"capitals", []MongoPerson{{FirstName: "Ada", FamilyName: "LOVELACE"}}, []string{`all_caps_name: "Ada LOVELACE" is written in capitals`}},
+		{
+			"missing and capitals",
+			[]MongoPerson{{FamilyName: "LOVELACE"}},
+			[]string{`missing_name: "LOVELACE" is missing a first or family name`, `all_caps_name: "LOVELACE" is written in capitals`},
+		},
+	}
+	for _, test := range tests {
+		got := messages(nameIssues(MongoContribution{Authors: &test.persons}))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: nameIssues = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDisplayOrderIssues(t *testing.T) {
+	ada := MongoPerson{FirstName: "Ada", FamilyName: "Lovelace", DisplayOrder: 1}
+	tests := []struct {
+		name       string
+		presenters []MongoPerson
+		authors    []MongoPerson
+		want       []string
+	}{
+		{"presenter also an author", []MongoPerson{ada}, []MongoPerson{ada, {FirstName: "Jean", FamilyName: "Dupont", DisplayOrder: 2}}, nil},
+		{"spacing and case", []MongoPerson{ada}, []MongoPerson{{FirstName: " ada", FamilyName: "LOVELACE ", DisplayOrder: 1}}, nil},
+		{
+			"collision",
+			nil,
+			[]MongoPerson{ada, {FirstName: "Jean", FamilyName: "Dupont", DisplayOrder: 1}, {FirstName: "Li", FamilyName: "Wei", DisplayOrder: 0}, {FirstName: "Ana", FamilyName: "Silva", DisplayOrder: 0}},
+			[]string{
+				"display_order_collision: ana silva, li wei share display order 0",
+				"display_order_collision: ada lovelace, jean dupont share display order 1",
+			},
+		},
+	}
+	for _, test := range tests {
+		got := messages(displayOrderIssues(MongoContribution{Presenters: &test.presenters, Authors: &test.authors}))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: displayOrderIssues = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPersonsMismatchIssues(t *testing.T) {
+	tests := []struct {
+		name    string
+		authors []MongoPerson
+		persons []MongoDetailedPerson
+		want    []string
+	}{
+		{"not synced", []MongoPerson{{FirstName: "Ada", FamilyName: "Lovelace"}}, nil, nil},
+		{
+			"matching",
+			[]MongoPerson{{FirstName: "Ada", FamilyName: "Lovelace"}},
+			[]MongoDetailedPerson{{FirstName: "ADA", LastName: "Lovelace"}},
+			nil,
+		},
+		{
+			"mismatch",
+			[]MongoPerson{{FirstName: "Ada", FamilyName: "Lovelace"}},
+			[]MongoDetailedPerson{{FirstName: "Jean", LastName: "Dupont"}},
+			[]string{
+				`persons_mismatch: "ada lovelace" is in the timetable but not in the contribution's persons`,
+				`persons_mismatch: "jean dupont" is in the contribution's persons but not in the timetable`,
+			},
+		},
+	}
+	for _, test := range tests {
+		got := messages(personsMismatchIssues(MongoContribution{Authors: &test.authors, Persons: test.persons}))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: personsMismatchIssues = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCommonSpellings(t *testing.T) {
+	person := func(id int, affiliation string) MongoDetailedPerson {
+		return MongoDetailedPerson{Affiliation: affiliation, AffiliationLink: MongoAffiliationLink{ID: id}}
+	}
+	contributions := []MongoContribution{
+		{Persons: []MongoDetailedPerson{person(1, "CERN"), person(1, "Cern"), person(2, "DESY"), person(0, "Unlinked")}},
+		{Persons: []MongoDetailedPerson{person(1, "CERN"), person(2, "Deutsches Elektronen-Synchrotron"), person(3, "")}},
+	}
+	want := map[int]string{1: "CERN", 2: "DESY"}
+	if got := commonSpellings(contributions); !reflect.DeepEqual(got, want) {
+		t.Errorf("commonSpellings = %v, want %v", got, want)
+	}
+}
+
+func TestAffiliationSpellingIssues(t *testing.T) {
+	spellings := map[int]string{1: "CERN"}
+	tests := []struct {
+		name   string
+		person MongoDetailedPerson
+		want   []string
+	}{
+		{"common spelling", MongoDetailedPerson{FirstName: "Ada", LastName: "Lovelace", Affiliation: "CERN", AffiliationLink: MongoAffiliationLink{ID: 1}}, nil},
+		{"unlinked", MongoDetailedPerson{FirstName: "Ada", LastName: "Lovelace", Affiliation: "Cern"}, nil},
+		{"no affiliation", MongoDetailedPerson{FirstName: "Ada", LastName: "Lovelace", AffiliationLink: MongoAffiliationLink{ID: 1}}, nil},
+		{
+			"other spelling",
+			MongoDetailedPerson{FirstName: "Ada", LastName: "Lovelace", Affiliation: "Cern", AffiliationLink: MongoAffiliationLink{ID: 1}},
+			[]string{`inconsistent_affiliation: Ada Lovelace's affiliation "Cern" is usually spelt "CERN"`},
+		},
+	}
+	for _, test := range tests {
+		contribution := MongoContribution{Persons: []MongoDetailedPerson{test.person}}
+		got := messages(affiliationSpellingIssues(contribution, spellings))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: affiliationSpellingIssues = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+// TestResponseFixture keeps testdata/response.json the same as the response
+// of validate. Run go test -update after changing the output.
+func TestResponseFixture(t *testing.T) {
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		if collection != "contributions" {
+			return nil
+		}
+		return []interface{}{MongoContribution{
+			ID:           7,
+			Code:         "TUPA071",
+			ConferenceId: 41,
+			Presenters:   &[]MongoPerson{{FirstName: "ADA", FamilyName: "LOVELACE", Affiliation: "ANSTO", DisplayOrder: 1}},
+			Authors:      &[]MongoPerson{{FirstName: "Jean", FamilyName: "Dupont", Affiliation: "ANSTO", DisplayOrder: 1}},
+			Persons: []MongoDetailedPerson{
+				{ID: 1, FirstName: "ADA", LastName: "LOVELACE", Affiliation: "ANSTO"},
+			},
+		}}
+	})
+	response, err := Main(Request{Conference: "41"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponse(t, "testdata/response.json", response)
+}
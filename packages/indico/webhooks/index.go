@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"os"
+	"time"
+)
+
+// MongoQueuedWebhook is an event queued by timetables or contributions
+type MongoQueuedWebhook struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	URL            string             `bson:"url"`
+	ConferenceId   int                `bson:"conferenceId"`
+	ContributionID int                `bson:"contributionId"`
+	Event          string             `bson:"event"`
+	Payload        string             `bson:"payload"`
+	Signature      string             `bson:"signature"`
+	Attempts       int                `bson:"attempts"`
+	NextAttemptAt  time.Time          `bson:"nextAttemptAt"`
+	CreatedAt      time.Time          `bson:"createdAt"`
+}
+
+type MongoWebhookDelivery struct {
+	URL            string    `bson:"url"`
+	ConferenceId   int       `bson:"conferenceId"`
+	ContributionID int       `bson:"contributionId"`
+	Event          string    `bson:"event"`
+	Attempts       int       `bson:"attempts"`
+	StatusCode     int       `bson:"statusCode"`
+	Error          string    `bson:"error,omitempty"`
+	DeliveredAt    time.Time `bson:"deliveredAt"`
+}
+
+type Request struct{}
+
+type Response struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+type DeliveryCounts struct {
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+	Retrying  int `json:"retrying"`
+}
+
+const (
+	webhookAttempts = 3
+	webhookTimeout  = 5 * time.Second
+	// deliveryBudget is how long a run keeps starting deliveries, leaving
+	// room for the last one to time out within the function's limit
+	deliveryBudget = 45 * time.Second
+)
+
+// nextAttempt is when a failed delivery is retried, backing off by five
+// minutes for every attempt made
+func nextAttempt(attempts int, now time.Time) time.Time {
+	return now.Add(time.Duration(attempts) * 5 * time.Minute)
+}
+
+// postWebhook makes one attempt to deliver a queued event and returns the
+// status code received
+func postWebhook(client *http.Client, queued MongoQueuedWebhook) (int, error) {
+	req, err := http.NewRequest("POST", queued.URL, bytes.NewReader([]byte(queued.Payload)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Indico-Middleware-Signature", queued.Signature)
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// deliver attempts a queued event once. Delivered events, and those out of
+// attempts, are logged in webhook_deliveries and removed from the queue;
+// the others are retried by a later run.
+func deliver(database *mongo.Database, client *http.Client, queued MongoQueuedWebhook, counts *DeliveryCounts) error {
+	statusCode, postErr := postWebhook(client, queued)
+	queued.Attempts++
+	queue := database.Collection("webhook_queue")
+
+	if postErr != nil && queued.Attempts < webhookAttempts {
+		counts.Retrying++
+		_, err := queue.UpdateOne(context.Background(), bson.D{{"_id", queued.ID}}, bson.D{{"$set", bson.D{
+			{"attempts", queued.Attempts},
+			{"nextAttemptAt", nextAttempt(queued.Attempts, time.Now())},
+		}}})
+		if err != nil {
+			return fmt.Errorf("error rescheduling webhook: %s", err.Error())
+		}
+		return nil
+	}
+
+	delivery := MongoWebhookDelivery{
+		URL:            queued.URL,
+		ConferenceId:   queued.ConferenceId,
+		ContributionID: queued.ContributionID,
+		Event:          queued.Event,
+		Attempts:       queued.Attempts,
+		StatusCode:     statusCode,
+		DeliveredAt:    time.Now(),
+	}
+	if postErr != nil {
+		delivery.Error = postErr.Error()
+		counts.Failed++
+	} else {
+		counts.Delivered++
+	}
+	if _, err := database.Collection("webhook_deliveries").InsertOne(context.Background(), delivery); err != nil {
+		return fmt.Errorf("error logging webhook delivery: %s", err.Error())
+	}
+	if _, err := queue.DeleteOne(context.Background(), bson.D{{"_id", queued.ID}}); err != nil {
+		return fmt.Errorf("error removing delivered webhook: %s", err.Error())
+	}
+	return nil
+}
+
+// Main sends the queued webhook events which are due, oldest first, until
+// the queue is empty or the run is out of time
+func Main(in Request) (*Response, error) {
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	database := client.Database("author-title")
+
+	start := time.Now()
+	findOptions := options.Find().SetSort(bson.D{{"nextAttemptAt", 1}, {"createdAt", 1}})
+	cursor, findError := database.Collection("webhook_queue").Find(context.Background(),
+		bson.D{{"nextAttemptAt", bson.D{{"$lte", start}}}}, findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("error finding queued webhooks: %s", findError.Error())
+	}
+	defer func(cursor *mongo.Cursor, ctx context.Context) {
+		_ = cursor.Close(ctx)
+	}(cursor, context.Background())
+
+	httpClient := &http.Client{Timeout: webhookTimeout}
+	var counts DeliveryCounts
+	for time.Since(start) < deliveryBudget && cursor.Next(context.Background()) {
+		var queued MongoQueuedWebhook
+		if err := cursor.Decode(&queued); err != nil {
+			return nil, fmt.Errorf("error decoding queued webhook: %s", err.Error())
+		}
+		if err := deliver(database, httpClient, queued, &counts); err != nil {
+			return nil, err
+		}
+	}
+
+	jsonBytes, err := json.Marshal(counts)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling counts: %s", err.Error())
+	}
+	return &Response{
+		Body: string(jsonBytes),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
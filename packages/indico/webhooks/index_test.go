@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPostWebhook(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		failed bool
+	}{
+		{"ok", http.StatusOK, false},
+		{"no content", http.StatusNoContent, false},
+		{"server error", http.StatusInternalServerError, true},
+		{"not modified", http.StatusNotModified, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var signature, body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				signature = r.Header.Get("X-Indico-Middleware-Signature")
+				content, _ := io.ReadAll(r.Body)
+				body = string(content)
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			queued := MongoQueuedWebhook{URL: server.URL, Payload: `{"event":"contribution.changed"}`, Signature: "sha256=abc"}
+			statusCode, err := postWebhook(server.Client(), queued)
+			if statusCode != test.status || (err != nil) != test.failed {
+				t.Fatalf("got %d, %v", statusCode, err)
+			}
+			if signature != queued.Signature || body != queued.Payload {
+				t.Errorf("sent %q signed %q", body, signature)
+			}
+		})
+	}
+}
+
+func TestPostWebhookTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	if _, err := postWebhook(client, MongoQueuedWebhook{URL: server.URL}); err == nil {
+		t.Fatal("a slow subscriber was not timed out")
+	}
+}
+
+func TestNextAttempt(t *testing.T) {
+	now := time.Date(2024, 5, 19, 12, 0, 0, 0, time.UTC)
+	tests := map[int]time.Duration{
+		1: 5 * time.Minute,
+		2: 10 * time.Minute,
+	}
+	for attempts, want := range tests {
+		if got := nextAttempt(attempts, now).Sub(now); got != want {
+			t.Errorf("nextAttempt(%d) is %s later, want %s", attempts, got, want)
+		}
+	}
+	// The last attempt must start, and time out, before the function does
+	if deliveryBudget+webhookTimeout >= 60*time.Second {
+		t.Errorf("deliveries can run past the 60s timeout")
+	}
+}
@@ -0,0 +1,230 @@
+//go:build ignore
+
+package main
+
+// access.go is copied into every web function by go generate in shared,
+// edit it there
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPRequest is the part of the web request passed to web functions
+// under the http key
+type HTTPRequest struct {
+	Headers map[string]string `json:"headers"`
+}
+
+// MongoAPIKey is an API key, stored by the SHA-256 of the key itself. A key
+// with conferences can only be used for those conferences.
+type MongoAPIKey struct {
+	ID          string    `bson:"_id"`
+	Name        string    `bson:"name"`
+	Conferences []int     `bson:"conferences"`
+	RateLimit   int64     `bson:"rateLimit"`
+	Disabled    bool      `bson:"disabled"`
+	CreatedAt   time.Time `bson:"createdAt"`
+}
+
+var rateLimitIndex sync.Once
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func header(request HTTPRequest, name string) string {
+	for key, value := range request.Headers {
+		if strings.EqualFold(key, name) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// requestAPIKey reads the key from the X-API-Key header or a bearer token
+func requestAPIKey(request HTTPRequest) string {
+	if key := header(request, "X-API-Key"); key != "" {
+		return key
+	}
+	authorization := header(request, "Authorization")
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return ""
+}
+
+func rateLimitFromEnv(name string, fallback int64) int64 {
+	if limit, err := strconv.ParseInt(os.Getenv(name), 10, 64); err == nil {
+		return limit
+	}
+	return fallback
+}
+
+func errorResponse(statusCode int, message string) *Response {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	return &Response{
+		StatusCode: statusCode,
+		Body:       string(body),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+}
+
+// clientAddress is the address the gateway saw the request come from. The
+// gateway appends it to X-Forwarded-For, so it is the last entry: the ones
+// before it are set by the client and can't be trusted.
+func clientAddress(request HTTPRequest) string {
+	forwarded := strings.Split(header(request, "X-Forwarded-For"), ",")
+	return strings.TrimSpace(forwarded[len(forwarded)-1])
+}
+
+// allowedConference checks a scoped key against the conference of the
+// request. Scoped keys can't be used for requests across all conferences.
+func allowedConference(key MongoAPIKey, conference string) bool {
+	if len(key.Conferences) == 0 {
+		return true
+	}
+	conferenceId, err := strconv.Atoi(conference)
+	if err != nil {
+		return false
+	}
+	for _, id := range key.Conferences {
+		if id == conferenceId {
+			return true
+		}
+	}
+	return false
+}
+
+// countRequest counts a request in the client's current one minute window
+// and returns how many requests it has made in it
+func countRequest(collection *mongo.Collection, client string, now time.Time) (int64, time.Time, error) {
+	rateLimitIndex.Do(func() {
+		_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+			Keys:    bson.D{{"expiresAt", 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+	})
+
+	window := now.Truncate(time.Minute)
+	reset := window.Add(time.Minute)
+	var counter struct {
+		Count int64 `bson:"count"`
+	}
+	err := collection.FindOneAndUpdate(context.Background(),
+		bson.D{{"_id", fmt.Sprintf("%s:%d", client, window.Unix())}},
+		bson.D{
+			{"$inc", bson.D{{"count", 1}}},
+			{"$setOnInsert", bson.D{{"expiresAt", reset}}},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, reset, fmt.Errorf("error counting request: %s", err.Error())
+	}
+	return counter.Count, reset, nil
+}
+
+// scopeFilter limits a query to the conferences of a scoped key, matching
+// field against them. It is empty for other keys.
+func scopeFilter(key MongoAPIKey, field string) bson.D {
+	if len(key.Conferences) == 0 {
+		return bson.D{}
+	}
+	return bson.D{{field, bson.D{{"$in", key.Conferences}}}}
+}
+
+// authorized runs the handler of a web function when the request's API key,
+// or the anonymous tier when there is none, may access the conference and
+// is within its rate limit
+func authorized(request HTTPRequest, conference string, handler func() (*Response, error)) (*Response, error) {
+	allowed := func(key MongoAPIKey) bool {
+		return allowedConference(key, conference)
+	}
+	return authorize(request, allowed, func(MongoAPIKey) (*Response, error) {
+		return handler()
+	})
+}
+
+// scopedAuthorized runs the handler of a web function which reads across
+// conferences, like graphql, for any key within its rate limit. The handler
+// gets the key, to only return the conferences a scoped key may access.
+func scopedAuthorized(request HTTPRequest, handler func(key MongoAPIKey) (*Response, error)) (*Response, error) {
+	return authorize(request, nil, handler)
+}
+
+// authorize checks the API key and rate limit of a request, and whether the
+// key is allowed when allowed is set, before running the handler. Anonymous
+// requests have an empty key.
+func authorize(request HTTPRequest, allowed func(key MongoAPIKey) bool, handler func(key MongoAPIKey) (*Response, error)) (*Response, error) {
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return nil, fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	database := client.Database("author-title")
+
+	var apiKey MongoAPIKey
+	var rateClient string
+	var limit int64
+	if key := requestAPIKey(request); key != "" {
+		err := database.Collection("api_keys").FindOne(context.Background(), bson.D{{"_id", hashAPIKey(key)}}).Decode(&apiKey)
+		if errors.Is(err, mongo.ErrNoDocuments) || apiKey.Disabled {
+			return errorResponse(http.StatusUnauthorized, "invalid API key"), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error finding API key: %s", err.Error())
+		}
+		if allowed != nil && !allowed(apiKey) {
+			return errorResponse(http.StatusForbidden, "this API key can't access this conference"), nil
+		}
+		rateClient = "key:" + apiKey.ID
+		limit = apiKey.RateLimit
+		if limit == 0 {
+			limit = rateLimitFromEnv("API_KEY_RATE_LIMIT", 600)
+		}
+	} else {
+		limit = rateLimitFromEnv("ANONYMOUS_RATE_LIMIT", 60)
+		if limit == 0 {
+			return errorResponse(http.StatusUnauthorized, "an API key is required"), nil
+		}
+		// Anonymous requests are limited per client address
+		rateClient = "anonymous:" + clientAddress(request)
+	}
+
+	count, reset, err := countRequest(database.Collection("rate_limits"), rateClient, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if count > limit {
+		response := errorResponse(http.StatusTooManyRequests, "rate limit exceeded")
+		response.Headers["Retry-After"] = strconv.Itoa(int(time.Until(reset).Seconds()) + 1)
+		return response, nil
+	}
+
+	response, err := handler(apiKey)
+	if response != nil {
+		if response.Headers == nil {
+			response.Headers = make(map[string]string)
+		}
+		response.Headers["X-RateLimit-Limit"] = strconv.FormatInt(limit, 10)
+		response.Headers["X-RateLimit-Remaining"] = strconv.FormatInt(limit-count, 10)
+	}
+	return response, err
+}
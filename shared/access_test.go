@@ -0,0 +1,409 @@
+//go:build ignore
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"go.mongodb.org/mongo-driver/bson"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the response fixtures in testdata")
+
+func TestClientAddress(t *testing.T) {
+	tests := []struct {
+		forwarded string
+		want      string
+	}{
+		{"203.0.113.7", "203.0.113.7"},
+		{"198.51.100.1, 203.0.113.7", "203.0.113.7"},
+		{"spoofed-1, spoofed-2,203.0.113.7 ", "203.0.113.7"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		request := HTTPRequest{Headers: map[string]string{"x-forwarded-for": test.forwarded}}
+		if got := clientAddress(request); got != test.want {
+			t.Errorf("clientAddress(%q) = %q, want %q", test.forwarded, got, test.want)
+		}
+	}
+	// A client choosing the first entries still counts against one address
+	first := clientAddress(HTTPRequest{Headers: map[string]string{"X-Forwarded-For": "1.1.1.1, 203.0.113.7"}})
+	second := clientAddress(HTTPRequest{Headers: map[string]string{"X-Forwarded-For": "2.2.2.2, 203.0.113.7"}})
+	if first != second {
+		t.Errorf("spoofed addresses are counted apart: %q, %q", first, second)
+	}
+}
+
+func TestRequestAPIKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"none", nil, ""},
+		{"header", map[string]string{"x-api-key": " imw_abc "}, "imw_abc"},
+		{"header case", map[string]string{"X-API-KEY": "imw_abc"}, "imw_abc"},
+		{"bearer", map[string]string{"authorization": "Bearer imw_abc"}, "imw_abc"},
+		{"bearer case", map[string]string{"Authorization": "bearer imw_abc"}, "imw_abc"},
+		{"basic", map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, ""},
+		{"header first", map[string]string{"X-API-Key": "imw_key", "Authorization": "Bearer imw_token"}, "imw_key"},
+	}
+	for _, test := range tests {
+		if got := requestAPIKey(HTTPRequest{Headers: test.headers}); got != test.want {
+			t.Errorf("%s: requestAPIKey = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAllowedConference(t *testing.T) {
+	tests := []struct {
+		name        string
+		conferences []int
+		conference  string
+		want        bool
+	}{
+		{"unscoped", nil, "41", true},
+		{"unscoped across conferences", nil, "", true},
+		{"scoped", []int{41, 42}, "42", true},
+		{"other conference", []int{41}, "42", false},
+		{"scoped across conferences", []int{41}, "", false},
+	}
+	for _, test := range tests {
+		if got := allowedConference(MongoAPIKey{Conferences: test.conferences}, test.conference); got != test.want {
+			t.Errorf("%s: allowedConference = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestHashAPIKey(t *testing.T) {
+	hash := hashAPIKey("imw_abc")
+	if len(hash) != 64 || hash == hashAPIKey("imw_abd") || hash != hashAPIKey("imw_abc") {
+		t.Errorf("unexpected hash %q", hash)
+	}
+}
+
+func TestRateLimitFromEnv(t *testing.T) {
+	t.Setenv("TEST_RATE_LIMIT", "")
+	if got := rateLimitFromEnv("TEST_RATE_LIMIT", 60); got != 60 {
+		t.Errorf("unset rateLimitFromEnv = %d, want 60", got)
+	}
+	t.Setenv("TEST_RATE_LIMIT", "0")
+	if got := rateLimitFromEnv("TEST_RATE_LIMIT", 60); got != 0 {
+		t.Errorf("rateLimitFromEnv = %d, want 0", got)
+	}
+	t.Setenv("TEST_RATE_LIMIT", "many")
+	if got := rateLimitFromEnv("TEST_RATE_LIMIT", 60); got != 60 {
+		t.Errorf("invalid rateLimitFromEnv = %d, want 60", got)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	response := errorResponse(http.StatusTooManyRequests, "rate limit exceeded")
+	if response.StatusCode != 429 || response.Body != `{"error":"rate limit exceeded"}` || response.Headers["Content-Type"] != "application/json" {
+		t.Errorf("unexpected response %+v", response)
+	}
+}
+
+func TestAuthorized(t *testing.T) {
+	scoped := MongoAPIKey{ID: hashAPIKey("imw_scoped"), Conferences: []int{58}, RateLimit: 5}
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		count      int
+		statusCode int
+		remaining  string
+	}{
+		{"anonymous", nil, 1, 0, "59"},
+		{"unknown key", map[string]string{"X-API-Key": "imw_unknown"}, 1, 401, ""},
+		{"other conference", map[string]string{"X-API-Key": "imw_scoped"}, 1, 403, ""},
+		{"rate limited", nil, 61, 429, ""},
+	}
+	for _, test := range tests {
+		fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+			if collection == "api_keys" && test.headers["X-API-Key"] == "imw_scoped" {
+				return []interface{}{scoped}
+			}
+			if collection == "rate_limits" {
+				return []interface{}{bson.D{{"count", test.count}}}
+			}
+			return nil
+		})
+		handled := false
+		response, err := authorized(HTTPRequest{Headers: test.headers}, "41", func() (*Response, error) {
+			handled = true
+			return &Response{Body: "[]"}, nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err.Error())
+		}
+		if response.StatusCode != test.statusCode || handled != (test.statusCode == 0) {
+			t.Errorf("%s: status %d, handled %v, want %d", test.name, response.StatusCode, handled, test.statusCode)
+		}
+		if got := response.Headers["X-RateLimit-Remaining"]; got != test.remaining {
+			t.Errorf("%s: X-RateLimit-Remaining = %q, want %q", test.name, got, test.remaining)
+		}
+		if retryAfter := response.Headers["Retry-After"]; (retryAfter != "") != (test.statusCode == 429) {
+			t.Errorf("%s: Retry-After = %q", test.name, retryAfter)
+		}
+	}
+}
+
+func TestScopedAuthorized(t *testing.T) {
+	scoped := MongoAPIKey{ID: hashAPIKey("imw_scoped"), Conferences: []int{58}}
+	fakeMongo(t, func(command string, collection string, body bson.Raw) []interface{} {
+		if collection == "api_keys" {
+			return []interface{}{scoped}
+		}
+		return nil
+	})
+	var got MongoAPIKey
+	response, err := scopedAuthorized(HTTPRequest{Headers: map[string]string{"X-API-Key": "imw_scoped"}}, func(key MongoAPIKey) (*Response, error) {
+		got = key
+		return &Response{Body: "[]"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != 0 || !reflect.DeepEqual(got.Conferences, scoped.Conferences) {
+		t.Errorf("scopedAuthorized = %+v with key %+v, want the handler to get the scoped key", response, got)
+	}
+}
+
+func TestScopeFilter(t *testing.T) {
+	if got := scopeFilter(MongoAPIKey{}, "conferenceId"); len(got) != 0 {
+		t.Errorf("scopeFilter of an unscoped key = %v, want it empty", got)
+	}
+	want := bson.D{{"_id", bson.D{{"$in", []int{41, 58}}}}}
+	if got := scopeFilter(MongoAPIKey{Conferences: []int{41, 58}}, "_id"); !reflect.DeepEqual(got, want) {
+		t.Errorf("scopeFilter = %v, want %v", got, want)
+	}
+}
+
+// mongoReply answers a command sent to fakeMongo with the documents of its
+// result: the batch of a find or aggregate, or the document a findAndModify
+// returns. The command is the name of the command, such as find, and body is
+// all of it, with the filter or pipeline.
+type mongoReply func(command string, collection string, body bson.Raw) []interface{}
+
+// fakeMongo serves enough of the MongoDB wire protocol for a web function to
+// run against it, and points MONGO_AUTH at it for the rest of the test.
+// Writes succeed without storing anything, and the rate limit counter is 1
+// unless reply returns another.
+func fakeMongo(t *testing.T, reply mongoReply) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveMongo(conn, reply)
+		}
+	}()
+	t.Setenv("MONGO_AUTH", "mongodb://"+listener.Addr().String()+"/?directConnection=true")
+	// The rate limits are the defaults, so responses don't depend on the
+	// environment the tests run in
+	t.Setenv("ANONYMOUS_RATE_LIMIT", "")
+	t.Setenv("API_KEY_RATE_LIMIT", "")
+}
+
+const (
+	opReply = 1
+	opQuery = 2004
+	opMsg   = 2013
+)
+
+// serveMongo answers the messages of one connection: the legacy OP_QUERY
+// the driver opens it with, then OP_MSG commands
+func serveMongo(conn net.Conn, reply mongoReply) {
+	defer conn.Close()
+	for {
+		header := make([]byte, 16)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		message := make([]byte, binary.LittleEndian.Uint32(header)-16)
+		if _, err := io.ReadFull(conn, message); err != nil {
+			return
+		}
+		requestId := binary.LittleEndian.Uint32(header[4:])
+		var response []byte
+		switch binary.LittleEndian.Uint32(header[12:]) {
+		case opQuery:
+			// The flags, collection name, number to skip and number to return
+			// come before the command
+			query := message[4:]
+			query = query[bytes.IndexByte(query, 0)+9:]
+			command := wireDocument(query)
+			if wrapped, ok := command.Lookup("$query").DocumentOK(); ok {
+				command = wrapped
+			}
+			document, _ := bson.Marshal(mongoCommand(command, reply))
+			// One document is returned, after the flags, cursor id and
+			// starting point
+			response = append(make([]byte, 32), 1, 0, 0, 0)
+			response = append(response, document...)
+			response = wireMessage(response, requestId, opReply)
+		case opMsg:
+			document, _ := bson.Marshal(mongoCommand(msgCommand(message), reply))
+			response = append(make([]byte, 21), document...)
+			response = wireMessage(response, requestId, opMsg)
+		default:
+			return
+		}
+		if _, err := conn.Write(response); err != nil {
+			return
+		}
+	}
+}
+
+// wireDocument is the BSON document at the start of data
+func wireDocument(data []byte) bson.Raw {
+	return bson.Raw(data[:binary.LittleEndian.Uint32(data)])
+}
+
+// wireMessage fills in the header at the start of a message
+func wireMessage(message []byte, responseTo uint32, opCode uint32) []byte {
+	binary.LittleEndian.PutUint32(message, uint32(len(message)))
+	binary.LittleEndian.PutUint32(message[8:], responseTo)
+	binary.LittleEndian.PutUint32(message[12:], opCode)
+	return message
+}
+
+// msgCommand is the command of an OP_MSG, with the documents of any
+// document sequences, such as those of an insert, added as arrays
+func msgCommand(message []byte) bson.Raw {
+	var command bson.D
+	sections := message[4:]
+	for len(sections) > 0 {
+		kind := sections[0]
+		sections = sections[1:]
+		if kind == 0 {
+			document := wireDocument(sections)
+			_ = bson.Unmarshal(document, &command)
+			sections = sections[len(document):]
+			continue
+		}
+		size := binary.LittleEndian.Uint32(sections)
+		sequence := sections[4:size]
+		name := string(sequence[:bytes.IndexByte(sequence, 0)])
+		sequence = sequence[len(name)+1:]
+		documents := bson.A{}
+		for len(sequence) > 0 {
+			document := wireDocument(sequence)
+			documents = append(documents, document)
+			sequence = sequence[len(document):]
+		}
+		command = append(command, bson.E{name, documents})
+		sections = sections[size:]
+	}
+	raw, _ := bson.Marshal(command)
+	return raw
+}
+
+func mongoCommand(command bson.Raw, reply mongoReply) bson.D {
+	name := command.Index(0).Key()
+	collection, _ := command.Index(0).Value().StringValueOK()
+	switch name {
+	case "hello", "isMaster", "ismaster":
+		return bson.D{
+			{"helloOk", true},
+			{"ismaster", true},
+			{"isWritablePrimary", true},
+			{"maxBsonObjectSize", 16 * 1024 * 1024},
+			{"maxMessageSizeBytes", 48000000},
+			{"maxWriteBatchSize", 100000},
+			{"localTime", time.Now()},
+			{"minWireVersion", 0},
+			{"maxWireVersion", 17},
+			{"ok", 1},
+		}
+	case "find", "aggregate":
+		batch := bson.A{}
+		for _, document := range reply(name, collection, command) {
+			batch = append(batch, document)
+		}
+		cursor := bson.D{{"id", int64(0)}, {"ns", "author-title." + collection}, {"firstBatch", batch}}
+		return bson.D{{"cursor", cursor}, {"ok", 1}}
+	case "findAndModify":
+		documents := reply(name, collection, command)
+		if len(documents) == 0 && collection == "rate_limits" {
+			documents = []interface{}{bson.D{{"count", 1}}}
+		}
+		var value interface{}
+		if len(documents) > 0 {
+			value = documents[0]
+		}
+		return bson.D{{"lastErrorObject", bson.D{{"n", 1}, {"updatedExisting", value != nil}}}, {"value", value}, {"ok", 1}}
+	case "insert", "update", "delete":
+		return bson.D{{"n", 1}, {"nModified", 1}, {"ok", 1}}
+	case "createIndexes", "endSessions", "killCursors", "ping":
+		return bson.D{{"ok", 1}}
+	}
+	return bson.D{{"ok", 0}, {"errmsg", "the fake MongoDB does not support " + name}, {"code", 59}}
+}
+
+// checkResponse compares a response with the fixture at path, which the
+// client checks against openapi.yaml, or rewrites the fixture when go test is
+// run with -update. A JSON body is written as JSON rather than as a string,
+// so the fixture can be read.
+func checkResponse(t *testing.T, path string, response *Response) {
+	t.Helper()
+	if response == nil {
+		t.Fatalf("no response for %s", path)
+	}
+	body, _ := json.Marshal(response.Body)
+	mediaType, _, _ := mime.ParseMediaType(response.Headers["Content-Type"])
+	if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+		if !json.Valid([]byte(response.Body)) {
+			t.Fatalf("the %s body for %s is not JSON: %s", mediaType, path, response.Body)
+		}
+		body = []byte(response.Body)
+	}
+	envelope, err := json.Marshal(struct {
+		StatusCode int               `json:"statusCode"`
+		Headers    map[string]string `json:"headers"`
+		Body       json.RawMessage   `json:"body"`
+	}{response.StatusCode, response.Headers, body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, envelope, "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	indented.WriteByte('\n')
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, indented.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	fixture, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fixture, indented.Bytes()) {
+		t.Errorf("%s is out of date, run go test -update\ngot:\n%s", path, indented.String())
+	}
+}
@@ -0,0 +1,43 @@
+//go:build ignore
+
+// copy writes the shared sources into the functions which use them
+package main
+
+import (
+	"fmt"
+	"indico-middleware/shared"
+	"os"
+)
+
+func main() {
+	if err := copySources(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+func copySources() error {
+	sources, err := shared.Sources()
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return err
+		}
+		targets, err := shared.Targets(source)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("no function uses %s", source)
+		}
+		for _, target := range targets {
+			if err := os.WriteFile(target, shared.Generated(source, content), 0644); err != nil {
+				return fmt.Errorf("error writing %s: %s", target, err.Error())
+			}
+		}
+	}
+	return nil
+}
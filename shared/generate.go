@@ -0,0 +1,70 @@
+// Package shared holds the sources which several functions need. Each
+// function is built on its own, so go generate copies them into the
+// functions instead of them being imported:
+//
+//	cd shared && go generate
+//
+// A source is copied into every function which has a file of the same name,
+// and its tests into the same functions. The sources are tagged ignore, so
+// they are only built as part of the functions.
+package shared
+
+//go:generate go run copy.go
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const ignoreTag = "//go:build ignore\n\n"
+
+// FunctionsDir is packages/indico, relative to shared
+var FunctionsDir = filepath.Join("..", "packages", "indico")
+
+// Sources are the shared files, which start with the ignore tag
+func Sources() ([]string, error) {
+	names, err := filepath.Glob("*.go")
+	if err != nil {
+		return nil, err
+	}
+	var sources []string
+	for _, name := range names {
+		if name == "copy.go" {
+			continue
+		}
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(content, []byte(ignoreTag)) {
+			sources = append(sources, name)
+		}
+	}
+	return sources, nil
+}
+
+// Targets are the copies of a source, in the functions which have a file
+// named like it, or like the file it tests
+func Targets(source string) ([]string, error) {
+	base := strings.TrimSuffix(source, "_test.go")
+	if base != source {
+		base += ".go"
+	}
+	functions, err := filepath.Glob(filepath.Join(FunctionsDir, "*", base))
+	if err != nil {
+		return nil, err
+	}
+	var targets []string
+	for _, function := range functions {
+		targets = append(targets, filepath.Join(filepath.Dir(function), source))
+	}
+	return targets, nil
+}
+
+// Generated is the content of a copy of a source, marked as generated
+func Generated(source string, content []byte) []byte {
+	header := "// Code generated by go generate in shared from " + source + ". DO NOT EDIT.\n\n"
+	return append([]byte(header), bytes.TrimPrefix(content, []byte(ignoreTag))...)
+}
@@ -0,0 +1,295 @@
+//go:build ignore
+
+package main
+
+// generator.go is copied into find and graphql by go generate in shared, edit
+// it there. It builds the title and author block of a paper, which find
+// returns and graphql has as the generator field of a contribution.
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"sort"
+	"strings"
+)
+
+// MongoAuthorIdentity is the part of an author_index entry linking a person
+// of a contribution to their resolved identity
+type MongoAuthorIdentity struct {
+	PersonID  int    `bson:"personId"`
+	FirstName string `bson:"firstName"`
+	LastName  string `bson:"lastName"`
+	AuthorID  string `bson:"authorId"`
+	ORCID     string `bson:"orcid"`
+}
+
+type GeneratorOrganisation struct {
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	Zipcode  string `json:"zipcode"`
+	ROR      string `json:"ror,omitempty"`
+}
+
+type GeneratorAuthor struct {
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	Affiliations []int  `json:"affiliations"`
+	AuthorID     string `json:"author_id,omitempty"`
+	ORCID        string `json:"orcid,omitempty"`
+}
+
+type GeneratorPayload struct {
+	Title         string                        `json:"title"`
+	Authors       map[int]GeneratorAuthor       `json:"authors"`
+	Organisations map[int]GeneratorOrganisation `json:"organisations"`
+	FundingAgency string                        `json:"funding_agency,omitempty"`
+	Footnotes     string                        `json:"footnotes,omitempty"`
+}
+
+func getAuthorsAndOrganisations(mongoPersons []MongoPerson, persons []MongoDetailedPerson, identities map[int]MongoAuthorIdentity, affiliations map[int]MongoAffiliation, conference MongoConference) (map[int]GeneratorAuthor, map[int]GeneratorOrganisation) {
+
+	authors := make(map[int]GeneratorAuthor)
+	uniqueOrganisations := make(map[int]GeneratorOrganisation)
+	positions := make(map[string]int)
+	organisationCount := 0
+	for index, mongoPerson := range mongoPersons {
+		linkId := persons[index].AffiliationLink.ID
+		organisation := "name:" + affiliationKey(mongoPerson.Affiliation)
+		if affiliation, found := lookupAffiliation(mongoPerson.Affiliation, linkId, affiliations); found {
+			organisation = fmt.Sprintf("id:%d", affiliation.ID)
+		}
+		position, ok := positions[organisation]
+		if !ok {
+			uniqueOrganisations[organisationCount] = findAffiliationDetails(mongoPerson.Affiliation, linkId, affiliations, conference)
+			position = organisationCount
+			positions[organisation] = position
+			organisationCount++
+		}
+
+		var affiliations []int
+		affiliations = append(affiliations, position)
+
+		author := GeneratorAuthor{
+			FirstName:    mongoPerson.FirstName,
+			LastName:     mongoPerson.FamilyName,
+			Affiliations: affiliations,
+		}
+		if identity, found := identities[persons[index].ID]; found && persons[index].ID != 0 {
+			author.AuthorID = identity.AuthorID
+			author.ORCID = identity.ORCID
+		}
+
+		if _, ok := authors[mongoPerson.DisplayOrder]; ok {
+			for {
+				if _, ok := authors[mongoPerson.DisplayOrder+1]; ok {
+					mongoPerson.DisplayOrder++
+				} else {
+					break
+				}
+			}
+			authors[mongoPerson.DisplayOrder] = author
+		} else {
+			authors[mongoPerson.DisplayOrder] = author
+		}
+	}
+
+	return authors, uniqueOrganisations
+}
+
+func affiliationKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// linkedAffiliations are the affiliations linked by the persons of a
+// contribution, keyed by their indico id, as stored with the person. They
+// are the fallback for affiliations not yet in the registry.
+func linkedAffiliations(allPersons []MongoDetailedPerson) map[int]MongoAffiliation {
+	affiliations := make(map[int]MongoAffiliation)
+	for _, person := range allPersons {
+		link := person.AffiliationLink
+		if link.ID == 0 {
+			continue
+		}
+		affiliations[link.ID] = MongoAffiliation{
+			ID:            link.ID,
+			Name:          link.Name,
+			City:          link.City,
+			CountryName:   link.CountryName,
+			CountryCode:   link.CountryCode,
+			Postcode:      link.Postcode,
+			CanonicalName: link.Name,
+		}
+	}
+	return affiliations
+}
+
+// registeredAffiliationsFilter finds the registry entries of linked
+// affiliations
+func registeredAffiliationsFilter(affiliations map[int]MongoAffiliation) bson.D {
+	ids := make([]int, 0, len(affiliations))
+	for id := range affiliations {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return bson.D{{"_id", bson.D{{"$in", ids}}}}
+}
+
+// addRegisteredAffiliations replaces linked affiliations by their registry
+// entries
+func addRegisteredAffiliations(affiliations map[int]MongoAffiliation, registered []MongoAffiliation) {
+	for _, affiliation := range registered {
+		if affiliation.CanonicalName == "" {
+			affiliation.CanonicalName = affiliation.Name
+		}
+		affiliations[affiliation.ID] = affiliation
+	}
+}
+
+// identitiesFilter finds the author_index entries of the persons of a
+// contribution which have a resolved identity
+func identitiesFilter(contribution MongoContribution) bson.D {
+	return bson.D{
+		{"conferenceId", contribution.ConferenceId},
+		{"contributionId", contribution.ID},
+		{"personId", bson.D{{"$exists", true}}},
+		{"authorId", bson.D{{"$exists", true}}},
+	}
+}
+
+// identitiesByPerson keys author_index entries by their indico person id
+func identitiesByPerson(entries []MongoAuthorIdentity) map[int]MongoAuthorIdentity {
+	identities := make(map[int]MongoAuthorIdentity)
+	for _, entry := range entries {
+		identities[entry.PersonID] = entry
+	}
+	return identities
+}
+
+// lookupAffiliation finds an affiliation by the indico id it is linked to.
+// Only a person without a link is matched on the name, canonical name or
+// aliases of the affiliations of the contribution, trying them in id order
+// so a name shared by two of them always gives the same one.
+func lookupAffiliation(name string, linkId int, affiliations map[int]MongoAffiliation) (MongoAffiliation, bool) {
+	if linkId != 0 {
+		affiliation, ok := affiliations[linkId]
+		return affiliation, ok
+	}
+	var ids []int
+	for id := range affiliations {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if affiliationNames(affiliations[id])[affiliationKey(name)] {
+			return affiliations[id], true
+		}
+	}
+	return MongoAffiliation{}, false
+}
+
+func findAffiliationDetails(name string, linkId int, affiliations map[int]MongoAffiliation, conference MongoConference) GeneratorOrganisation {
+	if affiliation, ok := lookupAffiliation(name, linkId, affiliations); ok {
+		return GeneratorOrganisation{
+			Name:     affiliation.CanonicalName,
+			Location: formatLocation(affiliation, conference),
+			Zipcode:  affiliation.Postcode,
+			ROR:      affiliation.ROR,
+		}
+	}
+	return GeneratorOrganisation{
+		Name:     name,
+		Location: "",
+		Zipcode:  "",
+	}
+}
+
+// affiliationNames are the names an affiliation is known by, as keys
+func affiliationNames(affiliation MongoAffiliation) map[string]bool {
+	names := map[string]bool{
+		affiliationKey(affiliation.Name):          true,
+		affiliationKey(affiliation.CanonicalName): true,
+	}
+	for _, alias := range affiliation.Aliases {
+		names[affiliationKey(alias)] = true
+	}
+	delete(names, "")
+	return names
+}
+
+func personName(firstName string, lastName string) string {
+	return strings.ToLower(strings.Join(strings.Fields(firstName+" "+lastName), " "))
+}
+
+// authorPersonIDs finds the indico person id of each timetable person, which
+// only has a name and display order. Co-authors who share a name are told
+// apart by display order: the first of them is the first detailed person with
+// that name. A presenter listed again as an author has the same display order,
+// so is the same person.
+func authorPersonIDs(mongoPersons []MongoPerson, persons []MongoDetailedPerson) []int {
+	detailed := make(map[string][]int)
+	for _, person := range persons {
+		name := personName(person.FirstName, person.LastName)
+		detailed[name] = append(detailed[name], person.ID)
+	}
+	orders := make(map[string][]int)
+	for _, mongoPerson := range mongoPersons {
+		name := personName(mongoPerson.FirstName, mongoPerson.FamilyName)
+		found := false
+		for _, order := range orders[name] {
+			found = found || order == mongoPerson.DisplayOrder
+		}
+		if !found {
+			orders[name] = append(orders[name], mongoPerson.DisplayOrder)
+		}
+	}
+	for _, list := range orders {
+		sort.Ints(list)
+	}
+	ids := make([]int, len(mongoPersons))
+	for index, mongoPerson := range mongoPersons {
+		name := personName(mongoPerson.FirstName, mongoPerson.FamilyName)
+		for occurrence, order := range orders[name] {
+			if order == mongoPerson.DisplayOrder && occurrence < len(detailed[name]) {
+				ids[index] = detailed[name][occurrence]
+			}
+		}
+	}
+	return ids
+}
+
+// mongoToGeneratorPayload orders the timetable presenters and authors by
+// display order and numbers their organisations in order of first appearance
+func mongoToGeneratorPayload(contribution MongoContribution, conference MongoConference, affiliations map[int]MongoAffiliation, identities map[int]MongoAuthorIdentity) GeneratorPayload {
+	var mongoPersons []MongoPerson
+	if contribution.Presenters != nil {
+		mongoPersons = append(mongoPersons, *contribution.Presenters...)
+	}
+	if contribution.Authors != nil {
+		mongoPersons = append(mongoPersons, *contribution.Authors...)
+	}
+	for i := 0; i < len(mongoPersons); i++ {
+		for j := 0; j < len(mongoPersons)-1; j++ {
+			if mongoPersons[j].DisplayOrder > mongoPersons[j+1].DisplayOrder {
+				mongoPersons[j], mongoPersons[j+1] = mongoPersons[j+1], mongoPersons[j]
+			}
+		}
+	}
+	// The detailed person each timetable person is, for their affiliation link
+	// and identity
+	persons := make([]MongoDetailedPerson, len(mongoPersons))
+	for index, personId := range authorPersonIDs(mongoPersons, contribution.Persons) {
+		for _, person := range contribution.Persons {
+			if personId != 0 && person.ID == personId {
+				persons[index] = person
+			}
+		}
+	}
+	authors, uniqueOrganisations := getAuthorsAndOrganisations(mongoPersons, persons, identities, affiliations, conference)
+	return GeneratorPayload{
+		Title:         contribution.Title,
+		Authors:       authors,
+		Organisations: uniqueOrganisations,
+		FundingAgency: contribution.FundingAgency,
+		Footnotes:     contribution.Footnotes,
+	}
+}
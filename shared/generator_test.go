@@ -0,0 +1,159 @@
+//go:build ignore
+
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+)
+
+func generatorAffiliations() map[int]MongoAffiliation {
+	return map[int]MongoAffiliation{
+		1: {
+			ID:            1,
+			Name:          "ANSTO",
+			City:          "Clayton",
+			CountryName:   "Australia",
+			CountryCode:   "AU",
+			Postcode:      "3168",
+			CanonicalName: "Australian Nuclear Science and Technology Organisation",
+			ROR:           "https://ror.org/05j7fep28",
+		},
+	}
+}
+
+func TestMongoToGeneratorPayload(t *testing.T) {
+	contribution := MongoContribution{
+		Title:      "Beam loss monitors",
+		Presenters: &[]MongoPerson{{FirstName: "Ada", FamilyName: "Lovelace", Affiliation: "ANSTO", DisplayOrder: 2}},
+		Authors: &[]MongoPerson{
+			{FirstName: "Jean", FamilyName: "Dupont", Affiliation: "Unlisted Laboratory", DisplayOrder: 3},
+			{FirstName: "Li", FamilyName: "Wei", Affiliation: "ANSTO", DisplayOrder: 1},
+		},
+		Persons: []MongoDetailedPerson{
+			{ID: 11, FirstName: "Ada", LastName: "Lovelace", AffiliationLink: MongoAffiliationLink{ID: 1, Name: "ANSTO"}},
+		},
+		FundingAgency: "DOE",
+	}
+	want := GeneratorPayload{
+		Title: "Beam loss monitors",
+		Authors: map[int]GeneratorAuthor{
+			1: {FirstName: "Li", LastName: "Wei", Affiliations: []int{0}},
+			2: {FirstName: "Ada", LastName: "Lovelace", Affiliations: []int{0}, AuthorID: "a1b2"},
+			3: {FirstName: "Jean", LastName: "Dupont", Affiliations: []int{1}},
+		},
+		Organisations: map[int]GeneratorOrganisation{
+			0: {
+				Name:     "Australian Nuclear Science and Technology Organisation",
+				Location: "Clayton, Australia",
+				Zipcode:  "3168",
+				ROR:      "https://ror.org/05j7fep28",
+			},
+			1: {Name: "Unlisted Laboratory"},
+		},
+		FundingAgency: "DOE",
+	}
+	identities := map[int]MongoAuthorIdentity{11: {PersonID: 11, AuthorID: "a1b2"}}
+	if got := mongoToGeneratorPayload(contribution, MongoConference{ID: 41}, generatorAffiliations(), identities); !reflect.DeepEqual(got, want) {
+		t.Errorf("mongoToGeneratorPayload =\n%+v\nwant\n%+v", got, want)
+	}
+
+	empty := mongoToGeneratorPayload(MongoContribution{Title: "Untitled"}, MongoConference{ID: 41}, nil, nil)
+	if len(empty.Authors) != 0 || len(empty.Organisations) != 0 {
+		t.Errorf("payload of a contribution without authors = %+v, want no authors", empty)
+	}
+}
+
+func TestMongoToGeneratorPayloadIdentities(t *testing.T) {
+	contribution := MongoContribution{
+		Presenters: &[]MongoPerson{},
+		Authors: &[]MongoPerson{
+			{FirstName: "Wei", FamilyName: "Zhang", DisplayOrder: 2},
+			{FirstName: "Wei", FamilyName: "Zhang", DisplayOrder: 1},
+		},
+		Persons: []MongoDetailedPerson{
+			{ID: 21, FirstName: "Wei", LastName: "Zhang"},
+			{ID: 23, FirstName: "Wei", LastName: "Zhang"},
+		},
+	}
+	identities := map[int]MongoAuthorIdentity{
+		21: {PersonID: 21, AuthorID: "first", ORCID: "0000-0002-1825-0097"},
+		23: {PersonID: 23, AuthorID: "second"},
+	}
+	payload := mongoToGeneratorPayload(contribution, MongoConference{}, nil, identities)
+	if got := payload.Authors[1]; got.AuthorID != "first" || got.ORCID != "0000-0002-1825-0097" {
+		t.Errorf("first author = %+v, want the identity of person 21", got)
+	}
+	if got := payload.Authors[2]; got.AuthorID != "second" || got.ORCID != "" {
+		t.Errorf("second author = %+v, want the identity of person 23", got)
+	}
+}
+
+func TestAuthorPersonIDs(t *testing.T) {
+	mongoPersons := []MongoPerson{
+		{FirstName: "Wei", FamilyName: "Zhang", DisplayOrder: 1},
+		{FirstName: "Wei", FamilyName: "Zhang", DisplayOrder: 1},
+		{FirstName: "Ada", FamilyName: "Lovelace", DisplayOrder: 2},
+		{FirstName: "wei", FamilyName: " Zhang", DisplayOrder: 3},
+		{FirstName: "Jean", FamilyName: "Dupont", DisplayOrder: 4},
+	}
+	persons := []MongoDetailedPerson{
+		{ID: 21, FirstName: "Wei", LastName: "Zhang"},
+		{ID: 22, FirstName: "Ada", LastName: "Lovelace"},
+		{ID: 23, FirstName: "Wei", LastName: "Zhang"},
+	}
+	want := []int{21, 21, 22, 23, 0}
+	if got := authorPersonIDs(mongoPersons, persons); !reflect.DeepEqual(got, want) {
+		t.Errorf("authorPersonIDs = %v, want %v", got, want)
+	}
+}
+
+func TestFindAffiliationDetails(t *testing.T) {
+	conference := MongoConference{ID: 41}
+	affiliations := generatorAffiliations()
+	affiliations[2] = MongoAffiliation{ID: 2, Name: "ANSTO Sydney", CanonicalName: "ANSTO Sydney", Aliases: []string{"ANSTO"}}
+	ansto := GeneratorOrganisation{
+		Name:     "Australian Nuclear Science and Technology Organisation",
+		Location: "Clayton, Australia",
+		Zipcode:  "3168",
+		ROR:      "https://ror.org/05j7fep28",
+	}
+	tests := []struct {
+		name   string
+		linkId int
+		want   GeneratorOrganisation
+	}{
+		{" ansto ", 0, ansto},
+		{"Unlisted Laboratory", 0, GeneratorOrganisation{Name: "Unlisted Laboratory"}},
+		{"ANSTO", 2, GeneratorOrganisation{Name: "ANSTO Sydney"}},
+		{"Old name of ANSTO", 1, ansto},
+		{"ANSTO", 9, GeneratorOrganisation{Name: "ANSTO"}},
+	}
+	for _, test := range tests {
+		if got := findAffiliationDetails(test.name, test.linkId, affiliations, conference); got != test.want {
+			t.Errorf("findAffiliationDetails(%q, %d) = %+v, want %+v", test.name, test.linkId, got, test.want)
+		}
+	}
+}
+
+func TestRegisteredAffiliations(t *testing.T) {
+	persons := []MongoDetailedPerson{
+		{ID: 11, AffiliationLink: MongoAffiliationLink{ID: 4, Name: "PSI", City: "Villigen"}},
+		{ID: 12},
+		{ID: 13, AffiliationLink: MongoAffiliationLink{ID: 1, Name: "ANSTO"}},
+	}
+	affiliations := linkedAffiliations(persons)
+	want := bson.D{{"_id", bson.D{{"$in", []int{1, 4}}}}}
+	if got := registeredAffiliationsFilter(affiliations); !reflect.DeepEqual(got, want) {
+		t.Errorf("registeredAffiliationsFilter = %v, want %v", got, want)
+	}
+	addRegisteredAffiliations(affiliations, []MongoAffiliation{{ID: 1, Name: "ANSTO", ROR: "https://ror.org/05j7fep28"}})
+	wantAffiliations := map[int]MongoAffiliation{
+		1: {ID: 1, Name: "ANSTO", CanonicalName: "ANSTO", ROR: "https://ror.org/05j7fep28"},
+		4: {ID: 4, Name: "PSI", City: "Villigen", CanonicalName: "PSI"},
+	}
+	if !reflect.DeepEqual(affiliations, wantAffiliations) {
+		t.Errorf("affiliations = %+v, want %+v", affiliations, wantAffiliations)
+	}
+}
@@ -0,0 +1,3 @@
+module indico-middleware/shared
+
+go 1.20
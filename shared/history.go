@@ -0,0 +1,45 @@
+//go:build ignore
+
+package main
+
+// history.go is copied into timetables and contributions by go generate in
+// shared, edit it there
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+type MongoFieldChange struct {
+	Field string      `bson:"field"`
+	Old   interface{} `bson:"old"`
+	New   interface{} `bson:"new"`
+}
+
+type MongoContributionHistory struct {
+	ContributionID int                `bson:"contributionId"`
+	ConferenceId   int                `bson:"conferenceId"`
+	Code           string             `bson:"code"`
+	Job            string             `bson:"job"`
+	SyncedAt       time.Time          `bson:"syncedAt"`
+	Changes        []MongoFieldChange `bson:"changes"`
+}
+
+// insertHistory records the changes a sync made to contributions in the
+// contribution_history collection
+func insertHistory(database *mongo.Database, history []MongoContributionHistory) error {
+	if len(history) == 0 {
+		return nil
+	}
+	var historyDocuments []interface{}
+	for _, change := range history {
+		historyDocuments = append(historyDocuments, change)
+	}
+	historyCollection := database.Collection("contribution_history")
+	if _, err := historyCollection.InsertMany(context.Background(), historyDocuments); err != nil {
+		return fmt.Errorf("error inserting contribution history: %s", err.Error())
+	}
+	return nil
+}
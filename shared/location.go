@@ -0,0 +1,64 @@
+//go:build ignore
+
+package main
+
+// location.go is copied into find and graphql by go generate in shared, edit
+// it there
+
+import (
+	"strings"
+)
+
+const defaultAffiliationFormat = "{city}, {country}"
+
+// jacowCountryNames are the short country names used in JACoW proceedings,
+// by ISO country code, where they differ from the names indico uses
+var jacowCountryNames = map[string]string{
+	"CZ": "Czech Republic",
+	"GB": "UK",
+	"IR": "Iran",
+	"KR": "Korea",
+	"RU": "Russia",
+	"TW": "Taiwan",
+	"US": "USA",
+	"VN": "Vietnam",
+}
+
+// countryName prefers the conference's own name for a country, then the
+// JACoW name, then indico's
+func countryName(affiliation MongoAffiliation, conference MongoConference) string {
+	code := strings.ToUpper(strings.TrimSpace(affiliation.CountryCode))
+	if name, ok := conference.CountryNames[code]; ok {
+		return name
+	}
+	if name, ok := jacowCountryNames[code]; ok {
+		return name
+	}
+	return strings.TrimSpace(affiliation.CountryName)
+}
+
+// formatLocation fills in the {city}, {postcode}, {country} and
+// {country_code} placeholders of the conference's affiliation format. Each
+// comma separated part of the format is left out when all of its
+// placeholders are empty, so a missing city gives "Switzerland" rather
+// than ", Switzerland".
+func formatLocation(affiliation MongoAffiliation, conference MongoConference) string {
+	format := conference.AffiliationFormat
+	if format == "" {
+		format = defaultAffiliationFormat
+	}
+	replacer := strings.NewReplacer(
+		"{city}", strings.TrimSpace(affiliation.City),
+		"{postcode}", strings.TrimSpace(affiliation.Postcode),
+		"{country}", countryName(affiliation, conference),
+		"{country_code}", strings.ToUpper(strings.TrimSpace(affiliation.CountryCode)),
+	)
+	var parts []string
+	for _, part := range strings.Split(format, ",") {
+		part = strings.Join(strings.Fields(replacer.Replace(part)), " ")
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,53 @@
+//go:build ignore
+
+package main
+
+import (
+	"testing"
+)
+
+func TestCountryName(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		indico    string
+		overrides map[string]string
+		want      string
+	}{
+		{"indico name", "CH", "Switzerland", nil, "Switzerland"},
+		{"JACoW name", "US", "United States of America", nil, "USA"},
+		{"lowercase code", " gb ", "United Kingdom", nil, "UK"},
+		{"conference name", "US", "United States of America", map[string]string{"US": "United States"}, "United States"},
+		{"conference name over indico", "CH", "Switzerland", map[string]string{"CH": "Schweiz"}, "Schweiz"},
+		{"no code", "", " Atlantis ", nil, "Atlantis"},
+	}
+	for _, test := range tests {
+		affiliation := MongoAffiliation{CountryCode: test.code, CountryName: test.indico}
+		if got := countryName(affiliation, MongoConference{CountryNames: test.overrides}); got != test.want {
+			t.Errorf("%s: countryName = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFormatLocation(t *testing.T) {
+	cern := MongoAffiliation{City: "Geneva", Postcode: "1211", CountryName: "Switzerland", CountryCode: "ch"}
+	tests := []struct {
+		name        string
+		affiliation MongoAffiliation
+		format      string
+		want        string
+	}{
+		{"default", cern, "", "Geneva, Switzerland"},
+		{"postcode", cern, "{postcode} {city}, {country}", "1211 Geneva, Switzerland"},
+		{"country code", cern, "{city}, {country_code}", "Geneva, CH"},
+		{"no city", MongoAffiliation{CountryName: "Switzerland"}, "", "Switzerland"},
+		{"no postcode", MongoAffiliation{City: " Geneva ", CountryName: "Switzerland"}, "{postcode} {city}, {country}", "Geneva, Switzerland"},
+		{"nothing", MongoAffiliation{}, "{postcode} {city}, {country}", ""},
+		{"literal text", cern, "{city} ({country_code})", "Geneva (CH)"},
+	}
+	for _, test := range tests {
+		if got := formatLocation(test.affiliation, MongoConference{AffiliationFormat: test.format}); got != test.want {
+			t.Errorf("%s: formatLocation = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+//go:build ignore
+
+package main
+
+// pii.go is copied into timetables and contributions by go generate in
+// shared, edit it there
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EMAIL_POLICY decides how the emails of persons are stored in MongoDB
+const (
+	emailPolicyNone      = "none"
+	emailPolicyHashed    = "hashed"
+	emailPolicyEncrypted = "encrypted"
+)
+
+const (
+	hashedEmailPrefix    = "hmac:"
+	encryptedEmailPrefix = "enc:"
+	// Emails hashed before EMAIL_HASH_KEY can't be matched any more
+	unkeyedEmailPrefix = "sha256:"
+)
+
+// emailPolicyFromEnv reads EMAIL_POLICY, which defaults to hashed so that
+// plaintext emails are never stored unless they are encrypted. Identities
+// keep the hashes of emails under both policies, so both need the hash key.
+func emailPolicyFromEnv() (string, error) {
+	policy := strings.ToLower(strings.TrimSpace(os.Getenv("EMAIL_POLICY")))
+	switch policy {
+	case "":
+		policy = emailPolicyHashed
+	case emailPolicyNone:
+		return policy, nil
+	case emailPolicyHashed:
+	case emailPolicyEncrypted:
+		if _, err := emailKeyFromEnv(); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unknown EMAIL_POLICY: %s", policy)
+	}
+	if _, err := emailHashKeyFromEnv(); err != nil {
+		return "", err
+	}
+	return policy, nil
+}
+
+// emailKeyFromEnv reads the base64 encoded 32 byte AES key in
+// EMAIL_ENCRYPTION_KEY
+func emailKeyFromEnv() ([]byte, error) {
+	encoded := os.Getenv("EMAIL_ENCRYPTION_KEY")
+	if encoded == "" {
+		return nil, errors.New("EMAIL_ENCRYPTION_KEY is required when EMAIL_POLICY is encrypted")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding EMAIL_ENCRYPTION_KEY: %s", err.Error())
+	}
+	if len(key) != 32 {
+		return nil, errors.New("EMAIL_ENCRYPTION_KEY must be 32 bytes")
+	}
+	return key, nil
+}
+
+// emailHashKeyFromEnv reads the base64 encoded secret of at least 32 bytes in
+// EMAIL_HASH_KEY
+func emailHashKeyFromEnv() ([]byte, error) {
+	encoded := os.Getenv("EMAIL_HASH_KEY")
+	if encoded == "" {
+		return nil, errors.New("EMAIL_HASH_KEY is required unless EMAIL_POLICY is none")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding EMAIL_HASH_KEY: %s", err.Error())
+	}
+	if len(key) < 32 {
+		return nil, errors.New("EMAIL_HASH_KEY must be at least 32 bytes")
+	}
+	return key, nil
+}
+
+func normaliseEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// hashEmail is the same for every policy, so hashed and decrypted emails can
+// be matched against each other. It is an HMAC rather than a plain hash, so
+// the emails can't be found by hashing a list of known addresses without the
+// key.
+func hashEmail(key []byte, email string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(normaliseEmail(email)))
+	return hashedEmailPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// encryptEmail uses AES-GCM with a nonce derived from the email, so the same
+// email always encrypts to the same value and syncs don't see a change
+func encryptEmail(key []byte, email string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("error creating cipher: %s", err.Error())
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", fmt.Errorf("error creating cipher: %s", err.Error())
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(email))
+	nonce := mac.Sum(nil)[:gcm.NonceSize()]
+	sealed := gcm.Seal(nonce, nonce, []byte(email), nil)
+	return encryptedEmailPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptEmail(key []byte, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedEmailPrefix))
+	if err != nil {
+		return "", fmt.Errorf("error decoding email: %s", err.Error())
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("error creating cipher: %s", err.Error())
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", fmt.Errorf("error creating cipher: %s", err.Error())
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("error decrypting email: too short")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting email: %s", err.Error())
+	}
+	return string(plain), nil
+}
+
+// protectEmail is the value stored for an email from Indico under
+// EMAIL_POLICY. The policy is checked when the sync starts, so any error here
+// drops the email rather than storing it in plaintext.
+func protectEmail(email string) string {
+	email = normaliseEmail(email)
+	if email == "" {
+		return ""
+	}
+	policy, err := emailPolicyFromEnv()
+	if err != nil {
+		return ""
+	}
+	switch policy {
+	case emailPolicyHashed:
+		key, _ := emailHashKeyFromEnv()
+		return hashEmail(key, email)
+	case emailPolicyEncrypted:
+		key, _ := emailKeyFromEnv()
+		encrypted, err := encryptEmail(key, email)
+		if err != nil {
+			return ""
+		}
+		return encrypted
+	}
+	return ""
+}
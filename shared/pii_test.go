@@ -0,0 +1,122 @@
+//go:build ignore
+
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+var (
+	testEmailKey     = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	testEmailHashKey = base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+)
+
+func TestEmailPolicyFromEnv(t *testing.T) {
+	short := base64.StdEncoding.EncodeToString([]byte("short"))
+	tests := []struct {
+		policy  string
+		key     string
+		hashKey string
+		want    string
+		err     bool
+	}{
+		{"", "", testEmailHashKey, emailPolicyHashed, false},
+		{"none", "", "", emailPolicyNone, false},
+		{" Hashed ", "", testEmailHashKey, emailPolicyHashed, false},
+		{"hashed", "", "", "", true},
+		{"hashed", "", "not base64!", "", true},
+		{"hashed", "", short, "", true},
+		{"encrypted", testEmailKey, testEmailHashKey, emailPolicyEncrypted, false},
+		{"encrypted", testEmailKey, "", "", true},
+		{"encrypted", "", testEmailHashKey, "", true},
+		{"encrypted", "not base64!", testEmailHashKey, "", true},
+		{"encrypted", short, testEmailHashKey, "", true},
+		{"plaintext", "", testEmailHashKey, "", true},
+	}
+	for _, test := range tests {
+		t.Setenv("EMAIL_POLICY", test.policy)
+		t.Setenv("EMAIL_ENCRYPTION_KEY", test.key)
+		t.Setenv("EMAIL_HASH_KEY", test.hashKey)
+		got, err := emailPolicyFromEnv()
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("EMAIL_POLICY=%q: got %q, %v", test.policy, got, err)
+		}
+	}
+}
+
+func TestProtectEmailHashed(t *testing.T) {
+	t.Setenv("EMAIL_POLICY", "hashed")
+	t.Setenv("EMAIL_HASH_KEY", testEmailHashKey)
+	want := "hmac:c14ec7d64a9a262a0fbb4a67f23fd9b826fe3ee8994c6fb50ef520311ee62a38"
+	if got := protectEmail(" Jane@Example.org "); got != want {
+		t.Errorf("protectEmail = %s, want the HMAC of the normalised email %s", got, want)
+	}
+	t.Setenv("EMAIL_HASH_KEY", testEmailKey)
+	if protectEmail("jane@example.org") == want {
+		t.Error("protectEmail is the same with another EMAIL_HASH_KEY")
+	}
+	t.Setenv("EMAIL_HASH_KEY", testEmailHashKey)
+	if protectEmail("jane@example.org") != protectEmail("JANE@example.org") {
+		t.Error("protectEmail is not deterministic")
+	}
+	if protectEmail("") != "" {
+		t.Error("an empty email was not left empty")
+	}
+}
+
+func TestProtectEmailEncrypted(t *testing.T) {
+	t.Setenv("EMAIL_POLICY", "encrypted")
+	t.Setenv("EMAIL_ENCRYPTION_KEY", testEmailKey)
+	t.Setenv("EMAIL_HASH_KEY", testEmailHashKey)
+	key, err := emailKeyFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"jane@example.org", "Ирина.Иванова@example.org", "a@b.c"} {
+		encrypted := protectEmail(email)
+		if !strings.HasPrefix(encrypted, encryptedEmailPrefix) || strings.Contains(encrypted, "@") {
+			t.Errorf("protectEmail(%q) = %s, want it encrypted", email, encrypted)
+			continue
+		}
+		if again := protectEmail(strings.ToUpper(email)); again != encrypted {
+			t.Errorf("protectEmail(%q) = %s then %s, want the same value", email, encrypted, again)
+		}
+		decrypted, err := decryptEmail(key, encrypted)
+		if err != nil {
+			t.Errorf("decryptEmail(%s): %s", encrypted, err.Error())
+		} else if decrypted != normaliseEmail(email) {
+			t.Errorf("decryptEmail(protectEmail(%q)) = %q", email, decrypted)
+		}
+	}
+	if protectEmail("jane@example.org") == protectEmail("john@example.org") {
+		t.Error("different emails encrypt to the same value")
+	}
+
+	otherKey := []byte("fedcba9876543210fedcba9876543210")
+	if _, err := decryptEmail(otherKey, protectEmail("jane@example.org")); err == nil {
+		t.Error("an email decrypted with the wrong key")
+	}
+	if _, err := decryptEmail(key, encryptedEmailPrefix+"AAAA"); err == nil {
+		t.Error("a truncated email decrypted")
+	}
+}
+
+func TestProtectEmailNone(t *testing.T) {
+	t.Setenv("EMAIL_POLICY", "none")
+	if got := protectEmail("jane@example.org"); got != "" {
+		t.Errorf("protectEmail = %q, want the email dropped", got)
+	}
+	// A misconfigured policy drops the email rather than storing it
+	t.Setenv("EMAIL_POLICY", "encrypted")
+	t.Setenv("EMAIL_ENCRYPTION_KEY", "")
+	if got := protectEmail("jane@example.org"); got != "" {
+		t.Errorf("protectEmail = %q, want the email dropped", got)
+	}
+	t.Setenv("EMAIL_POLICY", "hashed")
+	t.Setenv("EMAIL_HASH_KEY", "")
+	if got := protectEmail("jane@example.org"); got != "" {
+		t.Errorf("protectEmail = %q, want the email dropped", got)
+	}
+}
@@ -0,0 +1,69 @@
+package shared
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestCopiesAreUpToDate fails when a copy was edited, or a source was edited
+// without running go generate
+func TestCopiesAreUpToDate(t *testing.T) {
+	sources, err := Sources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) == 0 {
+		t.Fatal("no shared sources found")
+	}
+	for _, source := range sources {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Generated(source, content)
+		targets, err := Targets(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(targets) == 0 {
+			t.Errorf("no function uses %s", source)
+		}
+		for _, target := range targets {
+			got, err := os.ReadFile(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s differs from shared/%s, edit the source and run go generate in shared", target, source)
+			}
+		}
+	}
+}
+
+func TestTargets(t *testing.T) {
+	tests := map[string]int{
+		"access.go":         12,
+		"access_test.go":    12,
+		"generator.go":      2,
+		"generator_test.go": 2,
+		"history.go":        2,
+		"location.go":       2,
+		"location_test.go":  2,
+		"pii.go":            2,
+		"pii_test.go":       2,
+		"syncruns.go":       5,
+		"window.go":         4,
+		"window_test.go":    4,
+		"webhooks.go":       2,
+	}
+	for source, want := range tests {
+		targets, err := Targets(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(targets) != want {
+			t.Errorf("%s has %d targets, want %d: %v", source, len(targets), want, targets)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+//go:build ignore
+
+package main
+
+// syncruns.go is copied into the syncs, purge and runs by go generate in
+// shared, edit it there
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"time"
+)
+
+// MongoSyncRun is the outcome of a sync or purge, recorded in sync_runs and
+// listed by runs
+type MongoSyncRun struct {
+	Job         string    `bson:"job" json:"job"`
+	Start       time.Time `bson:"start" json:"start"`
+	End         time.Time `bson:"end" json:"end"`
+	Conferences []int     `bson:"conferences" json:"conferences"`
+	Inserted    int64     `bson:"inserted" json:"inserted"`
+	Updated     int64     `bson:"updated" json:"updated"`
+	Deleted     int64     `bson:"deleted" json:"deleted"`
+	Errors      []string  `bson:"errors" json:"errors"`
+}
+
+func recordSyncRun(run MongoSyncRun) error {
+	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_AUTH"))
+	client, connectErr := mongo.Connect(context.Background(), clientOptions)
+	if connectErr != nil {
+		return fmt.Errorf("error connecting to MongoDB: %s", connectErr.Error())
+	}
+	collection := client.Database("author-title").Collection("sync_runs")
+	if _, err := collection.InsertOne(context.Background(), run); err != nil {
+		return fmt.Errorf("error inserting sync run: %s", err.Error())
+	}
+	return nil
+}
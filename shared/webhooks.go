@@ -0,0 +1,129 @@
+//go:build ignore
+
+package main
+
+// webhooks.go is copied into timetables and contributions by go generate in
+// shared, edit it there
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+type MongoWebhook struct {
+	ConferenceId int    `bson:"conferenceId"`
+	URL          string `bson:"url"`
+	Secret       string `bson:"secret"`
+}
+
+// MongoQueuedWebhook is an event waiting in webhook_queue to be sent by the
+// webhooks function, so a slow subscriber never holds up a sync
+type MongoQueuedWebhook struct {
+	URL            string    `bson:"url"`
+	ConferenceId   int       `bson:"conferenceId"`
+	ContributionID int       `bson:"contributionId"`
+	Event          string    `bson:"event"`
+	Payload        string    `bson:"payload"`
+	Signature      string    `bson:"signature"`
+	Attempts       int       `bson:"attempts"`
+	NextAttemptAt  time.Time `bson:"nextAttemptAt"`
+	CreatedAt      time.Time `bson:"createdAt"`
+}
+
+type WebhookChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+type WebhookEvent struct {
+	Event          string          `json:"event"`
+	ConferenceId   int             `json:"conference_id"`
+	ContributionID int             `json:"contribution_id"`
+	Code           string          `json:"code"`
+	Job            string          `json:"job"`
+	SyncedAt       time.Time       `json:"synced_at"`
+	Changes        []WebhookChange `json:"changes"`
+}
+
+// signPayload is the X-Indico-Middleware-Signature of a payload
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookEvent is the contribution.changed event for the watched fields of
+// a change, or nil when none of them changed
+func webhookEvent(history MongoContributionHistory) *WebhookEvent {
+	event := WebhookEvent{
+		Event:          "contribution.changed",
+		ConferenceId:   history.ConferenceId,
+		ContributionID: history.ContributionID,
+		Code:           history.Code,
+		Job:            history.Job,
+		SyncedAt:       history.SyncedAt,
+	}
+	for _, change := range history.Changes {
+		if webhookFields[change.Field] {
+			event.Changes = append(event.Changes, WebhookChange{Field: change.Field, Old: change.Old, New: change.New})
+		}
+	}
+	if len(event.Changes) == 0 {
+		return nil
+	}
+	return &event
+}
+
+// notifyWebhooks queues a contribution.changed event, signed with each
+// webhook's secret, for every webhook configured for the conference when a
+// watched field has changed
+func notifyWebhooks(database *mongo.Database, history MongoContributionHistory) error {
+	event := webhookEvent(history)
+	if event == nil {
+		return nil
+	}
+
+	cursor, findError := database.Collection("webhooks").Find(context.Background(), bson.D{{"conferenceId", history.ConferenceId}})
+	if findError != nil {
+		return fmt.Errorf("error finding webhooks: %s", findError.Error())
+	}
+	var webhooks []MongoWebhook
+	if err := cursor.All(context.Background(), &webhooks); err != nil {
+		return fmt.Errorf("error decoding webhooks: %s", err.Error())
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error marshalling webhook event: %s", err.Error())
+	}
+
+	now := time.Now()
+	var queued []interface{}
+	for _, webhook := range webhooks {
+		queued = append(queued, MongoQueuedWebhook{
+			URL:            webhook.URL,
+			ConferenceId:   history.ConferenceId,
+			ContributionID: history.ContributionID,
+			Event:          event.Event,
+			Payload:        string(payload),
+			Signature:      signPayload(webhook.Secret, payload),
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+	}
+	if _, err := database.Collection("webhook_queue").InsertMany(context.Background(), queued); err != nil {
+		return fmt.Errorf("error queueing webhook events: %s", err.Error())
+	}
+	return nil
+}
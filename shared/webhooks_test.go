@@ -0,0 +1,71 @@
+//go:build ignore
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignPayload(t *testing.T) {
+	tests := []struct {
+		secret  string
+		payload string
+		want    string
+	}{
+		// RFC 4231 test case 2
+		{"Jefe", "what do ya want for nothing?", "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+		{"", "", "sha256=b613679a0814d9ec772f95d778c35fc5ff1697c493715653c6c712144292c5ad"},
+	}
+	for _, test := range tests {
+		if got := signPayload(test.secret, []byte(test.payload)); got != test.want {
+			t.Errorf("signPayload(%q, %q) = %s, want %s", test.secret, test.payload, got, test.want)
+		}
+	}
+}
+
+func TestWebhookEvent(t *testing.T) {
+	var watched string
+	for field := range webhookFields {
+		watched = field
+	}
+	history := func(fields ...string) MongoContributionHistory {
+		var changes []MongoFieldChange
+		for _, field := range fields {
+			changes = append(changes, MongoFieldChange{Field: field, Old: "old", New: "new"})
+		}
+		return MongoContributionHistory{
+			ContributionID: 7,
+			ConferenceId:   41,
+			Code:           "TUPA071",
+			Job:            "sync",
+			SyncedAt:       time.Date(2024, 5, 19, 0, 0, 0, 0, time.UTC),
+			Changes:        changes,
+		}
+	}
+	tests := []struct {
+		name    string
+		history MongoContributionHistory
+		changes int
+	}{
+		{"no changes", history(), 0},
+		{"unwatched", history("not_watched"), 0},
+		{"watched", history(watched), 1},
+		{"watched and unwatched", history("not_watched", watched), 1},
+	}
+	for _, test := range tests {
+		event := webhookEvent(test.history)
+		if test.changes == 0 {
+			if event != nil {
+				t.Errorf("%s: got an event %+v", test.name, event)
+			}
+			continue
+		}
+		if event == nil || len(event.Changes) != test.changes || event.Changes[0].Field != watched {
+			t.Fatalf("%s: unexpected event %+v", test.name, event)
+		}
+		if event.Event != "contribution.changed" || event.ConferenceId != 41 || event.ContributionID != 7 || event.Code != "TUPA071" {
+			t.Errorf("%s: unexpected event %+v", test.name, event)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+//go:build ignore
+
+package main
+
+// window.go is copied into timetables, contributions, digest and purge by go
+// generate in shared, edit it there
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"os"
+	"strconv"
+	"time"
+)
+
+// daysFromEnv reads a number of days from the environment, returning 0 when
+// the variable is unset or not a number
+func daysFromEnv(name string) time.Duration {
+	days, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || days < 0 {
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// activeConferencesFilter selects conferences which have not ended more than
+// CONFERENCE_GRACE_DAYS ago and, when CONFERENCE_LEAD_DAYS is set, start
+// within that many days. A conference with pinned set to true is always
+// selected, and one with pinned set to false never is.
+func activeConferencesFilter(now time.Time) bson.D {
+	window := bson.D{
+		{"pinned", bson.D{{"$ne", false}}},
+		{"end", bson.D{{"$gte", now.Add(-daysFromEnv("CONFERENCE_GRACE_DAYS"))}}},
+	}
+	if lead := daysFromEnv("CONFERENCE_LEAD_DAYS"); lead > 0 {
+		window = append(window, bson.E{"start", bson.D{{"$lte", now.Add(lead)}}})
+	}
+	return bson.D{{"$or", bson.A{
+		bson.D{{"pinned", true}},
+		window,
+	}}}
+}
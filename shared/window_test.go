@@ -0,0 +1,74 @@
+//go:build ignore
+
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestActiveConferencesFilter(t *testing.T) {
+	now := time.Date(2024, 5, 19, 2, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		name  string
+		grace string
+		lead  string
+		want  bson.D
+	}{
+		{
+			name: "defaults",
+			want: bson.D{{"$or", bson.A{
+				bson.D{{"pinned", true}},
+				bson.D{{"pinned", bson.D{{"$ne", false}}}, {"end", bson.D{{"$gte", now}}}},
+			}}},
+		},
+		{
+			name:  "grace and lead",
+			grace: "7",
+			lead:  "30",
+			want: bson.D{{"$or", bson.A{
+				bson.D{{"pinned", true}},
+				bson.D{
+					{"pinned", bson.D{{"$ne", false}}},
+					{"end", bson.D{{"$gte", now.Add(-7 * day)}}},
+					{"start", bson.D{{"$lte", now.Add(30 * day)}}},
+				},
+			}}},
+		},
+		{
+			name:  "invalid days",
+			grace: "-7",
+			lead:  "a month",
+			want: bson.D{{"$or", bson.A{
+				bson.D{{"pinned", true}},
+				bson.D{{"pinned", bson.D{{"$ne", false}}}, {"end", bson.D{{"$gte", now}}}},
+			}}},
+		},
+	}
+	for _, test := range tests {
+		t.Setenv("CONFERENCE_GRACE_DAYS", test.grace)
+		t.Setenv("CONFERENCE_LEAD_DAYS", test.lead)
+		if got := activeConferencesFilter(now); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: activeConferencesFilter = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDaysFromEnv(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":        0,
+		"0":       0,
+		"3":       3 * 24 * time.Hour,
+		"-3":      0,
+		"a month": 0,
+	}
+	for value, want := range tests {
+		t.Setenv("CONFERENCE_GRACE_DAYS", value)
+		if got := daysFromEnv("CONFERENCE_GRACE_DAYS"); got != want {
+			t.Errorf("daysFromEnv(%q) = %s, want %s", value, got, want)
+		}
+	}
+}